@@ -25,10 +25,11 @@ type startReq struct {
 //
 // Pointers are to distinguish between undefined and zero value.
 type startResp struct {
-	OnAir   *bool    `json:"on_air"`
-	Cmd     *string  `json:"cmd"`
-	Total   *total   `json:"total"`
-	Streams []stream `json:"streams"`
+	OnAir    *bool     `json:"on_air"`
+	Cmd      *string   `json:"cmd"`
+	Total    *total    `json:"total"`
+	Streams  []stream  `json:"streams"`
+	Services []service `json:"services"`
 }
 
 // total represents aggregated information about stream since previous response
@@ -51,15 +52,75 @@ type stream struct {
 	TypeName    string `json:"type_name"`
 }
 
+// service represents service (program) from SDT
+type service struct {
+	Descriptors []any `json:"descriptors"`
+	Sid         int   `json:"sid"`
+}
+
 // Result represents check result containing averages of info such as bitrate and various errors
 type Result struct {
-	Bitrate   int // Kbit/s
-	CCErrors  int
-	PCRErrors int
-	PESErrors int
-	Scrambled bool
-	HasAudio  bool
-	HasVideo  bool
+	Bitrate     int // Kbit/s
+	CCErrors    int
+	PCRErrors   int
+	PESErrors   int
+	Scrambled   bool
+	HasAudio    bool
+	HasVideo    bool
+	Streams     []ElementaryStream
+	ServiceName string // Empty if SDT is not reported
+}
+
+// Kinds of elementary streams
+const (
+	VideoKind = "VIDEO"
+	AudioKind = "AUDIO"
+	DataKind  = "DATA"
+)
+
+// ElementaryStream represents elementary stream of checked input
+type ElementaryStream struct {
+	PID       int
+	TypeID    int      // Stream type from PMT
+	Kind      string   // VideoKind, AudioKind, DataKind or other kind reported by astra analyzer
+	Codec     string   // Such as 'h264', 'hevc', 'aac' or 'ac3', 'type_0x..' if unknown
+	Languages []string // ISO 639 language codes
+}
+
+// toElementaryStream returns elementary stream <s> reported by astra analyzer converted to ElementaryStream
+func (s stream) toElementaryStream() ElementaryStream {
+	es := ElementaryStream{PID: s.Pid, TypeID: s.TypeID, Kind: s.TypeName}
+	var tags []byte
+	for _, desc := range descriptorMaps(s.Descriptors) {
+		if tag, ok := desc["type_id"].(float64); ok {
+			tags = append(tags, byte(tag))
+		}
+		if lang, ok := desc["lang"].(string); ok && lang != "" {
+			es.Languages = append(es.Languages, lang)
+		}
+	}
+	es.Codec = esCodec(byte(s.TypeID), tags)
+	return es
+}
+
+// serviceName returns name of the first service having it in <services> reported by astra analyzer
+func serviceName(services []service) string {
+	for _, svc := range services {
+		for _, desc := range descriptorMaps(svc.Descriptors) {
+			if name, ok := desc["service_name"].(string); ok && name != "" {
+				return name
+			}
+		}
+	}
+	return ""
+}
+
+// descriptorMaps returns <descriptors> reported by astra analyzer which are objects
+func descriptorMaps(descriptors []any) []map[string]any {
+	return lo.FilterMap(descriptors, func(desc any, _ int) (map[string]any, bool) {
+		m, ok := desc.(map[string]any)
+		return m, ok
+	})
 }
 
 // stopReq represets stop request to analyzer
@@ -164,14 +225,20 @@ func (a analyzer) Check(watchTime time.Duration, maxAttempts int, urlToCheck str
 				if resp.Streams != nil {
 					if !result.HasAudio {
 						result.HasAudio = lo.ContainsBy(resp.Streams, func(s stream) bool {
-							return s.TypeName == "AUDIO"
+							return s.TypeName == AudioKind
 						})
 					}
 					if !result.HasVideo {
 						result.HasVideo = lo.ContainsBy(resp.Streams, func(s stream) bool {
-							return s.TypeName == "VIDEO"
+							return s.TypeName == VideoKind
 						})
 					}
+					result.Streams = lo.Map(resp.Streams, func(s stream, _ int) ElementaryStream {
+						return s.toElementaryStream()
+					})
+				}
+				if name := serviceName(resp.Services); name != "" {
+					result.ServiceName = name
 				}
 			case <-ctx.Done():
 				// Deadline exceeded
@@ -227,12 +294,14 @@ func (a analyzer) Check(watchTime time.Duration, maxAttempts int, urlToCheck str
 // fakeAnalyzer represents fake astra analyzer client
 type fakeAnalyzer struct {
 	urlResultMap map[string]Result
+	urlErrMap    map[string]error
 }
 
 // NewFake returns new fake astra analyzer client
 func NewFake() *fakeAnalyzer {
 	return &fakeAnalyzer{
 		urlResultMap: map[string]Result{},
+		urlErrMap:    map[string]error{},
 	}
 }
 
@@ -241,7 +310,15 @@ func (a fakeAnalyzer) AddResult(url string, result Result) {
 	a.urlResultMap[url] = result
 }
 
-// Check returns fake result for <urlToCheck> and nil error
+// AddError adds new <err> to return when checking <url>
+func (a fakeAnalyzer) AddError(url string, err error) {
+	a.urlErrMap[url] = err
+}
+
+// Check returns fake result for <urlToCheck> and error if added for <urlToCheck> or nil otherwise
 func (a fakeAnalyzer) Check(watchTime time.Duration, maxAttempts int, urlToCheck string) (Result, error) {
+	if err := a.urlErrMap[urlToCheck]; err != nil {
+		return Result{}, err
+	}
 	return a.urlResultMap[urlToCheck], nil
 }
@@ -7,6 +7,9 @@ import (
 
 	"m3u_merge_astra/util/logger"
 
+	json "github.com/SCP002/jsonexraw"
+	"github.com/cockroachdb/errors"
+	"github.com/samber/lo"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -91,8 +94,42 @@ func TestCheck(t *testing.T) {
 	wg.Wait()
 }
 
+func TestToElementaryStream(t *testing.T) {
+	var resp startResp
+	err := json.Unmarshal([]byte(`{"streams": [
+		{"pid": 256, "type_id": 27, "type_name": "VIDEO", "descriptors": []},
+		{"pid": 257, "type_id": 6, "type_name": "AUDIO", "descriptors": [
+			{"type_id": 10, "type_name": "lang", "lang": "eng"},
+			{"type_id": 122, "type_name": "unknown", "data": "00"}
+		]},
+		{"pid": 258, "type_id": 3, "type_name": "AUDIO", "descriptors": [{"type_id": 10, "lang": "rus"}, "raw"]}
+	]}`), &resp)
+	assert.NoError(t, err, "should decode response")
+
+	expected := []ElementaryStream{
+		{PID: 256, TypeID: 27, Kind: VideoKind, Codec: "h264"},
+		{PID: 257, TypeID: 6, Kind: AudioKind, Codec: "eac3", Languages: []string{"eng"}},
+		{PID: 258, TypeID: 3, Kind: AudioKind, Codec: "mp2", Languages: []string{"rus"}},
+	}
+	actual := lo.Map(resp.Streams, func(s stream, _ int) ElementaryStream { return s.toElementaryStream() })
+	assert.Exactly(t, expected, actual, "should convert elementary streams with codecs and languages")
+}
+
+func TestServiceName(t *testing.T) {
+	var resp startResp
+	err := json.Unmarshal([]byte(`{"services": [
+		{"sid": 1, "descriptors": [{"type_id": 80, "type_name": "component"}]},
+		{"sid": 2, "descriptors": [{"type_id": 72, "type_name": "service", "service_type_id": 1,
+			"service_provider": "Provider", "service_name": "Channel"}]}
+	]}`), &resp)
+	assert.NoError(t, err, "should decode response")
+	assert.Exactly(t, "Channel", serviceName(resp.Services), "should return the first found service name")
+	assert.Exactly(t, "", serviceName(nil), "should return empty string if SDT is not reported")
+}
+
 func TestNewFake(t *testing.T) {
-	assert.Exactly(t, &fakeAnalyzer{urlResultMap: map[string]Result{}}, NewFake(), "should initialize fake analyzer")
+	expected := &fakeAnalyzer{urlResultMap: map[string]Result{}, urlErrMap: map[string]error{}}
+	assert.Exactly(t, expected, NewFake(), "should initialize fake analyzer")
 }
 
 func TestAddResult(t *testing.T) {
@@ -104,11 +141,24 @@ func TestAddResult(t *testing.T) {
 	assert.Exactly(t, expected, analyzer.urlResultMap, "should add results to analyzer")
 }
 
+func TestAddError(t *testing.T) {
+	analyzer := NewFake()
+	err := errors.New("Connection refused")
+	analyzer.AddError("url1", err)
+
+	assert.Exactly(t, map[string]error{"url1": err}, analyzer.urlErrMap, "should add errors to analyzer")
+}
+
 func TestFakeCheck(t *testing.T) {
 	analyzer := NewFake()
 	analyzer.AddResult("url1", Result{Bitrate: 1})
+	analyzer.AddError("url2", errors.New("Connection refused"))
 
 	result, err := analyzer.Check(time.Second, 1, "url1")
 	assert.Exactly(t, Result{Bitrate: 1}, result, "should return that result")
 	assert.NoError(t, err, "should not return error")
+
+	result, err = analyzer.Check(time.Second, 1, "url2")
+	assert.Exactly(t, Result{}, result, "should return empty result")
+	assert.ErrorContains(t, err, "Connection refused", "should return that error")
 }
@@ -0,0 +1,186 @@
+package analyzer
+
+import (
+	"context"
+	"encoding/binary"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+
+	"m3u_merge_astra/util/iter"
+	"m3u_merge_astra/util/logger"
+	"m3u_merge_astra/util/network"
+
+	"github.com/cockroachdb/errors"
+)
+
+// maxDatagramSize represents maximum size of UDP datagram
+const maxDatagramSize = 65535
+
+// prober represents built-in MPEG-TS stream prober which does not require astra analyzer
+type prober struct {
+	httpClient *http.Client
+	log        *logger.Logger
+}
+
+// NewProbe returns new built-in MPEG-TS stream prober which waits up to <dialTimeout> for response from stream.
+//
+// Supports HTTP(S), UDP and RTP (including multicast, interface can be specified as in 'udp://eth0@239.0.0.1:1234').
+func NewProbe(log *logger.Logger, dialTimeout time.Duration) *prober {
+	httpClient := network.NewHttpClient(0) // Time of reading the body is limited by watch time
+	httpClient.Transport.(*http.Transport).ResponseHeaderTimeout = dialTimeout
+	return &prober{httpClient: httpClient, log: log}
+}
+
+// Check returns check result of <urlToCheck> reading it for <watchTime>.
+//
+// Returns after <watchTime> is up, up to <maxAttempts> times or earlier if bitrate was > 0 during previous attempt.
+//
+// Does Not return error if <urlToCheck> is dead, rely on bitrate == 0. Error counts are averages per second.
+func (p prober) Check(watchTime time.Duration, maxAttempts int, urlToCheck string) (Result, error) {
+	parsedURL, err := url.Parse(urlToCheck)
+	if err != nil {
+		return Result{}, errors.Wrap(err, "Parse URL")
+	}
+	if parsedURL.Scheme != "http" && parsedURL.Scheme != "https" && parsedURL.Scheme != "udp" &&
+		parsedURL.Scheme != "rtp" {
+		return Result{}, errors.Newf("Unsupported protocol %v", parsedURL.Scheme)
+	}
+
+	var result Result
+	iter.Times(maxAttempts, func(attempt int) bool {
+		p.log.DebugFi("Probing", "url", urlToCheck, "attempt", attempt)
+		ctx, cancel := context.WithTimeout(context.Background(), watchTime)
+		defer cancel()
+		result = p.probe(ctx, parsedURL)
+		return result.Bitrate == 0 // Stop trying if stream is alive
+	})
+
+	return result, nil
+}
+
+// probe returns result of reading stream at <streamURL> until <ctx> is done or stream ends
+func (p prober) probe(ctx context.Context, streamURL *url.URL) Result {
+	stats := newTSStats()
+	start := time.Now()
+
+	var err error
+	switch streamURL.Scheme {
+	case "http", "https":
+		err = p.readHTTP(ctx, streamURL, stats)
+	case "udp":
+		err = p.readUDP(ctx, streamURL, stats, false)
+	case "rtp":
+		err = p.readUDP(ctx, streamURL, stats, true)
+	}
+	if err != nil {
+		p.log.DebugFi("Stream read error", "url", streamURL, "error", err)
+	}
+
+	// Calculate averages per second
+	seconds := max(time.Since(start).Seconds(), 1)
+	return Result{
+		Bitrate:     int(float64(stats.Bytes*8) / seconds / 1000),
+		CCErrors:    int(float64(stats.CCErrors) / seconds),
+		PCRErrors:   int(float64(stats.PCRErrors) / seconds),
+		PESErrors:   int(float64(stats.PESErrors) / seconds),
+		Scrambled:   stats.Scrambled,
+		HasAudio:    stats.HasAudio,
+		HasVideo:    stats.HasVideo,
+		Streams:     stats.Streams,
+		ServiceName: stats.ServiceName,
+	}
+}
+
+// readHTTP feeds body of response from <streamURL> to <stats> until <ctx> is done or body ends
+func (p prober) readHTTP(ctx context.Context, streamURL *url.URL, stats *tsStats) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, streamURL.String(), nil)
+	if err != nil {
+		return errors.Wrap(err, "Create request")
+	}
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return errors.Wrap(err, "Send request")
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 400 {
+		return errors.Newf("Responded with: %v", resp.Status)
+	}
+
+	if _, err := io.Copy(stats, resp.Body); err != nil && !errors.Is(err, context.DeadlineExceeded) {
+		return errors.Wrap(err, "Read response")
+	}
+	return nil
+}
+
+// readUDP feeds datagrams received at address from <streamURL> to <stats> until <ctx> is done.
+//
+// If <rtp> is true, RTP header is removed from every datagram.
+func (p prober) readUDP(ctx context.Context, streamURL *url.URL, stats *tsStats, rtp bool) error {
+	addr, err := net.ResolveUDPAddr("udp", streamURL.Host)
+	if err != nil {
+		return errors.Wrap(err, "Resolve address")
+	}
+
+	var conn *net.UDPConn
+	if addr.IP.IsMulticast() {
+		var iface *net.Interface
+		if ifaceName := streamURL.User.Username(); ifaceName != "" {
+			if iface, err = net.InterfaceByName(ifaceName); err != nil {
+				return errors.Wrap(err, "Find interface")
+			}
+		}
+		conn, err = net.ListenMulticastUDP("udp", iface, addr)
+	} else {
+		conn, err = net.ListenUDP("udp", addr)
+	}
+	if err != nil {
+		return errors.Wrap(err, "Listen")
+	}
+	defer conn.Close()
+
+	deadline, _ := ctx.Deadline()
+	if err := conn.SetReadDeadline(deadline); err != nil {
+		return errors.Wrap(err, "Set read deadline")
+	}
+	datagram := make([]byte, maxDatagramSize)
+	for {
+		n, _, err := conn.ReadFromUDP(datagram)
+		if errors.Is(err, net.ErrClosed) || network.GetErrType(err) == network.Timeout {
+			return nil
+		}
+		if err != nil {
+			return errors.Wrap(err, "Read datagram")
+		}
+		payload := datagram[:n]
+		if rtp {
+			if payload = rtpPayload(payload); payload == nil {
+				continue
+			}
+		}
+		_, _ = stats.Write(payload)
+	}
+}
+
+// rtpPayload returns payload of RTP <packet> or nil if packet is invalid
+func rtpPayload(packet []byte) []byte {
+	if len(packet) < 12 || packet[0]>>6 != 2 {
+		return nil
+	}
+	headerLen := 12 + int(packet[0]&0x0f)*4 // Fixed header and CSRC identifiers
+	if packet[0]&0x10 != 0 {                // Header extension
+		if len(packet) < headerLen+4 {
+			return nil
+		}
+		headerLen += 4 + int(binary.BigEndian.Uint16(packet[headerLen+2:headerLen+4]))*4
+	}
+	if packet[0]&0x20 != 0 { // Padding, the last byte is it's length
+		packet = packet[:max(len(packet)-int(packet[len(packet)-1]), 0)]
+	}
+	if len(packet) < headerLen {
+		return nil
+	}
+	return packet[headerLen:]
+}
@@ -0,0 +1,102 @@
+package analyzer
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"m3u_merge_astra/util/logger"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// freeUDPPort returns UDP port which is free at the moment
+func freeUDPPort(t *testing.T) int {
+	conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	assert.NoError(t, err, "should listen on free port")
+	defer conn.Close()
+	return conn.LocalAddr().(*net.UDPAddr).Port
+}
+
+// sendUDP sends <datagram> to <port> at localhost every 10 ms for <duration>
+func sendUDP(t *testing.T, port int, datagram []byte, duration time.Duration) {
+	conn, err := net.Dial("udp", "127.0.0.1:"+strconv.Itoa(port))
+	assert.NoError(t, err, "should dial UDP port")
+	defer conn.Close()
+	for start := time.Now(); time.Since(start) < duration; time.Sleep(time.Millisecond * 10) {
+		_, _ = conn.Write(datagram)
+	}
+}
+
+func TestNewProbe(t *testing.T) {
+	log := logger.New(logger.DebugLevel)
+	prober := NewProbe(log, time.Second)
+	assert.Exactly(t, log, prober.log, "should set logger for prober")
+	assert.Exactly(t, time.Duration(0), prober.httpClient.Timeout, "should not limit time of reading response")
+	assert.Exactly(t, time.Second, prober.httpClient.Transport.(*http.Transport).ResponseHeaderTimeout,
+		"should limit time of waiting for response")
+}
+
+func TestProbeCheck(t *testing.T) {
+	prober := NewProbe(logger.New(logger.DebugLevel), time.Second)
+	stream := newTestStream()
+
+	// HTTP
+	mux := http.NewServeMux()
+	mux.HandleFunc("/stream.ts", func(w http.ResponseWriter, req *http.Request) {
+		for range 10 {
+			_, _ = w.Write(stream)
+		}
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	result, err := prober.Check(time.Second, 3, srv.URL+"/stream.ts")
+	assert.NoError(t, err, "should not return error")
+	assert.Exactly(t, len(stream)*10*8/1000, result.Bitrate,
+		"should read the whole response, considering it lasted at least a second")
+	assert.True(t, result.CCErrors > 0, "should find CC errors as the stream repeats")
+	assert.True(t, result.HasAudio, "should find audio")
+	assert.True(t, result.HasVideo, "should find video")
+	assert.Len(t, result.Streams, 2, "should find elementary streams")
+	assert.Exactly(t, "Channel", result.ServiceName, "should find service name")
+
+	result, err = prober.Check(time.Second, 2, srv.URL+"/dead.ts")
+	assert.NoError(t, err, "should not return error for dead stream")
+	assert.Exactly(t, Result{}, result, "should return empty result for dead stream")
+
+	// UDP
+	port := freeUDPPort(t)
+	go sendUDP(t, port, stream[:packetSize*7], time.Second)
+	result, err = prober.Check(time.Millisecond*500, 1, "udp://127.0.0.1:"+strconv.Itoa(port)+"#sync")
+	assert.NoError(t, err, "should not return error")
+	assert.True(t, result.Bitrate > 0, "should receive stream over UDP")
+	assert.True(t, result.HasVideo, "should find video")
+
+	// RTP
+	port = freeUDPPort(t)
+	rtpHeader := []byte{0x80, 33, 0, 1, 0, 0, 0, 0, 0, 0, 0, 1}
+	go sendUDP(t, port, append(rtpHeader, stream[:packetSize*7]...), time.Second)
+	result, err = prober.Check(time.Millisecond*500, 1, "rtp://127.0.0.1:"+strconv.Itoa(port))
+	assert.NoError(t, err, "should not return error")
+	assert.True(t, result.Bitrate > 0, "should receive stream over RTP")
+	assert.True(t, result.HasVideo, "should find video")
+
+	_, err = prober.Check(time.Second, 1, "rtsp://127.0.0.1/stream")
+	assert.ErrorContains(t, err, "Unsupported protocol rtsp")
+}
+
+func TestRTPPayload(t *testing.T) {
+	payload := []byte{syncByte, 1, 2}
+	assert.Exactly(t, payload, rtpPayload(append([]byte{0x80, 33, 0, 1, 0, 0, 0, 0, 0, 0, 0, 1}, payload...)),
+		"should remove fixed header")
+	assert.Exactly(t, payload, rtpPayload(append([]byte{0x91, 33, 0, 1, 0, 0, 0, 0, 0, 0, 0, 1, 0, 0, 0, 2,
+		0xbe, 0xde, 0, 1, 1, 2, 3, 4}, payload...)), "should remove CSRC identifiers and header extension")
+	assert.Exactly(t, payload, rtpPayload(append(append([]byte{0xa0, 33, 0, 1, 0, 0, 0, 0, 0, 0, 0, 1}, payload...),
+		0, 0, 3)), "should remove padding")
+	assert.Nil(t, rtpPayload([]byte{0x40, 33, 0, 1, 0, 0, 0, 0, 0, 0, 0, 1}), "should return nil for other version")
+	assert.Nil(t, rtpPayload([]byte{0x80, 33}), "should return nil for too short packet")
+}
@@ -0,0 +1,381 @@
+package analyzer
+
+import (
+	"encoding/binary"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/samber/lo"
+)
+
+// packetSize represents size of MPEG-TS packet in bytes
+const packetSize = 188
+
+// syncByte represents the first byte of every MPEG-TS packet
+const syncByte = 0x47
+
+// nullPID represents PID of MPEG-TS null packets
+const nullPID = 0x1fff
+
+// sdtPID represents PID of service description table
+const sdtPID = 0x11
+
+// maxPCRInterval represents maximum allowed interval between PCRs in 27 MHz ticks (100 ms, ISO/IEC 13818-1)
+const maxPCRInterval = 27_000_000 / 10
+
+// pcrWrap represents value at which PCR wraps around in 27 MHz ticks
+const pcrWrap = (1 << 33) * 300
+
+// videoStreamTypes represents PMT stream types of video elementary streams
+var videoStreamTypes = []byte{0x01, 0x02, 0x10, 0x1b, 0x20, 0x24, 0x42, 0xd1, 0xea}
+
+// audioStreamTypes represents PMT stream types of audio elementary streams
+var audioStreamTypes = []byte{0x03, 0x04, 0x0f, 0x11, 0x1c, 0x81, 0x82, 0x83, 0x84, 0x85, 0x86, 0x87}
+
+// audioDescriptorTags represents tags of PMT descriptors marking private data elementary stream as audio (AC-3,
+// E-AC-3, DTS, AAC)
+var audioDescriptorTags = []byte{0x6a, 0x7a, 0x7b, 0x7c}
+
+// streamTypeCodecs represents codec names of PMT stream types
+var streamTypeCodecs = map[byte]string{
+	0x01: "mpeg1video", 0x02: "mpeg2video", 0x03: "mp2", 0x04: "mp2", 0x0f: "aac", 0x10: "mpeg4", 0x11: "aac_latm",
+	0x1b: "h264", 0x1c: "aac", 0x20: "h264", 0x24: "hevc", 0x42: "cavs", 0x81: "ac3", 0x82: "dts", 0x83: "truehd",
+	0x84: "eac3", 0x85: "dts", 0x86: "dts", 0x87: "eac3", 0xd1: "dirac", 0xea: "vc1",
+}
+
+// descriptorCodecs represents codec names of private data elementary streams by tags of their PMT descriptors
+var descriptorCodecs = map[byte]string{0x6a: "ac3", 0x7a: "eac3", 0x7b: "dts", 0x7c: "aac", 0x56: "teletext",
+	0x59: "dvb_subtitle"}
+
+// esKind returns kind of elementary stream with <streamType> and <descriptorTags>
+func esKind(streamType byte, descriptorTags []byte) string {
+	switch {
+	case slices.Contains(videoStreamTypes, streamType):
+		return VideoKind
+	case slices.Contains(audioStreamTypes, streamType):
+		return AudioKind
+	case streamType == 0x06 && lo.Some(descriptorTags, audioDescriptorTags):
+		return AudioKind
+	}
+	return DataKind
+}
+
+// esCodec returns codec name of elementary stream with <streamType> and <descriptorTags>
+func esCodec(streamType byte, descriptorTags []byte) string {
+	if codec, found := streamTypeCodecs[streamType]; found {
+		return codec
+	}
+	if streamType == 0x06 {
+		for _, tag := range descriptorTags {
+			if codec, found := descriptorCodecs[tag]; found {
+				return codec
+			}
+		}
+	}
+	return fmt.Sprintf("type_0x%02x", streamType)
+}
+
+// descriptor represents descriptor from descriptors loop of PSI table
+type descriptor struct {
+	Tag  byte
+	Data []byte
+}
+
+// pidState represents state of MPEG-TS PID between packets
+type pidState struct {
+	lastCC   int // -1 if no packets with payload yet
+	dupCount int
+	lastPCR  int64 // -1 if no PCR yet
+}
+
+// tsStats represents statistics of MPEG-TS stream collected by feeding it's bytes
+type tsStats struct {
+	Bytes     int
+	CCErrors  int
+	PCRErrors int
+	PESErrors int
+	Scrambled bool
+	HasAudio  bool
+	HasVideo  bool
+
+	Streams     []ElementaryStream
+	ServiceName string
+
+	buf     []byte
+	pids    map[int]*pidState
+	pmtPIDs map[int]bool
+	esPIDs  map[int]bool
+	synced  bool
+}
+
+// newTSStats returns new empty statistics of MPEG-TS stream
+func newTSStats() *tsStats {
+	return &tsStats{
+		pids:    map[int]*pidState{},
+		pmtPIDs: map[int]bool{},
+		esPIDs:  map[int]bool{},
+	}
+}
+
+// Write feeds <data> of MPEG-TS stream to the statistics. Data does not need to be aligned to packets.
+//
+// Always returns length of <data> and nil error, implementing io.Writer.
+func (s *tsStats) Write(data []byte) (int, error) {
+	s.buf = append(s.buf, data...)
+	for len(s.buf) >= packetSize {
+		// Find packet boundary by two sync bytes in a row if stream is not aligned yet
+		if !s.synced {
+			if len(s.buf) <= packetSize {
+				break
+			}
+			if s.buf[0] != syncByte || s.buf[packetSize] != syncByte {
+				s.buf = s.buf[1:]
+				continue
+			}
+			s.synced = true
+		}
+		if s.buf[0] != syncByte {
+			s.synced = false
+			continue
+		}
+		s.packet(s.buf[:packetSize])
+		s.buf = s.buf[packetSize:]
+	}
+	// Copy the remainder so memory of processed data can be freed
+	s.buf = append([]byte{}, s.buf...)
+	return len(data), nil
+}
+
+// packet collects statistics of MPEG-TS packet <p>
+func (s *tsStats) packet(p []byte) {
+	s.Bytes += packetSize
+
+	pid := int(p[1]&0x1f)<<8 | int(p[2])
+	if pid == nullPID || p[1]&0x80 != 0 { // Skip null and corrupted (transport error indicator) packets
+		return
+	}
+	pusi := p[1]&0x40 != 0
+	scrambling := p[3] >> 6
+	hasAdaptation := p[3]&0x20 != 0
+	hasPayload := p[3]&0x10 != 0
+	cc := int(p[3] & 0x0f)
+
+	state, found := s.pids[pid]
+	if !found {
+		state = &pidState{lastCC: -1, lastPCR: -1}
+		s.pids[pid] = state
+	}
+
+	payload := p[4:]
+	discontinuity := false
+	if hasAdaptation {
+		adaptationLen := int(p[4])
+		if adaptationLen >= 183 {
+			payload = nil
+		} else {
+			payload = p[5+adaptationLen:]
+		}
+		if adaptationLen > 0 {
+			flags := p[5]
+			discontinuity = flags&0x80 != 0
+			if flags&0x10 != 0 && adaptationLen >= 7 {
+				s.pcr(state, p[6:12], discontinuity)
+			}
+		}
+	}
+
+	// Continuity counter is incremented only for packets with payload, one duplicate packet is allowed
+	if hasPayload {
+		switch {
+		case state.lastCC == -1 || discontinuity:
+		case cc == state.lastCC:
+			state.dupCount++
+			if state.dupCount > 1 {
+				s.CCErrors++
+			}
+		case cc != (state.lastCC+1)&0x0f:
+			s.CCErrors++
+		}
+		if cc != state.lastCC {
+			state.dupCount = 0
+		}
+		state.lastCC = cc
+	}
+
+	if scrambling != 0 && s.esPIDs[pid] {
+		s.Scrambled = true
+	}
+	if !hasPayload || !pusi || len(payload) == 0 {
+		return
+	}
+	switch {
+	case pid == 0:
+		s.pat(payload)
+	case s.pmtPIDs[pid]:
+		s.pmt(payload)
+	case pid == sdtPID && s.ServiceName == "":
+		s.sdt(payload)
+	case s.esPIDs[pid] && scrambling == 0:
+		// PES packet should start with packet start code prefix
+		if len(payload) < 3 || payload[0] != 0 || payload[1] != 0 || payload[2] != 1 {
+			s.PESErrors++
+		}
+	}
+}
+
+// pcr checks PCR from 6 bytes of <field> against the previous one of the PID with <state>.
+//
+// PCR is not checked against the previous one if <discontinuity> is true.
+func (s *tsStats) pcr(state *pidState, field []byte, discontinuity bool) {
+	base := int64(binary.BigEndian.Uint32(field[:4]))<<1 | int64(field[4]>>7)
+	ext := int64(field[4]&0x01)<<8 | int64(field[5])
+	pcr := base*300 + ext
+
+	if state.lastPCR != -1 && !discontinuity {
+		interval := (pcr - state.lastPCR + pcrWrap) % pcrWrap
+		if interval > maxPCRInterval {
+			s.PCRErrors++
+		}
+	}
+	state.lastPCR = pcr
+}
+
+// section returns PSI section with <tableID> from <payload> starting with pointer field without CRC or nil if
+// payload does not contain such section.
+//
+// Sections spanning multiple packets are not supported.
+func section(payload []byte, tableID byte) []byte {
+	pointer := int(payload[0])
+	if 1+pointer+3 > len(payload) {
+		return nil
+	}
+	sec := payload[1+pointer:]
+	if sec[0] != tableID {
+		return nil
+	}
+	sectionLen := int(sec[1]&0x0f)<<8 | int(sec[2])
+	if sectionLen < 9 || 3+sectionLen > len(sec) {
+		return nil
+	}
+	return sec[:3+sectionLen-4]
+}
+
+// pat collects PIDs of PMTs from PAT section in <payload>
+func (s *tsStats) pat(payload []byte) {
+	sec := section(payload, 0x00)
+	if sec == nil {
+		return
+	}
+	for entry := sec[8:]; len(entry) >= 4; entry = entry[4:] {
+		programNumber := binary.BigEndian.Uint16(entry[:2])
+		if programNumber == 0 { // Network information table
+			continue
+		}
+		s.pmtPIDs[int(entry[2]&0x1f)<<8|int(entry[3])] = true
+	}
+}
+
+// pmt collects elementary streams with their types, codecs and languages from PMT section in <payload>
+func (s *tsStats) pmt(payload []byte) {
+	sec := section(payload, 0x02)
+	if sec == nil || len(sec) < 12 {
+		return
+	}
+	programInfoLen := int(sec[10]&0x0f)<<8 | int(sec[11])
+	if 12+programInfoLen > len(sec) {
+		return
+	}
+	for entry := sec[12+programInfoLen:]; len(entry) >= 5; {
+		streamType := entry[0]
+		pid := int(entry[1]&0x1f)<<8 | int(entry[2])
+		esInfoLen := int(entry[3]&0x0f)<<8 | int(entry[4])
+		if 5+esInfoLen > len(entry) {
+			return
+		}
+		descriptors := parseDescriptors(entry[5 : 5+esInfoLen])
+		entry = entry[5+esInfoLen:]
+		if s.esPIDs[pid] { // PMT is repeated
+			continue
+		}
+		s.esPIDs[pid] = true
+
+		tags := lo.Map(descriptors, func(d descriptor, _ int) byte { return d.Tag })
+		es := ElementaryStream{PID: pid, TypeID: int(streamType), Kind: esKind(streamType, tags),
+			Codec: esCodec(streamType, tags)}
+		for _, d := range descriptors {
+			if d.Tag != 0x0a { // ISO 639 language descriptor
+				continue
+			}
+			for lang := d.Data; len(lang) >= 4; lang = lang[4:] { // Language code and audio type
+				es.Languages = append(es.Languages, string(lang[:3]))
+			}
+		}
+		s.Streams = append(s.Streams, es)
+		s.HasVideo = s.HasVideo || es.Kind == VideoKind
+		s.HasAudio = s.HasAudio || es.Kind == AudioKind
+	}
+}
+
+// sdt collects name of the first service having it from SDT section of actual transport stream in <payload>
+func (s *tsStats) sdt(payload []byte) {
+	sec := section(payload, 0x42)
+	if sec == nil || len(sec) < 11 {
+		return
+	}
+	for entry := sec[11:]; len(entry) >= 5; { // Skip original network ID
+		loopLen := int(entry[3]&0x0f)<<8 | int(entry[4])
+		if 5+loopLen > len(entry) {
+			return
+		}
+		descriptors := parseDescriptors(entry[5 : 5+loopLen])
+		entry = entry[5+loopLen:]
+
+		for _, d := range descriptors {
+			if d.Tag != 0x48 || len(d.Data) < 2 { // Service descriptor
+				continue
+			}
+			providerLen := int(d.Data[1])
+			if 2+providerLen >= len(d.Data) {
+				continue
+			}
+			name := d.Data[3+providerLen:]
+			name = name[:min(int(d.Data[2+providerLen]), len(name))]
+			if s.ServiceName = dvbText(name); s.ServiceName != "" {
+				return
+			}
+		}
+	}
+}
+
+// parseDescriptors returns descriptors from descriptors <loop>, ignoring the last one if it is truncated
+func parseDescriptors(loop []byte) []descriptor {
+	var descriptors []descriptor
+	for len(loop) >= 2 {
+		end := 2 + int(loop[1])
+		if end > len(loop) {
+			break
+		}
+		descriptors = append(descriptors, descriptor{Tag: loop[0], Data: loop[2:end]})
+		loop = loop[end:]
+	}
+	return descriptors
+}
+
+// dvbText returns DVB encoded <text> without character table selection bytes and control codes.
+//
+// Characters of tables other than the default one are kept as is.
+func dvbText(text []byte) string {
+	if len(text) > 0 && text[0] < 0x20 {
+		switch text[0] {
+		case 0x10: // Three bytes selecting ISO/IEC 8859 part
+			text = text[min(3, len(text)):]
+		case 0x1f: // Two bytes selecting encoding type
+			text = text[min(2, len(text)):]
+		default:
+			text = text[1:]
+		}
+	}
+	text = slices.DeleteFunc(slices.Clone(text), func(b byte) bool { return b >= 0x80 && b <= 0x9f })
+	return strings.TrimSpace(string(text))
+}
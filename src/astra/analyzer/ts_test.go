@@ -0,0 +1,196 @@
+package analyzer
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// tsPacket represents MPEG-TS packet to build for tests
+type tsPacket struct {
+	PID           int
+	PUSI          bool
+	CC            int
+	Scrambled     bool
+	NoPayload     bool
+	Discontinuity bool
+	PCR           int64 // In 27 MHz ticks, -1 if packet has no PCR
+	Payload       []byte
+}
+
+// bytes returns MPEG-TS packet <p> encoded, stuffed to packet size
+func (p tsPacket) bytes() []byte {
+	data := []byte{syncByte, byte(p.PID >> 8 & 0x1f), byte(p.PID), byte(p.CC & 0x0f)}
+	if p.PUSI {
+		data[1] |= 0x40
+	}
+	if p.Scrambled {
+		data[3] |= 0x80
+	}
+	if !p.NoPayload {
+		data[3] |= 0x10
+	}
+
+	adaptation := []byte{}
+	if p.PCR >= 0 || p.Discontinuity || p.NoPayload {
+		data[3] |= 0x20
+		flags := byte(0)
+		if p.Discontinuity {
+			flags |= 0x80
+		}
+		adaptation = append(adaptation, flags)
+		if p.PCR >= 0 {
+			adaptation[0] |= 0x10
+			base, ext := p.PCR/300, p.PCR%300
+			adaptation = append(adaptation, byte(base>>25), byte(base>>17), byte(base>>9), byte(base>>1),
+				byte(base&1)<<7|0x7e|byte(ext>>8), byte(ext))
+		}
+	}
+	// Stuff adaptation field so the payload ends at the end of the packet
+	if data[3]&0x20 != 0 {
+		stuffing := packetSize - len(data) - 1 - len(adaptation) - len(p.Payload)
+		for range stuffing {
+			adaptation = append(adaptation, 0xff)
+		}
+		data = append(append(data, byte(len(adaptation))), adaptation...)
+	} else {
+		for range packetSize - len(data) - len(p.Payload) {
+			p.Payload = append(p.Payload, 0xff)
+		}
+	}
+
+	return append(data, p.Payload...)
+}
+
+// newPacket returns MPEG-TS packet of <pid> with continuity counter <cc> and <payload> without PCR
+func newPacket(pid int, cc int, pusi bool, payload ...byte) tsPacket {
+	return tsPacket{PID: pid, PUSI: pusi, CC: cc, PCR: -1, Payload: payload}
+}
+
+// psi returns payload of packet with PSI section of <tableID> with <tableIDExt> and <body>, dummy CRC included
+func psi(tableID byte, tableIDExt int, body ...byte) []byte {
+	sectionLen := 5 + len(body) + 4
+	payload := []byte{0, tableID, 0xb0 | byte(sectionLen>>8), byte(sectionLen), byte(tableIDExt >> 8),
+		byte(tableIDExt), 0xc1, 0, 0}
+	return append(append(payload, body...), 0, 0, 0, 0)
+}
+
+// newTestStream returns MPEG-TS stream with PAT, SDT with service 'Channel', PMT with video PID 0x101 and english
+// AC-3 audio PID 0x102 and a few packets of each elementary stream without errors
+func newTestStream() []byte {
+	pat := psi(0x00, 1, 0, 0, 0xe0, 0x10, 0, 1, 0xe1, 0x00) // NIT on 0x10, program 1 on 0x100
+	sdt := psi(0x42, 1, 0, 1, 0xff, 0, 1, 0xfc, 0x80, 16,   // Original network 1, service 1 with service descriptor
+		0x48, 14, 0x01, 4, 'P', 'r', 'o', 'v', 7, 'C', 'h', 'a', 'n', 'n', 'e', 'l')
+	pmt := psi(0x02, 1, 0xe1, 0x01, 0xf0, 0, 0x1b, 0xe1, 0x01, 0xf0, 0, 0x06, // PCR PID 0x101, H.264 on 0x101
+		0xe1, 0x02, 0xf0, 0x09, 0x0a, 0x04, 'e', 'n', 'g', 0, 0x6a, 0x01, 0x00) // Private data on 0x102 with language
+	// and AC-3 descriptors
+	packets := []tsPacket{
+		newPacket(0, 0, true, pat...),
+		newPacket(sdtPID, 0, true, sdt...),
+		newPacket(0x100, 0, true, pmt...),
+		{PID: 0x101, PUSI: true, CC: 0, PCR: 0, Payload: []byte{0, 0, 1, 0xe0}},
+		newPacket(0x101, 1, false),
+		newPacket(0x102, 0, true, 0, 0, 1, 0xbd),
+		{PID: 0x101, CC: 1, NoPayload: true, PCR: 27_000_000 / 25},
+		newPacket(0x101, 2, false),
+		newPacket(0x1fff, 5, false),
+	}
+	var stream []byte
+	for _, p := range packets {
+		stream = append(stream, p.bytes()...)
+	}
+	return stream
+}
+
+func TestTSStatsWrite(t *testing.T) {
+	stream := newTestStream()
+	assert.Len(t, stream, packetSize*9, "test stream should consist of whole packets")
+
+	// Test stream without errors written in parts not aligned to packets, after garbage
+	stats := newTSStats()
+	data := append([]byte{syncByte, 1, 2, 3, syncByte}, stream...)
+	for len(data) > 0 {
+		n := min(100, len(data))
+		written, err := stats.Write(data[:n])
+		assert.NoError(t, err, "should not return error")
+		assert.Exactly(t, n, written, "should return amount of bytes written")
+		data = data[n:]
+	}
+	assert.Exactly(t, packetSize*9, stats.Bytes, "should count bytes of all packets after garbage")
+	assert.Exactly(t, 0, stats.CCErrors, "should not find CC errors")
+	assert.Exactly(t, 0, stats.PCRErrors, "should not find PCR errors")
+	assert.Exactly(t, 0, stats.PESErrors, "should not find PES errors")
+	assert.False(t, stats.Scrambled, "should not find scrambled packets")
+	assert.True(t, stats.HasVideo, "should find video by stream type")
+	assert.True(t, stats.HasAudio, "should find audio by descriptor of private data stream")
+	expected := []ElementaryStream{
+		{PID: 0x101, TypeID: 0x1b, Kind: VideoKind, Codec: "h264"},
+		{PID: 0x102, TypeID: 0x06, Kind: AudioKind, Codec: "ac3", Languages: []string{"eng"}},
+	}
+	assert.Exactly(t, expected, stats.Streams, "should collect elementary streams from PMT")
+	assert.Exactly(t, "Channel", stats.ServiceName, "should find service name in SDT")
+
+	// Test stream with errors
+	packets := []tsPacket{
+		newPacket(0x101, 2, false),                                        // Duplicate of the last packet, allowed
+		newPacket(0x101, 2, false),                                        // The second duplicate, CC error
+		newPacket(0x101, 4, false),                                        // Skipped packet, CC error
+		newPacket(0x102, 1, true, 0xff, 0xff),                             // No start code, PES error
+		{PID: 0x102, CC: 2, Scrambled: true, PCR: -1},                     // Scrambled
+		{PID: 0x101, CC: 4, NoPayload: true, PCR: 27_000_000},             // Too late, PCR error
+		{PID: 0x101, CC: 4, NoPayload: true, PCR: 0, Discontinuity: true}, // Discontinuity, not an error
+		{PID: 0x101, CC: 0, PCR: -1, Discontinuity: true},                 // Discontinuity, not a CC error
+	}
+	stats = newTSStats()
+	_, _ = stats.Write(stream)
+	_, _ = stats.Write(stream[packetSize*2 : packetSize*3]) // Repeated PMT
+	for _, p := range packets {
+		_, _ = stats.Write(p.bytes())
+	}
+	assert.Exactly(t, 2, stats.CCErrors, "should find CC errors")
+	assert.Exactly(t, 1, stats.PCRErrors, "should find PCR errors")
+	assert.Exactly(t, 1, stats.PESErrors, "should find PES errors")
+	assert.True(t, stats.Scrambled, "should find scrambled packets")
+	assert.Len(t, stats.Streams, 2, "should not collect elementary streams of repeated PMT")
+}
+
+func TestSection(t *testing.T) {
+	payload := psi(0x00, 1, 0, 1, 0xe1, 0x00)
+	expected := []byte{0x00, 0xb0, 13, 0, 1, 0xc1, 0, 0, 0, 1, 0xe1, 0x00}
+	assert.Exactly(t, expected, section(payload, 0x00), "should return section without pointer field and CRC")
+	assert.Nil(t, section(payload, 0x02), "should return nil for other table")
+	assert.Nil(t, section(payload[:10], 0x00), "should return nil for section spanning multiple packets")
+	assert.Nil(t, section([]byte{5, 0}, 0x00), "should return nil for invalid pointer field")
+}
+
+func TestParseDescriptors(t *testing.T) {
+	descriptors := parseDescriptors([]byte{0x0a, 0x04, 'e', 'n', 'g', 0, 0x6a, 0x00, 0x7a, 0x10, 0x6a})
+	expected := []descriptor{{Tag: 0x0a, Data: []byte{'e', 'n', 'g', 0}}, {Tag: 0x6a, Data: []byte{}}}
+	assert.Exactly(t, expected, descriptors, "should return descriptors without the truncated one")
+	assert.Nil(t, parseDescriptors([]byte{0x0a}), "should return nil for too short loop")
+}
+
+func TestESKind(t *testing.T) {
+	assert.Exactly(t, VideoKind, esKind(0x1b, nil), "should detect video by stream type")
+	assert.Exactly(t, AudioKind, esKind(0x0f, nil), "should detect audio by stream type")
+	assert.Exactly(t, AudioKind, esKind(0x06, []byte{0x0a, 0x7a}), "should detect audio by descriptor")
+	assert.Exactly(t, DataKind, esKind(0x06, []byte{0x56}), "should consider private data with other descriptor data")
+	assert.Exactly(t, DataKind, esKind(0x05, nil), "should consider other stream types data")
+}
+
+func TestESCodec(t *testing.T) {
+	assert.Exactly(t, "hevc", esCodec(0x24, nil), "should find codec by stream type")
+	assert.Exactly(t, "eac3", esCodec(0x06, []byte{0x0a, 0x7a}), "should find codec by descriptor")
+	assert.Exactly(t, "type_0x06", esCodec(0x06, []byte{0x0a}), "should name unknown private data by stream type")
+	assert.Exactly(t, "type_0x99", esCodec(0x99, nil), "should name unknown stream type")
+}
+
+func TestDVBText(t *testing.T) {
+	assert.Exactly(t, "Channel", dvbText([]byte("Channel ")), "should return text of default table as is")
+	assert.Exactly(t, "Channel", dvbText(append([]byte{0x05}, "Channel"...)), "should remove table selection byte")
+	assert.Exactly(t, "Channel", dvbText(append([]byte{0x10, 0x00, 0x05}, "Channel"...)),
+		"should remove three bytes selecting ISO/IEC 8859 part")
+	assert.Exactly(t, "HD Channel", dvbText([]byte{'H', 'D', 0x86, ' ', 'C', 'h', 'a', 'n', 'n', 'e', 'l', 0x87}),
+		"should remove control codes")
+	assert.Exactly(t, "", dvbText([]byte{0x10, 0x00}), "should return empty string for truncated text")
+}
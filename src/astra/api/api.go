@@ -5,12 +5,18 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"sync"
+	"time"
 
 	"m3u_merge_astra/astra"
+	"m3u_merge_astra/cfg"
 	"m3u_merge_astra/util/logger"
+	"m3u_merge_astra/util/network"
 
 	json "github.com/SCP002/jsonexraw"
+	"github.com/alitto/pond"
 	"github.com/cockroachdb/errors"
+	"github.com/go-co-op/gocron"
 	"github.com/samber/lo"
 )
 
@@ -45,9 +51,30 @@ type setCategoryResp struct {
 	Error  string `json:"error"`
 }
 
+// Handler represents source of astra config and destination of changes to it
+type Handler interface {
+	FetchCfg() (astra.Cfg, error)
+	SetCategories(idxCategoryMap []lo.Entry[int, astra.Category], stopOnErr bool) []CategoryResult
+	SetStreams(streams []astra.Stream, stopOnErr bool) []StreamResult
+}
+
+// CategoryResult represents result of setting category
+type CategoryResult struct {
+	Idx      int // Index of the category as passed to SetCategories
+	Category astra.Category
+	Err      error // Nil if category was set successfully
+}
+
+// StreamResult represents result of setting stream
+type StreamResult struct {
+	Stream astra.Stream
+	Err    error // Nil if stream was set successfully
+}
+
 // handler holds dependencies and credentials to access astra API
 type handler struct {
 	log        *logger.Logger
+	cfg        cfg.General
 	httpClient *http.Client
 	address    string
 	user       string
@@ -55,11 +82,15 @@ type handler struct {
 }
 
 // NewHandler returns new astra API handler
-func NewHandler(log *logger.Logger, httpClient *http.Client, address string, user string, password string) handler {
-	return handler{log: log, httpClient: httpClient, address: address, user: user, password: password}
+func NewHandler(log *logger.Logger, cfg cfg.General, httpClient *http.Client, address string, user string,
+	password string) handler {
+	return handler{log: log, cfg: cfg, httpClient: httpClient, address: address, user: user, password: password}
 }
 
-// SetCategories makes a requests to API setting categories by indexes as defined in <idxCategoryMap> synchronously.
+// SetCategories makes a requests to API setting categories by indexes as defined in <idxCategoryMap> synchronously and
+// returns result for every category sent.
+//
+// If <stopOnErr> is true, categories after the first failed one are not sent.
 //
 // Use negative key (index) in <idxCategoryMap> to create new category.
 //
@@ -70,19 +101,26 @@ func NewHandler(log *logger.Logger, httpClient *http.Client, address string, use
 //   - {Key: -1, Value: Category: {Name: "B"}},
 //   - {Key: 1, Remove: true}, // Removes category B
 //   - {Key: 0, Remove: true}, // Removes category A
-func (h handler) SetCategories(idxCategoryMap []lo.Entry[int, astra.Category]) {
+func (h handler) SetCategories(idxCategoryMap []lo.Entry[int, astra.Category], stopOnErr bool) []CategoryResult {
 	h.log.Info("Sending changed categories to astra")
 
+	results := []CategoryResult{}
 	for _, entry := range idxCategoryMap {
 		err := h.SetCategory(entry.Key, entry.Value)
+		results = append(results, CategoryResult{Idx: entry.Key, Category: entry.Value, Err: err})
 		if err == nil {
 			h.log.InfoFi("Successfully set category", "name", entry.Value.Name, "groups",
 				fmt.Sprintf("%+v", entry.Value.Groups), "remove", entry.Value.Remove)
-		} else {
-			h.log.ErrorFi("Failed to set category", "name", entry.Value.Name, "groups",
-				fmt.Sprintf("%+v", entry.Value.Groups), "remove", entry.Value.Remove, "error", err)
+			continue
+		}
+		h.log.ErrorFi("Failed to set category", "name", entry.Value.Name, "groups",
+			fmt.Sprintf("%+v", entry.Value.Groups), "remove", entry.Value.Remove, "error", err)
+		if stopOnErr {
+			break
 		}
 	}
+
+	return results
 }
 
 // SetCategory makes a request to API setting category with <idx> to <category>.
@@ -101,27 +139,99 @@ func (h handler) SetCategory(idx int, category astra.Category) error {
 	if err != nil {
 		return errors.Wrap(err, "Invalid API response")
 	}
-	if resp.Status != "ok" {
-		return errors.Wrap(err, fmt.Sprintf("API responded with bad status (%v)", resp.Status))
-	}
 	if resp.Error != "" {
-		return errors.Wrap(err, fmt.Sprintf("API responded with error (%v)", resp.Error))
+		return errors.Newf("API responded with error (%v)", resp.Error)
+	}
+	if resp.Status != "ok" {
+		return errors.Newf("API responded with bad status (%v)", resp.Status)
 	}
 
 	return nil
 }
 
-// SetStreams makes a requests to API setting <streams> synchronously
-func (h handler) SetStreams(streams []astra.Stream) {
+// SetStreams makes a requests to API setting <streams> and returns result for every stream sent in the same order.
+//
+// Up to cfg.AstraAPIMaxConns requests are made simultaneously, limited to cfg.AstraAPIMaxRPS requests per second.
+// Requests failed with temporary network errors are retried up to cfg.AstraAPIMaxRetries times with exponential
+// backoff starting from cfg.AstraAPIRetryDelay.
+//
+// If <stopOnErr> is true, streams after the first failed one are not sent. Streams which were already being sent at
+// the moment of failure are still included in the results.
+func (h handler) SetStreams(streams []astra.Stream, stopOnErr bool) []StreamResult {
 	h.log.Info("Sending changed streams to astra")
 
-	for _, stream := range streams {
+	pool := pond.New(max(h.cfg.AstraAPIMaxConns, 1), 0, pond.MinWorkers(0))
+	limiter := newLimiter(h.cfg.AstraAPIMaxRPS)
+	var mut sync.Mutex
+	results := make([]*StreamResult, len(streams))
+	done := 0
+	failed := false
+
+	// getProgress returns formatted progress of streams sent
+	getProgress := func() string {
+		mut.Lock()
+		defer mut.Unlock()
+		return fmt.Sprintf("%v / %v (%v%%)", done, len(streams), (done*100)/max(len(streams), 1))
+	}
+
+	progressScheduler := gocron.NewScheduler(time.UTC)
+	_, err := progressScheduler.Every(30).Seconds().WaitForSchedule().Do(func() {
+		h.log.InfoFi("Sending changed streams to astra", "progress", getProgress())
+	})
+	if err != nil {
+		h.log.Errorf("Failed to print progress of sending streams: %v", err)
+	}
+	progressScheduler.StartAsync()
+
+	for idx, stream := range streams {
+		pool.Submit(func() {
+			mut.Lock()
+			skip := stopOnErr && failed
+			mut.Unlock()
+			if skip {
+				return
+			}
+
+			limiter.wait()
+			err := h.setStreamWithRetries(stream)
+			if err == nil {
+				h.log.InfoFi("Successfully set stream", "ID", stream.ID, "name", stream.Name)
+			} else {
+				h.log.ErrorFi("Failed to set stream", "ID", stream.ID, "name", stream.Name, "error", err)
+			}
+
+			mut.Lock()
+			results[idx] = &StreamResult{Stream: stream, Err: err}
+			failed = failed || err != nil
+			done++
+			mut.Unlock()
+		})
+	}
+
+	pool.StopAndWait()
+	progressScheduler.Stop()
+	limiter.stop()
+
+	return lo.FilterMap(results, func(res *StreamResult, _ int) (StreamResult, bool) {
+		return lo.FromPtr(res), res != nil
+	})
+}
+
+// setStreamWithRetries makes a request to API setting <stream>, retrying it on temporary network errors.
+//
+// Delay between retries starts from cfg.AstraAPIRetryDelay and doubles on every retry.
+func (h handler) setStreamWithRetries(stream astra.Stream) error {
+	delay := h.cfg.AstraAPIRetryDelay
+	for attempt := 1; ; attempt++ {
 		err := h.SetStream(stream.ID, stream)
-		if err == nil {
-			h.log.InfoFi("Successfully set stream", "ID", stream.ID, "name", stream.Name)
-		} else {
-			h.log.ErrorFi("Failed to set stream", "ID", stream.ID, "name", stream.Name, "error", err)
+		errType := network.GetErrType(err)
+		if attempt > h.cfg.AstraAPIMaxRetries || !errType.IsTemporary() {
+			return err
 		}
+		h.log.WarnFi("Failed to set stream, retrying", "ID", stream.ID, "name", stream.Name, "attempt", attempt,
+			"delay", delay.String(), "reason", string(errType))
+		time.Sleep(delay)
+		delay *= 2
 	}
 }
 
@@ -137,11 +247,11 @@ func (h handler) SetStream(id string, stream astra.Stream) error {
 	if err != nil {
 		return errors.Wrap(err, "Invalid API response")
 	}
-	if resp.Status != "ok" {
-		return errors.Wrap(err, fmt.Sprintf("API responded with bad status (%v)", resp.Status))
-	}
 	if resp.Error != "" {
-		return errors.Wrap(err, fmt.Sprintf("API responded with error (%v)", resp.Error))
+		return errors.Newf("API responded with error (%v)", resp.Error)
+	}
+	if resp.Status != "ok" {
+		return errors.Newf("API responded with bad status (%v)", resp.Status)
 	}
 
 	return nil
@@ -7,6 +7,9 @@ import (
 	"m3u_merge_astra/util/logger"
 	"m3u_merge_astra/util/network"
 	"m3u_merge_astra/util/rnd"
+	"net/http"
+	"net/http/httptest"
+	"sync"
 	"testing"
 	"time"
 
@@ -19,22 +22,25 @@ import (
 func TestNewHandler(t *testing.T) {
 	log := logger.New(logger.DebugLevel)
 	httpClient := network.NewHttpClient(time.Second * 3)
+	generalCfg := cfg.NewDefCfg().General
 
 	expected := handler{
 		log:        log,
+		cfg:        generalCfg,
 		httpClient: httpClient,
 		address:    "127.0.0.1",
 		user:       "user",
 		password:   "pass",
 	}
-	assert.Exactly(t, expected, NewHandler(log, httpClient, "127.0.0.1", "user", "pass"), "should initialize handler")
+	actual := NewHandler(log, generalCfg, httpClient, "127.0.0.1", "user", "pass")
+	assert.Exactly(t, expected, actual, "should initialize handler")
 }
 
 // Requires a running astra
 func TestSetCategories(t *testing.T) {
 	log := logger.New(logger.DebugLevel)
 	httpClient := network.NewHttpClient(time.Second * 3)
-	apiHandler := NewHandler(log, httpClient, "http://127.0.0.1:8000", "admin", "admin")
+	apiHandler := NewHandler(log, cfg.NewDefCfg().General, httpClient, "http://127.0.0.1:8000", "admin", "admin")
 
 	// Remove existing categories
 	config, err := apiHandler.FetchCfg()
@@ -84,7 +90,11 @@ func TestSetCategories(t *testing.T) {
 			Value: astra.Category{Remove: true},
 		},
 	}
-	apiHandler.SetCategories(idxCategoryMap)
+	results := apiHandler.SetCategories(idxCategoryMap, false)
+	assert.Len(t, results, len(idxCategoryMap), "should return result for every category")
+	for _, res := range results {
+		assert.NoError(t, res.Err, "should set category")
+	}
 
 	// Check
 	config, err = apiHandler.FetchCfg()
@@ -101,14 +111,14 @@ func TestSetCategories(t *testing.T) {
 	out := capturer.CaptureStderr(func() {
 		log := logger.New(logger.DebugLevel)
 		httpClient := network.NewHttpClient(time.Second * 3)
-		apiHandler := NewHandler(log, httpClient, "http://127.0.0.1:8000", "admin", "admin")
+		apiHandler := NewHandler(log, cfg.NewDefCfg().General, httpClient, "http://127.0.0.1:8000", "admin", "admin")
 		idxCategoryMap = []lo.Entry[int, astra.Category]{
 			{
 				Key:   -1,
 				Value: astra.Category{Name: "Category 0", Groups: []astra.Group{{Name: "Group 0"}, {Name: "Group 01"}}},
 			},
 		}
-		apiHandler.SetCategories(idxCategoryMap)
+		apiHandler.SetCategories(idxCategoryMap, false)
 	})
 	assert.Contains(t, out, `Successfully set category: name "Category 0", groups "[{Name:Group 0 Remove:false} `+
 		`{Name:Group 01 Remove:false}]", remove "false"`)
@@ -119,7 +129,7 @@ func TestSetCategories(t *testing.T) {
 func TestSetCategory(t *testing.T) {
 	log := logger.New(logger.DebugLevel)
 	httpClient := network.NewHttpClient(time.Second * 3)
-	apiHandler := NewHandler(log, httpClient, "http://127.0.0.1:8000", "admin", "admin")
+	apiHandler := NewHandler(log, cfg.NewDefCfg().General, httpClient, "http://127.0.0.1:8000", "admin", "admin")
 
 	astraCfg, err := apiHandler.FetchCfg()
 	assert.NoError(t, err, "should not return error")
@@ -172,7 +182,7 @@ func TestSetCategory(t *testing.T) {
 func TestSetStreams(t *testing.T) {
 	log := logger.New(logger.DebugLevel)
 	httpClient := network.NewHttpClient(time.Second * 3)
-	apiHandler := NewHandler(log, httpClient, "http://127.0.0.1:8000", "admin", "admin")
+	apiHandler := NewHandler(log, cfg.NewDefCfg().General, httpClient, "http://127.0.0.1:8000", "admin", "admin")
 
 	// Remove existing streams
 	config, err := apiHandler.FetchCfg()
@@ -180,7 +190,7 @@ func TestSetStreams(t *testing.T) {
 	apiHandler.SetStreams(lo.Map(config.Streams, func(s astra.Stream, _ int) astra.Stream {
 		s.Remove = true
 		return s
-	}))
+	}), false)
 
 	// Set
 	streams := []astra.Stream{
@@ -188,7 +198,9 @@ func TestSetStreams(t *testing.T) {
 		{ID: "0002", Name: "Name 2", Type: string(cfg.SPTS)},
 		{ID: "0003", Name: "Name 3", Type: string(cfg.SPTS)},
 	}
-	apiHandler.SetStreams(streams)
+	results := apiHandler.SetStreams(streams, false)
+	assert.Exactly(t, []StreamResult{{Stream: streams[0]}, {Stream: streams[1]}, {Stream: streams[2]}}, results,
+		"should return successful result for every stream")
 
 	// Check
 	config, err = apiHandler.FetchCfg()
@@ -199,11 +211,11 @@ func TestSetStreams(t *testing.T) {
 	out := capturer.CaptureStderr(func() {
 		log := logger.New(logger.DebugLevel)
 		httpClient := network.NewHttpClient(time.Second * 3)
-		apiHandler := NewHandler(log, httpClient, "http://127.0.0.1:8000", "admin", "admin")
+		apiHandler := NewHandler(log, cfg.NewDefCfg().General, httpClient, "http://127.0.0.1:8000", "admin", "admin")
 		streams := []astra.Stream{
 			{ID: "0000", Name: "Name 0", Type: string(cfg.SPTS)},
 		}
-		apiHandler.SetStreams(streams)
+		apiHandler.SetStreams(streams, false)
 	})
 	assert.Contains(t, out, `Successfully set stream: ID "0000", name "Name 0"`)
 	assert.NotContains(t, out, "Failed")
@@ -213,7 +225,7 @@ func TestSetStreams(t *testing.T) {
 func TestSetStream(t *testing.T) {
 	log := logger.New(logger.DebugLevel)
 	httpClient := network.NewHttpClient(time.Second * 3)
-	apiHandler := NewHandler(log, httpClient, "http://127.0.0.1:8000", "admin", "admin")
+	apiHandler := NewHandler(log, cfg.NewDefCfg().General, httpClient, "http://127.0.0.1:8000", "admin", "admin")
 
 	// Set
 	streamName := fmt.Sprintf("Stream %v", rnd.String(4, false, true))
@@ -245,11 +257,114 @@ func TestSetStream(t *testing.T) {
 	}), "returned config should contain data from stream set")
 }
 
+func TestSetRejected(t *testing.T) {
+	respBody := ""
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(respBody))
+	}))
+	defer server.Close()
+
+	log := logger.New(logger.DebugLevel)
+	apiHandler := NewHandler(log, cfg.NewDefCfg().General, server.Client(), server.URL, "admin", "admin")
+
+	respBody = `{"set-stream": "ok"}`
+	assert.NoError(t, apiHandler.SetStream("0000", astra.Stream{ID: "0000"}), "should not return error")
+
+	respBody = `{"set-stream": "fail"}`
+	err := apiHandler.SetStream("0000", astra.Stream{ID: "0000"})
+	assert.ErrorContains(t, err, "API responded with bad status (fail)", "should return error on bad status")
+
+	respBody = `{"set-stream": "fail", "error": "wrong stream"}`
+	err = apiHandler.SetStream("0000", astra.Stream{ID: "0000"})
+	assert.ErrorContains(t, err, "API responded with error (wrong stream)", "should return error with API message")
+
+	respBody = `{"set-category": "fail"}`
+	err = apiHandler.SetCategory(-1, astra.Category{Name: "Category"})
+	assert.ErrorContains(t, err, "API responded with bad status (fail)", "should return error on bad status")
+
+	// Test stopping on error
+	generalCfg := cfg.NewDefCfg().General
+	generalCfg.AstraAPIMaxConns = 1
+	apiHandler = NewHandler(log, generalCfg, server.Client(), server.URL, "admin", "admin")
+
+	respBody = `{"set-stream": "fail"}`
+	streams := []astra.Stream{{ID: "0000"}, {ID: "0001"}}
+	results := apiHandler.SetStreams(streams, true)
+	assert.Len(t, results, 1, "should stop on the first failed stream")
+	assert.Error(t, results[0].Err, "should return error in result")
+
+	results = apiHandler.SetStreams(streams, false)
+	assert.Len(t, results, 2, "should send all streams")
+
+	respBody = `{"set-category": "fail"}`
+	catResults := apiHandler.SetCategories([]lo.Entry[int, astra.Category]{{Key: -1}, {Key: -1}}, true)
+	assert.Len(t, catResults, 1, "should stop on the first failed category")
+	assert.Error(t, catResults[0].Err, "should return error in result")
+}
+
+func TestSetStreamsConcurrent(t *testing.T) {
+	var mut sync.Mutex
+	inFlight, maxInFlight, requests := 0, 0, 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mut.Lock()
+		requests++
+		// Drop connection on the first request to test retries
+		if requests == 1 {
+			mut.Unlock()
+			conn, _, _ := w.(http.Hijacker).Hijack()
+			_ = conn.Close()
+			return
+		}
+		inFlight++
+		maxInFlight = max(maxInFlight, inFlight)
+		mut.Unlock()
+
+		time.Sleep(time.Millisecond * 50)
+
+		mut.Lock()
+		inFlight--
+		mut.Unlock()
+		_, _ = w.Write([]byte(`{"set-stream": "ok"}`))
+	}))
+	defer server.Close()
+
+	generalCfg := cfg.NewDefCfg().General
+	generalCfg.AstraAPIMaxConns = 3
+	generalCfg.AstraAPIMaxRPS = 0
+	generalCfg.AstraAPIRetryDelay = time.Millisecond
+
+	streams := lo.Times(9, func(idx int) astra.Stream {
+		return astra.Stream{ID: fmt.Sprintf("%04d", idx), Name: fmt.Sprintf("Stream %v", idx)}
+	})
+
+	var results []StreamResult
+	out := capturer.CaptureStderr(func() {
+		log := logger.New(logger.DebugLevel)
+		apiHandler := NewHandler(log, generalCfg, server.Client(), server.URL, "admin", "admin")
+		results = apiHandler.SetStreams(streams, false)
+	})
+
+	expected := lo.Map(streams, func(s astra.Stream, _ int) StreamResult { return StreamResult{Stream: s} })
+	assert.Exactly(t, expected, results, "should return successful results in the order of streams")
+	assert.Exactly(t, 3, maxInFlight, "should make this amount of simultaneous requests")
+	assert.Exactly(t, 10, requests, "should retry dropped request")
+	assert.Regexp(t, `Failed to set stream, retrying: ID "\d{4}", name "Stream \d", attempt "1", delay "1ms", `+
+		`reason "Connection reset"`, out)
+	assert.NotContains(t, out, "Failed to set stream:")
+
+	// Test rate limit
+	generalCfg.AstraAPIMaxRPS = 50
+	start := time.Now()
+	_ = NewHandler(logger.New(logger.DebugLevel), generalCfg, server.Client(), server.URL, "admin", "admin").
+		SetStreams(streams, false)
+	assert.GreaterOrEqual(t, time.Since(start), time.Millisecond*180, "should limit requests rate")
+}
+
 // Requires a running astra
 func TestFetchCfg(t *testing.T) {
 	log := logger.New(logger.DebugLevel)
 	httpClient := network.NewHttpClient(time.Second * 3)
-	apiHandler := NewHandler(log, httpClient, "http://127.0.0.1:8000", "admin", "admin")
+	apiHandler := NewHandler(log, cfg.NewDefCfg().General, httpClient, "http://127.0.0.1:8000", "admin", "admin")
 	astraCfg, err := apiHandler.FetchCfg()
 	assert.NotEmpty(t, astraCfg, "should return not empty config")
 	assert.NoError(t, err, "should not return error")
@@ -259,7 +374,7 @@ func TestFetchCfg(t *testing.T) {
 func TestRequest(t *testing.T) {
 	log := logger.New(logger.DebugLevel)
 	httpClient := network.NewHttpClient(time.Second * 3)
-	apiHandler := NewHandler(log, httpClient, "http://127.0.0.1:8000", "admin", "admin")
+	apiHandler := NewHandler(log, cfg.NewDefCfg().General, httpClient, "http://127.0.0.1:8000", "admin", "admin")
 	resp, err := apiHandler.request("POST", "/control/", loadReq{Cmd: "sessions"})
 	assert.Contains(t, string(resp), "sessions", "should return sessions list")
 	assert.NoError(t, err, "should not return error")
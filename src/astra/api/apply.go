@@ -0,0 +1,136 @@
+package api
+
+import (
+	"m3u_merge_astra/astra"
+	"m3u_merge_astra/util/logger"
+
+	"github.com/samber/lo"
+)
+
+// Outcome represents overall outcome of applying changes to astra
+type Outcome int
+
+const (
+	Applied         Outcome = iota // All changes were applied
+	NothingChanged                 // There was nothing to apply or applying was declined
+	PartiallyFailed                // Some changes were not applied
+	Aborted                        // Some changes were not applied and applied ones were reverted
+)
+
+// String returns human-readable representation of <o>
+func (o Outcome) String() string {
+	switch o {
+	case Applied:
+		return "applied"
+	case NothingChanged:
+		return "nothing changed"
+	case PartiallyFailed:
+		return "partially failed"
+	case Aborted:
+		return "aborted"
+	default:
+		return "unknown"
+	}
+}
+
+// Restorer represents builder of changes turning current astra config into a snapshot (see astra.repo)
+type Restorer interface {
+	RestoreCategories(current, snapshot []astra.Category) []lo.Entry[int, astra.Category]
+	RestoreStreams(current, snapshot []astra.Stream) []astra.Stream
+}
+
+// Summary represents results of applying changes to astra
+type Summary struct {
+	Categories []CategoryResult
+	Streams    []StreamResult
+	Outcome    Outcome
+}
+
+// FailedCategories returns results of categories which were not set
+func (s Summary) FailedCategories() []CategoryResult {
+	return lo.Filter(s.Categories, func(res CategoryResult, _ int) bool { return res.Err != nil })
+}
+
+// FailedStreams returns results of streams which were not set
+func (s Summary) FailedStreams() []StreamResult {
+	return lo.Filter(s.Streams, func(res StreamResult, _ int) bool { return res.Err != nil })
+}
+
+// Apply sends <idxCategoryMap> and <streams> to astra using handler <h> and returns summary of results.
+//
+// Streams which are not valid (see astra.Stream.Validate) are not sent and reported as failed.
+//
+// If <atomic> is true, stops on the first failure and reverts already applied changes by restoring <original> config
+// with the help of <restorer>. Outcome is Aborted if reverting succeeded and PartiallyFailed otherwise. If any stream
+// is not valid, nothing is sent and outcome is Aborted.
+func Apply(log *logger.Logger, h Handler, restorer Restorer, original astra.Cfg,
+	idxCategoryMap []lo.Entry[int, astra.Category], streams []astra.Stream, atomic bool) (out Summary) {
+	if len(idxCategoryMap) == 0 && len(streams) == 0 {
+		log.Info("Nothing to apply to astra")
+		out.Outcome = NothingChanged
+		return
+	}
+
+	invalid := lo.FilterMap(streams, func(s astra.Stream, _ int) (StreamResult, bool) {
+		err := lo.Ternary(s.Remove, nil, s.Validate())
+		return StreamResult{Stream: s, Err: err}, err != nil
+	})
+	streams = lo.Reject(streams, func(s astra.Stream, _ int) bool {
+		return lo.ContainsBy(invalid, func(res StreamResult) bool { return res.Stream.ID == s.ID })
+	})
+
+	if atomic && len(invalid) > 0 {
+		out.Streams = invalid
+		out.Outcome = Aborted
+	} else {
+		out.Categories = h.SetCategories(idxCategoryMap, atomic)
+		if !atomic || len(out.FailedCategories()) == 0 {
+			out.Streams = append(h.SetStreams(streams, atomic), invalid...)
+		}
+	}
+
+	failedCats, failedStreams := out.FailedCategories(), out.FailedStreams()
+	switch {
+	case out.Outcome == Aborted:
+		log.Error("Nothing is sent to astra because some streams are not valid")
+	case len(failedCats) == 0 && len(failedStreams) == 0:
+		out.Outcome = Applied
+	case atomic && revert(log, h, restorer, original):
+		out.Outcome = Aborted
+	default:
+		out.Outcome = PartiallyFailed
+	}
+
+	for _, res := range failedCats {
+		log.ErrorFi("Category is not set", "name", res.Category.Name, "error", res.Err)
+	}
+	for _, res := range failedStreams {
+		log.ErrorFi("Stream is not set", "ID", res.Stream.ID, "name", res.Stream.Name, "error", res.Err)
+	}
+	log.InfoFi("Applied changes to astra", "outcome", out.Outcome, "categories sent", len(out.Categories),
+		"categories failed", len(failedCats), "streams sent", len(out.Streams), "streams failed", len(failedStreams))
+
+	return
+}
+
+// revert fetches current astra config using handler <h> and sends changes turning it back into <original> config.
+//
+// Returns true if every change was reverted.
+func revert(log *logger.Logger, h Handler, restorer Restorer, original astra.Cfg) bool {
+	log.Warn("Reverting applied changes")
+
+	current, err := h.FetchCfg()
+	if err != nil {
+		log.ErrorFi("Failed to revert applied changes", "error", err)
+		return false
+	}
+	catResults := h.SetCategories(restorer.RestoreCategories(current.Categories, original.Categories), false)
+	streamResults := h.SetStreams(restorer.RestoreStreams(current.Streams, original.Streams), false)
+
+	ok := !lo.ContainsBy(catResults, func(res CategoryResult) bool { return res.Err != nil }) &&
+		!lo.ContainsBy(streamResults, func(res StreamResult) bool { return res.Err != nil })
+	if !ok {
+		log.Error("Failed to revert some of applied changes")
+	}
+	return ok
+}
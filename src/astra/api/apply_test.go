@@ -0,0 +1,162 @@
+package api
+
+import (
+	"testing"
+
+	"m3u_merge_astra/astra"
+	"m3u_merge_astra/cfg"
+	"m3u_merge_astra/util/copier"
+	"m3u_merge_astra/util/logger"
+
+	"github.com/cockroachdb/errors"
+	"github.com/samber/lo"
+	"github.com/stretchr/testify/assert"
+	"github.com/zenizh/go-capturer"
+)
+
+// fakeHandler represents in-memory astra config failing to set streams with specific IDs and silently dropping streams
+// with IDs from dropIDs as many times as specified there
+type fakeHandler struct {
+	cfg       *astra.Cfg
+	failIDs   []string
+	dropIDs   map[string]int
+	failFetch bool
+}
+
+func (h fakeHandler) FetchCfg() (astra.Cfg, error) {
+	if h.failFetch {
+		return astra.Cfg{}, errors.New("Fetch failed")
+	}
+	return copier.MustDeep(*h.cfg), nil
+}
+
+func (h fakeHandler) SetCategories(idxCategoryMap []lo.Entry[int, astra.Category], stopOnErr bool) []CategoryResult {
+	*h.cfg = h.cfg.ApplyCategories(idxCategoryMap)
+	return lo.Map(idxCategoryMap, func(entry lo.Entry[int, astra.Category], _ int) CategoryResult {
+		return CategoryResult{Idx: entry.Key, Category: entry.Value}
+	})
+}
+
+func (h fakeHandler) SetStreams(streams []astra.Stream, stopOnErr bool) (out []StreamResult) {
+	for _, s := range streams {
+		if lo.Contains(h.failIDs, s.ID) {
+			out = append(out, StreamResult{Stream: s, Err: errors.New("Rejected")})
+			if stopOnErr {
+				break
+			}
+			continue
+		}
+		if h.dropIDs[s.ID] > 0 {
+			h.dropIDs[s.ID]--
+			out = append(out, StreamResult{Stream: s})
+			continue
+		}
+		*h.cfg = h.cfg.ApplyStreams([]astra.Stream{s})
+		out = append(out, StreamResult{Stream: s})
+	}
+	return
+}
+
+func TestApply(t *testing.T) {
+	log := logger.New(logger.DebugLevel)
+	restorer := astra.NewRepo(log, cfg.NewDefCfg())
+
+	original := astra.Cfg{
+		Categories: []astra.Category{{Name: "Category 1", Groups: []astra.Group{{Name: "Group 1"}}}},
+		Streams:    []astra.Stream{{ID: "0", Name: "Stream 0"}, {ID: "1", Name: "Stream 1"}},
+	}
+	catMap := []lo.Entry[int, astra.Category]{
+		{Key: -1, Value: astra.Category{Name: "Category 2", Groups: []astra.Group{{Name: "Group 2"}}}},
+	}
+	streams := []astra.Stream{
+		{ID: "0", Name: "Stream 0 renamed"},
+		{ID: "1", Name: "Stream 1", Remove: true},
+		{ID: "2", Name: "Stream 2"},
+	}
+	newHandler := func(failIDs ...string) fakeHandler {
+		return fakeHandler{cfg: lo.ToPtr(copier.TestDeep(t, original)), failIDs: failIDs}
+	}
+
+	// Test nothing to apply
+	h := newHandler()
+	summary := Apply(log, h, restorer, original, nil, nil, false)
+	assert.Exactly(t, Summary{Outcome: NothingChanged}, summary, "should not send anything")
+	assert.Exactly(t, original, *h.cfg, "should not change config")
+
+	// Test applying all changes
+	h = newHandler()
+	summary = Apply(log, h, restorer, original, catMap, streams, false)
+	assert.Exactly(t, Applied, summary.Outcome, "should apply all changes")
+	assert.Len(t, summary.Categories, 1, "should return result for every category")
+	assert.Len(t, summary.Streams, 3, "should return result for every stream")
+	assert.Empty(t, summary.FailedCategories(), "should not return failed categories")
+	assert.Empty(t, summary.FailedStreams(), "should not return failed streams")
+	expected := astra.Cfg{
+		Categories: []astra.Category{
+			{Name: "Category 1", Groups: []astra.Group{{Name: "Group 1"}}},
+			{Name: "Category 2", Groups: []astra.Group{{Name: "Group 2"}}},
+		},
+		Streams: []astra.Stream{{ID: "0", Name: "Stream 0 renamed"}, {ID: "2", Name: "Stream 2"}},
+	}
+	assert.Exactly(t, expected, *h.cfg, "should apply all changes to config")
+
+	// Test partial failure
+	h = newHandler("1")
+	summary = Apply(log, h, restorer, original, catMap, streams, false)
+	assert.Exactly(t, PartiallyFailed, summary.Outcome, "should report partial failure")
+	assert.Len(t, summary.Streams, 3, "should send all streams")
+	assert.Len(t, summary.FailedStreams(), 1, "should return failed streams")
+	assert.Exactly(t, "1", summary.FailedStreams()[0].Stream.ID, "should return this failed stream")
+	expected.Streams = []astra.Stream{{ID: "0", Name: "Stream 0 renamed"}, {ID: "1", Name: "Stream 1"},
+		{ID: "2", Name: "Stream 2"}}
+	assert.Exactly(t, expected, *h.cfg, "should apply all changes but failed ones")
+
+	// Test atomic mode
+	h = newHandler("1")
+	summary = Apply(log, h, restorer, original, catMap, streams, true)
+	assert.Exactly(t, Aborted, summary.Outcome, "should report aborted apply")
+	assert.Len(t, summary.Streams, 2, "should stop on the first failed stream")
+	assert.Exactly(t, original, *h.cfg, "should revert applied changes")
+
+	// Test atomic mode with failed revert
+	h = newHandler("1")
+	h.failFetch = true
+	summary = Apply(log, h, restorer, original, catMap, streams, true)
+	assert.Exactly(t, PartiallyFailed, summary.Outcome, "should report partial failure if revert failed")
+
+	// Test invalid streams
+	invalidStreams := append(copier.TestDeep(t, streams), astra.Stream{ID: "3", Name: "Stream 3", Timeout: lo.ToPtr(astra.Number(-1))})
+	h = newHandler()
+	summary = Apply(log, h, restorer, original, catMap, invalidStreams, false)
+	assert.Exactly(t, PartiallyFailed, summary.Outcome, "should report partial failure")
+	assert.Len(t, summary.FailedStreams(), 1, "should return invalid stream as failed")
+	assert.Exactly(t, "3", summary.FailedStreams()[0].Stream.ID, "should return this failed stream")
+	assert.ErrorContains(t, summary.FailedStreams()[0].Err, "timeout -1 is negative")
+	assert.Exactly(t, expected.Categories, h.cfg.Categories, "should apply categories")
+	assert.Len(t, h.cfg.Streams, 2, "should apply valid streams")
+
+	h = newHandler()
+	summary = Apply(log, h, restorer, original, catMap, invalidStreams, true)
+	assert.Exactly(t, Aborted, summary.Outcome, "should abort in atomic mode")
+	assert.Empty(t, summary.Categories, "should not send categories")
+	assert.Len(t, summary.Streams, 1, "should return only invalid streams")
+	assert.Exactly(t, original, *h.cfg, "should not change config")
+
+	// Test log output
+	out := capturer.CaptureStderr(func() {
+		log := logger.New(logger.DebugLevel)
+		_ = Apply(log, newHandler("1"), restorer, original, catMap, streams, true)
+	})
+	assert.Contains(t, out, "Reverting applied changes")
+	assert.Contains(t, out, `Stream is not set: ID "1", name "Stream 1", error "Rejected"`)
+	assert.Contains(t, out, `Applied changes to astra: outcome "aborted", categories sent "1", categories failed "0", `+
+		`streams sent "2", streams failed "1"`)
+}
+
+func TestOutcomeString(t *testing.T) {
+	assert.Exactly(t, "applied", Applied.String())
+	assert.Exactly(t, "nothing changed", NothingChanged.String())
+	assert.Exactly(t, "partially failed", PartiallyFailed.String())
+	assert.Exactly(t, "aborted", Aborted.String())
+	assert.Exactly(t, "unknown", Outcome(-1).String())
+}
@@ -0,0 +1,92 @@
+package api
+
+import (
+	"fmt"
+
+	"m3u_merge_astra/astra"
+	"m3u_merge_astra/util/logger"
+
+	"github.com/samber/lo"
+)
+
+// fileHandler holds dependencies and paths to work with astra config file instead of astra API
+type fileHandler struct {
+	log     *logger.Logger
+	inPath  string
+	outPath string
+	cfg     *astra.Cfg // Last fetched or modified config
+	written *bool      // Was output file written?
+}
+
+// NewFileHandler returns new handler which reads astra config from JSON file at <inPath> and writes modified config to
+// JSON file at <outPath>.
+//
+// If <outPath> is empty, modified config is written back to <inPath>.
+func NewFileHandler(log *logger.Logger, inPath string, outPath string) fileHandler {
+	return fileHandler{log: log, inPath: inPath, outPath: lo.Ternary(outPath != "", outPath, inPath), cfg: &astra.Cfg{},
+		written: lo.ToPtr(false)}
+}
+
+// FetchCfg returns astra config read from input file or from output file if it was already written, so refetching
+// config after sending changes returns config with the changes as astra API does
+func (h fileHandler) FetchCfg() (astra.Cfg, error) {
+	path := lo.Ternary(*h.written, h.outPath, h.inPath)
+	h.log.InfoFi("Reading astra config file", "path", path)
+
+	cfg, err := astra.ReadCfgFile(path)
+	if err != nil {
+		return astra.Cfg{}, err
+	}
+	*h.cfg = cfg
+
+	return cfg, nil
+}
+
+// SetCategories sets categories by indexes as defined in <idxCategoryMap> in the last fetched config, writes it to
+// output file and returns result for every category.
+//
+// All categories are written at once, so they either all succeed or all fail with the same error regardless of
+// <stopOnErr>.
+//
+// See handler.SetCategories for requirements to <idxCategoryMap>.
+func (h fileHandler) SetCategories(idxCategoryMap []lo.Entry[int, astra.Category], stopOnErr bool) []CategoryResult {
+	h.log.InfoFi("Writing changed categories to astra config file", "path", h.outPath)
+
+	for _, entry := range idxCategoryMap {
+		h.log.InfoFi("Setting category", "name", entry.Value.Name, "groups",
+			fmt.Sprintf("%+v", entry.Value.Groups), "remove", entry.Value.Remove)
+	}
+	err := h.write(h.cfg.ApplyCategories(idxCategoryMap))
+
+	return lo.Map(idxCategoryMap, func(entry lo.Entry[int, astra.Category], _ int) CategoryResult {
+		return CategoryResult{Idx: entry.Key, Category: entry.Value, Err: err}
+	})
+}
+
+// SetStreams sets <streams> in the last fetched config, writes it to output file and returns result for every stream.
+//
+// All streams are written at once, so they either all succeed or all fail with the same error regardless of
+// <stopOnErr>.
+func (h fileHandler) SetStreams(streams []astra.Stream, stopOnErr bool) []StreamResult {
+	h.log.InfoFi("Writing changed streams to astra config file", "path", h.outPath)
+
+	for _, stream := range streams {
+		h.log.InfoFi("Setting stream", "ID", stream.ID, "name", stream.Name, "remove", stream.Remove)
+	}
+	err := h.write(h.cfg.ApplyStreams(streams))
+
+	return lo.Map(streams, func(stream astra.Stream, _ int) StreamResult {
+		return StreamResult{Stream: stream, Err: err}
+	})
+}
+
+// write writes <cfg> to output file and remembers it as the last modified config
+func (h fileHandler) write(cfg astra.Cfg) error {
+	if err := astra.WriteCfgFile(h.outPath, cfg); err != nil {
+		h.log.ErrorFi("Failed to write astra config file", "path", h.outPath, "error", err)
+		return err
+	}
+	*h.cfg = cfg
+	*h.written = true
+	return nil
+}
@@ -0,0 +1,118 @@
+package api
+
+import (
+	"path/filepath"
+	"testing"
+
+	"m3u_merge_astra/astra"
+	"m3u_merge_astra/cfg"
+	"m3u_merge_astra/util/logger"
+
+	"github.com/samber/lo"
+	"github.com/stretchr/testify/assert"
+	"github.com/zenizh/go-capturer"
+)
+
+func TestNewFileHandler(t *testing.T) {
+	log := logger.New(logger.DebugLevel)
+
+	h := NewFileHandler(log, "in.json", "out.json")
+	assert.Exactly(t, "in.json", h.inPath, "should set input path")
+	assert.Exactly(t, "out.json", h.outPath, "should set output path")
+	assert.Exactly(t, &astra.Cfg{}, h.cfg, "should initialize empty config")
+	assert.False(t, *h.written, "should not be written yet")
+
+	h = NewFileHandler(log, "in.json", "")
+	assert.Exactly(t, "in.json", h.outPath, "should use input path as output path if it is not set")
+}
+
+func TestFileHandler(t *testing.T) {
+	dir := t.TempDir()
+	inPath := filepath.Join(dir, "in.json")
+	outPath := filepath.Join(dir, "out.json")
+
+	inCfg := astra.Cfg{
+		Categories: []astra.Category{{Name: "Category 1", Groups: []astra.Group{{Name: "Group 1"}}}},
+		Streams:    []astra.Stream{{ID: "0", Name: "Stream 0"}, {ID: "1", Name: "Stream 1"}},
+		Unknown:    map[string]any{"gid": float64(1)},
+	}
+	err := astra.WriteCfgFile(inPath, inCfg)
+	assert.NoError(t, err, "should write input config")
+
+	out := capturer.CaptureStderr(func() {
+		h := NewFileHandler(logger.New(logger.DebugLevel), inPath, outPath)
+
+		// Fetch
+		actual, err := h.FetchCfg()
+		assert.NoError(t, err, "should not return error")
+		assert.Exactly(t, inCfg, actual, "should read config from input file")
+
+		// Set categories
+		catResults := h.SetCategories([]lo.Entry[int, astra.Category]{
+			{Key: -1, Value: astra.Category{Name: "Category 2", Groups: []astra.Group{{Name: "Group 2"}}}},
+		}, false)
+		expectedCatResults := []CategoryResult{
+			{Idx: -1, Category: astra.Category{Name: "Category 2", Groups: []astra.Group{{Name: "Group 2"}}}},
+		}
+		assert.Exactly(t, expectedCatResults, catResults, "should return successful result for every category")
+
+		// Set streams
+		streams := []astra.Stream{{ID: "1", Name: "Stream 1", Remove: true}, {ID: "2", Name: "Stream 2"}}
+		streamResults := h.SetStreams(streams, false)
+		expectedStreamResults := []StreamResult{{Stream: streams[0]}, {Stream: streams[1]}}
+		assert.Exactly(t, expectedStreamResults, streamResults, "should return successful result for every stream")
+	})
+
+	assert.Contains(t, out, `Reading astra config file: path "`+inPath+`"`)
+	assert.Contains(t, out, `Writing changed categories to astra config file: path "`+outPath+`"`)
+	assert.Contains(t, out, `Setting category: name "Category 2", groups "[{Name:Group 2 Remove:false}]", `+
+		`remove "false"`)
+	assert.Contains(t, out, `Writing changed streams to astra config file: path "`+outPath+`"`)
+	assert.Contains(t, out, `Setting stream: ID "1", name "Stream 1", remove "true"`)
+	assert.Contains(t, out, `Setting stream: ID "2", name "Stream 2", remove "false"`)
+
+	actual, err := astra.ReadCfgFile(inPath)
+	assert.NoError(t, err, "should read input config")
+	assert.Exactly(t, inCfg, actual, "should not modify input file")
+
+	actual, err = astra.ReadCfgFile(outPath)
+	assert.NoError(t, err, "should read output config")
+	expected := astra.Cfg{
+		Categories: []astra.Category{
+			{Name: "Category 1", Groups: []astra.Group{{Name: "Group 1"}}},
+			{Name: "Category 2", Groups: []astra.Group{{Name: "Group 2"}}},
+		},
+		Streams: []astra.Stream{{ID: "0", Name: "Stream 0"}, {ID: "2", Name: "Stream 2"}},
+		Unknown: map[string]any{"gid": float64(1)},
+	}
+	assert.Exactly(t, expected, actual, "should write config with both categories and streams changes applied")
+
+	// Test verifying changes written to output file
+	h := NewFileHandler(logger.New(logger.DebugLevel), inPath, outPath)
+	verifier := astra.NewRepo(logger.New(logger.DebugLevel), cfg.NewDefCfg())
+	current, err := h.FetchCfg()
+	assert.NoError(t, err, "should not return error")
+	catMap := []lo.Entry[int, astra.Category]{{Key: -1, Value: astra.Category{Name: "Category 3"}}}
+	summary := Apply(logger.New(logger.DebugLevel), h, verifier, current, catMap,
+		[]astra.Stream{{ID: "5", Name: "Stream 5"}}, false)
+	drift, err := Verify(logger.New(logger.DebugLevel), h, verifier, summary, 1)
+	assert.NoError(t, err, "should not return error")
+	assert.Empty(t, drift, "should refetch config from output file and find no drift")
+	actual, err = astra.ReadCfgFile(inPath)
+	assert.NoError(t, err, "should read input config")
+	assert.Exactly(t, inCfg, actual, "should not modify input file")
+
+	// Test writing to missing directory
+	h = NewFileHandler(logger.New(logger.DebugLevel), inPath, filepath.Join(dir, "missing", "out.json"))
+	_, err = h.FetchCfg()
+	assert.NoError(t, err, "should not return error")
+	streamResults := h.SetStreams([]astra.Stream{{ID: "3"}, {ID: "4"}}, true)
+	assert.Len(t, streamResults, 2, "should return result for every stream")
+	for _, res := range streamResults {
+		assert.Error(t, res.Err, "should return error for every stream")
+	}
+
+	// Test fetching missing file
+	_, err = NewFileHandler(logger.New(logger.DebugLevel), filepath.Join(dir, "missing.json"), "").FetchCfg()
+	assert.Error(t, err, "should return error")
+}
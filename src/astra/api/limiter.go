@@ -0,0 +1,30 @@
+package api
+
+import "time"
+
+// limiter represents limiter of requests rate
+type limiter struct {
+	ticker *time.Ticker // Nil if rate is not limited
+}
+
+// newLimiter returns new limiter allowing up to <rps> requests per second. Zero or negative <rps> disables the limit.
+func newLimiter(rps float64) limiter {
+	if rps <= 0 {
+		return limiter{}
+	}
+	return limiter{ticker: time.NewTicker(time.Duration(float64(time.Second) / rps))}
+}
+
+// wait blocks until the next request is allowed
+func (l limiter) wait() {
+	if l.ticker != nil {
+		<-l.ticker.C
+	}
+}
+
+// stop releases resources of the limiter
+func (l limiter) stop() {
+	if l.ticker != nil {
+		l.ticker.Stop()
+	}
+}
@@ -0,0 +1,74 @@
+package api
+
+import (
+	"m3u_merge_astra/astra"
+	"m3u_merge_astra/util/logger"
+
+	"github.com/cockroachdb/errors"
+	"github.com/samber/lo"
+)
+
+// Verifier represents comparer of changes sent to astra with actual astra config (see astra.repo)
+type Verifier interface {
+	VerifyCategories(sent []lo.Entry[int, astra.Category], actual []astra.Category) []astra.Drift
+	VerifyStreams(sent, actual []astra.Stream) []astra.Drift
+}
+
+// Verify refetches astra config using handler <h> and returns drift between it and changes successfully applied
+// according to <summary>.
+//
+// Mismatched categories and streams are sent again up to <retries> times, returned drift is the one left after the
+// last retry.
+//
+// Returns error if astra config can't be fetched.
+func Verify(log *logger.Logger, h Handler, verifier Verifier, summary Summary, retries int) ([]astra.Drift, error) {
+	log.Info("Verifying changes applied to astra")
+
+	sentCats := lo.FilterMap(summary.Categories, func(res CategoryResult, _ int) (lo.Entry[int, astra.Category], bool) {
+		return lo.Entry[int, astra.Category]{Key: res.Idx, Value: res.Category}, res.Err == nil
+	})
+	sentStreams := lo.FilterMap(summary.Streams, func(res StreamResult, _ int) (astra.Stream, bool) {
+		return res.Stream, res.Err == nil
+	})
+
+	for attempt := 0; ; attempt++ {
+		actual, err := h.FetchCfg()
+		if err != nil {
+			return nil, errors.Wrap(err, "Verify changes applied to astra")
+		}
+		catDrift := verifier.VerifyCategories(sentCats, actual.Categories)
+		streamDrift := verifier.VerifyStreams(sentStreams, actual.Streams)
+		drift := append(catDrift, streamDrift...)
+		if len(drift) == 0 || attempt >= retries {
+			log.InfoFi("Verified changes applied to astra", "categories drifted", len(catDrift), "streams drifted",
+				len(streamDrift))
+			return drift, nil
+		}
+
+		log.InfoFi("Sending drifted changes to astra again", "attempt", attempt+1, "categories", len(catDrift),
+			"streams", len(streamDrift))
+		h.SetCategories(driftedCategories(sentCats, catDrift, actual.Categories), false)
+		h.SetStreams(driftedStreams(sentStreams, streamDrift), false)
+	}
+}
+
+// driftedCategories returns categories from <sent> which are in <drift> with indexes resolved by name in <actual>
+// categories
+func driftedCategories(sent []lo.Entry[int, astra.Category], drift []astra.Drift,
+	actual []astra.Category) (out []lo.Entry[int, astra.Category]) {
+	for _, entry := range sent {
+		if !lo.ContainsBy(drift, func(d astra.Drift) bool { return d.StreamID == "" && d.Name == entry.Value.Name }) {
+			continue
+		}
+		_, idx, _ := lo.FindIndexOf(actual, func(c astra.Category) bool { return c.Name == entry.Value.Name })
+		out = append(out, lo.Entry[int, astra.Category]{Key: idx, Value: entry.Value})
+	}
+	return
+}
+
+// driftedStreams returns streams from <sent> which are in <drift>
+func driftedStreams(sent []astra.Stream, drift []astra.Drift) []astra.Stream {
+	return lo.Filter(sent, func(s astra.Stream, _ int) bool {
+		return lo.ContainsBy(drift, func(d astra.Drift) bool { return d.StreamID == s.ID })
+	})
+}
@@ -0,0 +1,83 @@
+package api
+
+import (
+	"testing"
+
+	"m3u_merge_astra/astra"
+	"m3u_merge_astra/cfg"
+	"m3u_merge_astra/util/copier"
+	"m3u_merge_astra/util/logger"
+
+	"github.com/samber/lo"
+	"github.com/stretchr/testify/assert"
+	"github.com/zenizh/go-capturer"
+)
+
+func TestVerify(t *testing.T) {
+	log := logger.New(logger.DebugLevel)
+	verifier := astra.NewRepo(log, cfg.NewDefCfg())
+
+	original := astra.Cfg{
+		Categories: []astra.Category{{Name: "Category 1", Groups: []astra.Group{{Name: "Group 1"}}}},
+		Streams:    []astra.Stream{{ID: "0", Name: "Stream 0"}, {ID: "1", Name: "Stream 1"}},
+	}
+	catMap := []lo.Entry[int, astra.Category]{
+		{Key: -1, Value: astra.Category{Name: "Category 2", Groups: []astra.Group{{Name: "Group 2"}}}},
+	}
+	streams := []astra.Stream{
+		{ID: "0", Name: "Stream 0 renamed"},
+		{ID: "1", Name: "Stream 1", Remove: true},
+		{ID: "2", Name: "Stream 2"},
+		{ID: "3", Name: "Stream 3"},
+	}
+	newHandler := func(dropIDs map[string]int, failIDs ...string) fakeHandler {
+		return fakeHandler{cfg: lo.ToPtr(copier.TestDeep(t, original)), failIDs: failIDs, dropIDs: dropIDs}
+	}
+
+	// Test no drift
+	h := newHandler(nil)
+	summary := Apply(log, h, verifier, original, catMap, streams, false)
+	drift, err := Verify(log, h, verifier, summary, 0)
+	assert.NoError(t, err, "should not return error")
+	assert.Empty(t, drift, "should not return drift")
+
+	// Test drift without retries, failed streams should not be verified
+	h = newHandler(map[string]int{"0": 1, "2": 1}, "3")
+	summary = Apply(log, h, verifier, original, catMap, streams, false)
+	drift, err = Verify(log, h, verifier, summary, 0)
+	assert.NoError(t, err, "should not return error")
+	expected := []astra.Drift{
+		{StreamID: "0", Name: "Stream 0 renamed", Fields: []string{"name"}, Reason: "Fields differ"},
+		{StreamID: "2", Name: "Stream 2", Reason: "Missing in astra"},
+	}
+	assert.Exactly(t, expected, drift, "should return drift of dropped streams")
+
+	// Test drift fixed by retries
+	h = newHandler(map[string]int{"0": 2, "2": 1})
+	summary = Apply(log, h, verifier, original, catMap, streams, false)
+	drift, err = Verify(log, h, verifier, summary, 1)
+	assert.NoError(t, err, "should not return error")
+	assert.Len(t, drift, 1, "should return drift left after retries")
+	assert.Exactly(t, "0", drift[0].StreamID, "should return drift of this stream")
+
+	h = newHandler(map[string]int{"0": 2, "2": 1})
+	summary = Apply(log, h, verifier, original, catMap, streams, false)
+	drift, err = Verify(log, h, verifier, summary, 2)
+	assert.NoError(t, err, "should not return error")
+	assert.Empty(t, drift, "should not return drift fixed by retries")
+
+	// Test failed fetch
+	h.failFetch = true
+	_, err = Verify(log, h, verifier, summary, 0)
+	assert.Error(t, err, "should return error")
+
+	// Test log output
+	out := capturer.CaptureStderr(func() {
+		log := logger.New(logger.DebugLevel)
+		h := newHandler(map[string]int{"2": 1})
+		summary := Apply(log, h, verifier, original, catMap, streams, false)
+		_, _ = Verify(log, h, verifier, summary, 1)
+	})
+	assert.Contains(t, out, `Sending drifted changes to astra again: attempt "1", categories "0", streams "1"`)
+	assert.Contains(t, out, `Verified changes applied to astra: categories drifted "0", streams drifted "0"`)
+}
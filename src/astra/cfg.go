@@ -4,16 +4,90 @@ import (
 	"m3u_merge_astra/util/copier"
 	"m3u_merge_astra/util/slice"
 	"m3u_merge_astra/util/slice/find"
+	"os"
 	"sort"
 
+	json "github.com/SCP002/jsonexraw"
+	"github.com/cockroachdb/errors"
 	"github.com/google/go-cmp/cmp"
 	"github.com/samber/lo"
 )
 
 // Cfg represents astra config
 type Cfg struct {
-	Categories []Category `json:"categories"`
-	Streams    []Stream   `json:"make_stream"`
+	Categories []Category     `json:"categories"`
+	Streams    []Stream       `json:"make_stream"`
+	Unknown    map[string]any `json:"-" jsonex:"true"` // All unknown fields go here.
+}
+
+// ReadCfgFile returns astra config read from JSON file at <path>
+func ReadCfgFile(path string) (Cfg, error) {
+	cfgBytes, err := os.ReadFile(path)
+	if err != nil {
+		return Cfg{}, errors.Wrap(err, "Read astra config file")
+	}
+
+	var astraCfg Cfg
+	if err := json.Unmarshal(cfgBytes, &astraCfg); err != nil {
+		return Cfg{}, errors.Wrap(err, "Decode astra config file")
+	}
+
+	return astraCfg, nil
+}
+
+// WriteCfgFile writes <astraCfg> to JSON file at <path>
+func WriteCfgFile(path string, astraCfg Cfg) error {
+	cfgBytes, err := json.MarshalIndent(astraCfg, "", "  ")
+	if err != nil {
+		return errors.Wrap(err, "Encode astra config file")
+	}
+
+	if err := os.WriteFile(path, cfgBytes, 0644); err != nil {
+		return errors.Wrap(err, "Write astra config file")
+	}
+
+	return nil
+}
+
+// ApplyCategories returns deep copy of <c> with categories set by indexes as defined in <idxCategoryMap> the same way
+// as astra API does (see api.handler.SetCategories).
+//
+// Groups with Remove field set to true are removed from the category.
+func (c Cfg) ApplyCategories(idxCategoryMap []lo.Entry[int, Category]) Cfg {
+	c = copier.MustDeep(c)
+	for _, entry := range idxCategoryMap {
+		cat := entry.Value
+		cat.Groups = lo.Reject(cat.Groups, func(g Group, _ int) bool { return g.Remove })
+		switch {
+		case entry.Key < 0 && !cat.Remove:
+			c.Categories = append(c.Categories, cat)
+		case entry.Key >= 0 && entry.Key < len(c.Categories) && cat.Remove:
+			c.Categories = append(c.Categories[:entry.Key], c.Categories[entry.Key+1:]...)
+		case entry.Key >= 0 && entry.Key < len(c.Categories):
+			c.Categories[entry.Key] = cat
+		}
+	}
+	return c
+}
+
+// ApplyStreams returns deep copy of <c> with <streams> set by ID the same way as astra API does (see
+// api.handler.SetStreams).
+//
+// Streams with Remove field set to true are removed.
+func (c Cfg) ApplyStreams(streams []Stream) Cfg {
+	c = copier.MustDeep(c)
+	for _, s := range copier.MustDeep(streams) {
+		_, idx, found := lo.FindIndexOf(c.Streams, func(curr Stream) bool { return curr.ID == s.ID })
+		switch {
+		case found && s.Remove:
+			c.Streams = append(c.Streams[:idx], c.Streams[idx+1:]...)
+		case found:
+			c.Streams[idx] = s
+		case !s.Remove:
+			c.Streams = append(c.Streams, s)
+		}
+	}
+	return c
 }
 
 // Category represents category for groups of astra streams
@@ -1,6 +1,8 @@
 package astra
 
 import (
+	"os"
+	"path/filepath"
 	"testing"
 
 	"m3u_merge_astra/util/copier"
@@ -10,6 +12,95 @@ import (
 	"github.com/zenizh/go-capturer"
 )
 
+func TestReadWriteCfgFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "astra.json")
+	astraCfg := Cfg{
+		Categories: []Category{{Name: "Category 1", Groups: []Group{{Name: "Group 1"}}}},
+		Streams:    []Stream{{ID: "0", Name: "Stream 0", Unknown: map[string]any{"key": "val"}}},
+		Unknown:    map[string]any{"users": map[string]any{"admin": map[string]any{"type": float64(1)}}},
+	}
+	astraCfgOriginal := copier.TestDeep(t, astraCfg)
+
+	err := WriteCfgFile(path, astraCfg)
+	assert.NoError(t, err, "should not return error")
+	assert.Exactly(t, astraCfgOriginal, astraCfg, "should not modify the source config")
+
+	actual, err := ReadCfgFile(path)
+	assert.NoError(t, err, "should not return error")
+	assert.Exactly(t, astraCfg, actual, "should read the same config including unknown fields")
+
+	// Test reading damaged file
+	err = os.WriteFile(path, []byte("{"), 0644)
+	assert.NoError(t, err, "should write damaged file")
+	_, err = ReadCfgFile(path)
+	assert.Error(t, err, "should return error")
+
+	// Test reading missing file
+	_, err = ReadCfgFile(filepath.Join(t.TempDir(), "missing.json"))
+	assert.Error(t, err, "should return error")
+
+	// Test writing to missing directory
+	err = WriteCfgFile(filepath.Join(t.TempDir(), "missing", "astra.json"), astraCfg)
+	assert.Error(t, err, "should return error")
+}
+
+func TestApplyCategories(t *testing.T) {
+	astraCfg := Cfg{
+		Categories: []Category{
+			{Name: "Category 1", Groups: []Group{{Name: "Group 1"}}},
+			{Name: "Category 2", Groups: []Group{{Name: "Group 2"}}},
+			{Name: "Category 3", Groups: []Group{{Name: "Group 3"}}},
+		},
+	}
+	astraCfgOriginal := copier.TestDeep(t, astraCfg)
+
+	idxCategoryMap := []lo.Entry[int, Category]{
+		{Key: 2, Value: Category{Name: "Category 3", Remove: true}},
+		{Key: 0, Value: Category{Name: "Category 1", Groups: []Group{{Name: "Group 1", Remove: true}, {Name: "New"}}}},
+		{Key: -1, Value: Category{Name: "Category 4", Groups: []Group{{Name: "Group 4"}}}},
+		{Key: -1, Value: Category{Name: "Category 5", Remove: true}},
+		{Key: 10, Value: Category{Name: "Category 6"}},
+	}
+	actual := astraCfg.ApplyCategories(idxCategoryMap)
+
+	assert.Exactly(t, astraCfgOriginal, astraCfg, "should not modify the source config")
+
+	expected := []Category{
+		{Name: "Category 1", Groups: []Group{{Name: "New"}}},
+		{Name: "Category 2", Groups: []Group{{Name: "Group 2"}}},
+		{Name: "Category 4", Groups: []Group{{Name: "Group 4"}}},
+	}
+	assert.Exactly(t, expected, actual.Categories, "should replace, remove and add categories")
+}
+
+func TestApplyStreams(t *testing.T) {
+	astraCfg := Cfg{
+		Streams: []Stream{
+			{ID: "0", Name: "Stream 0"},
+			{ID: "1", Name: "Stream 1"},
+			{ID: "2", Name: "Stream 2"},
+		},
+	}
+	astraCfgOriginal := copier.TestDeep(t, astraCfg)
+
+	streams := []Stream{
+		{ID: "1", Name: "Stream 1", Remove: true},
+		{ID: "2", Name: "Stream 2 renamed"},
+		{ID: "3", Name: "Stream 3"},
+		{ID: "4", Name: "Stream 4", Remove: true},
+	}
+	actual := astraCfg.ApplyStreams(streams)
+
+	assert.Exactly(t, astraCfgOriginal, astraCfg, "should not modify the source config")
+
+	expected := []Stream{
+		{ID: "0", Name: "Stream 0"},
+		{ID: "2", Name: "Stream 2 renamed"},
+		{ID: "3", Name: "Stream 3"},
+	}
+	assert.Exactly(t, expected, actual.Streams, "should replace, remove and add streams")
+}
+
 func TestUpdateCategories(t *testing.T) {
 	r := newDefRepo()
 
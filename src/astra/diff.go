@@ -0,0 +1,300 @@
+package astra
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	json "github.com/SCP002/jsonexraw"
+	"github.com/cockroachdb/errors"
+	"github.com/google/go-cmp/cmp"
+	"github.com/samber/lo"
+)
+
+// Diff represents planned changes between astra config and it's modified version
+type Diff struct {
+	Streams    []StreamDiff   `json:"streams"`
+	Categories []CategoryDiff `json:"categories"`
+}
+
+// StreamDiff represents changes of a single astra stream
+type StreamDiff struct {
+	ID                    string                 `json:"id"`
+	Name                  string                 `json:"name"`
+	Added                 bool                   `json:"added,omitempty"`
+	Removed               bool                   `json:"removed,omitempty"`
+	Rename                *Change[string]        `json:"rename,omitempty"`
+	Enabled               *Change[bool]          `json:"enabled,omitempty"`
+	HTTPKeepActive        *Change[string]        `json:"http_keep_active,omitempty"`
+	Type                  *Change[string]        `json:"type,omitempty"`
+	AddedInputs           []string               `json:"added_inputs,omitempty"`
+	RemovedInputs         []string               `json:"removed_inputs,omitempty"`
+	InputsReordered       bool                   `json:"inputs_reordered,omitempty"`
+	AddedDisabledInputs   []string               `json:"added_disabled_inputs,omitempty"`
+	RemovedDisabledInputs []string               `json:"removed_disabled_inputs,omitempty"`
+	AddedGroups           map[string]string      `json:"added_groups,omitempty"`
+	RemovedGroups         map[string]string      `json:"removed_groups,omitempty"`
+	Options               map[string]Change[any] `json:"options,omitempty"`              // Stream options by JSON keys
+	OtherFieldsChanged    bool                   `json:"other_fields_changed,omitempty"` // Fields in Stream.Unknown
+}
+
+// CategoryDiff represents changes of a single astra category
+type CategoryDiff struct {
+	Name          string   `json:"name"`
+	Added         bool     `json:"added,omitempty"`
+	Removed       bool     `json:"removed,omitempty"`
+	AddedGroups   []string `json:"added_groups,omitempty"`
+	RemovedGroups []string `json:"removed_groups,omitempty"`
+}
+
+// Change represents old and new value of a field
+type Change[T any] struct {
+	Old T `json:"old"`
+	New T `json:"new"`
+}
+
+// IsEmpty returns true if there are no planned changes in <d>
+func (d Diff) IsEmpty() bool {
+	return len(d.Streams) == 0 && len(d.Categories) == 0
+}
+
+// JSON returns machine-readable report of <d>
+func (d Diff) JSON() ([]byte, error) {
+	out, err := json.MarshalIndent(d, "", "  ")
+	return out, errors.Wrap(err, "Encode diff")
+}
+
+// String returns human-readable report of <d>
+func (d Diff) String() string {
+	var sb strings.Builder
+
+	sb.WriteString("Streams:\n")
+	if len(d.Streams) == 0 {
+		sb.WriteString("  No changes\n")
+	}
+	for _, s := range d.Streams {
+		switch {
+		case s.Added:
+			sb.WriteString(fmt.Sprintf("  + [%v] %v (added)\n", s.ID, s.Name))
+		case s.Removed:
+			sb.WriteString(fmt.Sprintf("  - [%v] %v (removed)\n", s.ID, s.Name))
+			continue
+		default:
+			sb.WriteString(fmt.Sprintf("  ~ [%v] %v\n", s.ID, s.Name))
+		}
+		if s.Rename != nil {
+			sb.WriteString(fmt.Sprintf("      name: %q -> %q\n", s.Rename.Old, s.Rename.New))
+		}
+		if s.Enabled != nil {
+			sb.WriteString(fmt.Sprintf("      enabled: %v -> %v\n", s.Enabled.Old, s.Enabled.New))
+		}
+		if s.HTTPKeepActive != nil {
+			sb.WriteString(fmt.Sprintf("      http_keep_active: %q -> %q\n", s.HTTPKeepActive.Old, s.HTTPKeepActive.New))
+		}
+		if s.Type != nil {
+			sb.WriteString(fmt.Sprintf("      type: %q -> %q\n", s.Type.Old, s.Type.New))
+		}
+		for _, inp := range s.AddedInputs {
+			sb.WriteString(fmt.Sprintf("      + input: %v\n", inp))
+		}
+		for _, inp := range s.RemovedInputs {
+			sb.WriteString(fmt.Sprintf("      - input: %v\n", inp))
+		}
+		if s.InputsReordered {
+			sb.WriteString("      inputs reordered\n")
+		}
+		for _, inp := range s.AddedDisabledInputs {
+			sb.WriteString(fmt.Sprintf("      + disabled input: %v\n", inp))
+		}
+		for _, inp := range s.RemovedDisabledInputs {
+			sb.WriteString(fmt.Sprintf("      - disabled input: %v\n", inp))
+		}
+		for _, entry := range sortedEntries(s.AddedGroups) {
+			sb.WriteString(fmt.Sprintf("      + group: %v: %v\n", entry.Key, entry.Value))
+		}
+		for _, entry := range sortedEntries(s.RemovedGroups) {
+			sb.WriteString(fmt.Sprintf("      - group: %v: %v\n", entry.Key, entry.Value))
+		}
+		for _, key := range optionKeys {
+			change, found := s.Options[key]
+			if !found {
+				continue
+			}
+			sb.WriteString(fmt.Sprintf("      %v: %v -> %v\n", key, optionString(change.Old), optionString(change.New)))
+		}
+		if s.OtherFieldsChanged {
+			sb.WriteString("      other fields changed\n")
+		}
+	}
+
+	sb.WriteString("Categories:\n")
+	if len(d.Categories) == 0 {
+		sb.WriteString("  No changes\n")
+	}
+	for _, c := range d.Categories {
+		switch {
+		case c.Added:
+			sb.WriteString(fmt.Sprintf("  + %v (added)\n", c.Name))
+		case c.Removed:
+			sb.WriteString(fmt.Sprintf("  - %v (removed)\n", c.Name))
+			continue
+		default:
+			sb.WriteString(fmt.Sprintf("  ~ %v\n", c.Name))
+		}
+		for _, g := range c.AddedGroups {
+			sb.WriteString(fmt.Sprintf("      + group: %v\n", g))
+		}
+		for _, g := range c.RemovedGroups {
+			sb.WriteString(fmt.Sprintf("      - group: %v\n", g))
+		}
+	}
+
+	countBy := func(predicate func(StreamDiff) bool) int {
+		return lo.CountBy(d.Streams, predicate)
+	}
+	added := countBy(func(s StreamDiff) bool { return s.Added })
+	removed := countBy(func(s StreamDiff) bool { return s.Removed })
+	sb.WriteString(fmt.Sprintf("Summary: %v streams added, %v changed, %v removed; %v categories changed\n", added,
+		len(d.Streams)-added-removed, removed, len(d.Categories)))
+
+	return sb.String()
+}
+
+// Diff returns planned changes between <oldStreams>, <oldCats> and <newStreams>, <newCats>
+func (r repo) Diff(oldStreams, newStreams []Stream, oldCats, newCats []Category) (out Diff) {
+	r.log.Info("Building diff of planned changes")
+
+	out.Streams = []StreamDiff{}
+	for _, newStream := range newStreams {
+		oldStream, found := lo.Find(oldStreams, func(oldStream Stream) bool {
+			return newStream.ID == oldStream.ID
+		})
+		if !found && newStream.Remove {
+			continue
+		}
+		if d, changed := diffStream(oldStream, newStream, !found); changed {
+			out.Streams = append(out.Streams, d)
+		}
+	}
+
+	out.Categories = []CategoryDiff{}
+	for _, newCat := range newCats {
+		oldCat, found := lo.Find(oldCats, func(oldCat Category) bool {
+			return newCat.Name == oldCat.Name
+		})
+		if !found && newCat.Remove {
+			continue
+		}
+		if d, changed := diffCategory(oldCat, newCat, !found); changed {
+			out.Categories = append(out.Categories, d)
+		}
+	}
+
+	return
+}
+
+// diffStream returns changes between <oldStream> and <newStream> and true if there are any.
+//
+// If <added> is true, <newStream> is considered new.
+func diffStream(oldStream, newStream Stream, added bool) (StreamDiff, bool) {
+	d := StreamDiff{ID: newStream.ID, Name: newStream.Name, Added: added}
+
+	if newStream.Remove {
+		d.Name = oldStream.Name
+		d.Removed = true
+		return d, true
+	}
+	if !added && oldStream.Name != newStream.Name {
+		d.Rename = &Change[string]{Old: oldStream.Name, New: newStream.Name}
+	}
+	if oldStream.Enabled != newStream.Enabled {
+		d.Enabled = &Change[bool]{Old: oldStream.Enabled, New: newStream.Enabled}
+	}
+	if oldStream.HTTPKeepActive != newStream.HTTPKeepActive {
+		d.HTTPKeepActive = &Change[string]{Old: oldStream.HTTPKeepActive, New: newStream.HTTPKeepActive}
+	}
+	if oldStream.Type != newStream.Type {
+		d.Type = &Change[string]{Old: oldStream.Type, New: newStream.Type}
+	}
+	d.RemovedInputs, d.AddedInputs = lo.Difference(oldStream.Inputs, newStream.Inputs)
+	if len(d.AddedInputs) == 0 && len(d.RemovedInputs) == 0 {
+		d.InputsReordered = !cmp.Equal(oldStream.Inputs, newStream.Inputs) &&
+			len(oldStream.Inputs) == len(newStream.Inputs)
+	}
+	d.RemovedDisabledInputs, d.AddedDisabledInputs = lo.Difference(oldStream.DisabledInputs, newStream.DisabledInputs)
+	d.RemovedGroups = lo.OmitBy(oldStream.Groups, func(cat, group string) bool {
+		return newStream.Groups[cat] == group
+	})
+	d.AddedGroups = lo.OmitBy(newStream.Groups, func(cat, group string) bool {
+		oldGroup, found := oldStream.Groups[cat]
+		return found && oldGroup == group
+	})
+	oldOptions, newOptions := oldStream.options(), newStream.options()
+	for _, key := range optionKeys {
+		if !reflect.DeepEqual(oldOptions[key], newOptions[key]) {
+			d.Options = lo.Assign(d.Options, map[string]Change[any]{key: {Old: oldOptions[key], New: newOptions[key]}})
+		}
+	}
+	d.OtherFieldsChanged = !cmp.Equal(oldStream.Unknown, newStream.Unknown)
+
+	// Normalize empty values to make output consistent
+	d.AddedInputs = lo.Ternary(len(d.AddedInputs) == 0, nil, d.AddedInputs)
+	d.RemovedInputs = lo.Ternary(len(d.RemovedInputs) == 0, nil, d.RemovedInputs)
+	d.AddedDisabledInputs = lo.Ternary(len(d.AddedDisabledInputs) == 0, nil, d.AddedDisabledInputs)
+	d.RemovedDisabledInputs = lo.Ternary(len(d.RemovedDisabledInputs) == 0, nil, d.RemovedDisabledInputs)
+	d.AddedGroups = lo.Ternary(len(d.AddedGroups) == 0, nil, d.AddedGroups)
+	d.RemovedGroups = lo.Ternary(len(d.RemovedGroups) == 0, nil, d.RemovedGroups)
+
+	changed := added || d.Rename != nil || d.Enabled != nil || d.HTTPKeepActive != nil || d.Type != nil ||
+		d.AddedInputs != nil || d.RemovedInputs != nil || d.InputsReordered || d.AddedDisabledInputs != nil ||
+		d.RemovedDisabledInputs != nil || d.AddedGroups != nil || d.RemovedGroups != nil || d.Options != nil ||
+		d.OtherFieldsChanged
+	return d, changed
+}
+
+// diffCategory returns changes between <oldCat> and <newCat> and true if there are any.
+//
+// If <added> is true, <newCat> is considered new.
+func diffCategory(oldCat, newCat Category, added bool) (CategoryDiff, bool) {
+	d := CategoryDiff{Name: newCat.Name, Added: added}
+
+	if newCat.Remove {
+		d.Name = oldCat.Name
+		d.Removed = true
+		return d, true
+	}
+
+	groupNames := func(groups []Group, remove bool) []string {
+		return lo.Uniq(lo.FilterMap(groups, func(g Group, _ int) (string, bool) {
+			return g.Name, g.Remove == remove
+		}))
+	}
+	oldGroups := groupNames(oldCat.Groups, false)
+	newGroups := groupNames(newCat.Groups, false)
+	d.RemovedGroups, d.AddedGroups = lo.Difference(oldGroups, newGroups)
+	d.RemovedGroups = lo.Uniq(append(d.RemovedGroups, lo.Intersect(oldGroups, groupNames(newCat.Groups, true))...))
+
+	d.AddedGroups = lo.Ternary(len(d.AddedGroups) == 0, nil, d.AddedGroups)
+	d.RemovedGroups = lo.Ternary(len(d.RemovedGroups) == 0, nil, d.RemovedGroups)
+
+	return d, added || d.AddedGroups != nil || d.RemovedGroups != nil
+}
+
+// optionString returns human-readable representation of stream option value <val> decoded from JSON
+func optionString(val any) string {
+	if val == nil {
+		return "(default)"
+	}
+	valBytes, _ := json.Marshal(val)
+	return string(valBytes)
+}
+
+// sortedEntries returns entries of <m> sorted by key
+func sortedEntries(m map[string]string) []lo.Entry[string, string] {
+	entries := lo.Entries(m)
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].Key < entries[j].Key
+	})
+	return entries
+}
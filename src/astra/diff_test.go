@@ -0,0 +1,229 @@
+package astra
+
+import (
+	"testing"
+
+	"m3u_merge_astra/util/copier"
+
+	"github.com/samber/lo"
+	"github.com/stretchr/testify/assert"
+	"github.com/zenizh/go-capturer"
+)
+
+func TestDiff(t *testing.T) {
+	r := newDefRepo()
+
+	oldStreams := []Stream{
+		{ID: "0", Name: "Stream 0", Enabled: true, Inputs: []string{"http://a", "http://b"}},
+		{ID: "1", Name: "Stream 1", Enabled: false, Inputs: []string{"http://c"}, DisabledInputs: []string{"http://d"},
+			Groups: map[string]string{"Cat 1": "Grp 1", "Cat 2": "Grp 2"}},
+		{ID: "2", Name: "Stream 2", Type: "spts", HTTPKeepActive: "0", Inputs: []string{"http://e", "http://f"}},
+		{ID: "3", Name: "Stream 3", Inputs: []string{"http://g"}, Unknown: map[string]any{"key": "val"},
+			Timeout: lo.ToPtr(Number(10)), BackupType: "active"},
+		{ID: "4", Name: "Stream 4"},
+	}
+	oldStreamsOriginal := copier.TestDeep(t, oldStreams)
+	newStreams := []Stream{
+		{ID: "0", Name: "Stream 0", Enabled: true, Inputs: []string{"http://a", "http://b"}, MarkAdded: true},
+		{ID: "1", Name: "Stream 1 new", Enabled: true, Inputs: []string{"http://c", "http://h"},
+			Groups: map[string]string{"Cat 1": "Grp 1", "Cat 2": "Grp 3"}},
+		{ID: "2", Name: "Stream 2", Type: "mpts", HTTPKeepActive: "10", Inputs: []string{"http://f", "http://e"}},
+		{ID: "3", Name: "Stream 3", Inputs: []string{"http://g"}, Unknown: map[string]any{"key": "val 2"},
+			Timeout: lo.ToPtr(Number(20)), Outputs: []string{"udp://239.0.0.1:1234"}},
+		{ID: "4", Name: "Stream 4", Remove: true},
+		{ID: "5", Name: "Stream 5", Enabled: true, Inputs: []string{"http://i"}, Groups: map[string]string{"All": "A"}},
+		{ID: "6", Name: "Stream 6", Remove: true},
+	}
+	newStreamsOriginal := copier.TestDeep(t, newStreams)
+	oldCats := []Category{
+		{Name: "Category 1", Groups: []Group{{Name: "A"}, {Name: "B"}}},
+		{Name: "Category 2", Groups: []Group{{Name: "C"}}},
+		{Name: "Category 3", Groups: []Group{{Name: "D"}}},
+	}
+	oldCatsOriginal := copier.TestDeep(t, oldCats)
+	newCats := []Category{
+		{Name: "Category 1", Groups: []Group{{Name: "A"}, {Name: "B", Remove: true}, {Name: "E"}}},
+		{Name: "Category 2", Groups: []Group{{Name: "C"}}},
+		{Name: "Category 3", Remove: true},
+		{Name: "Category 4", Groups: []Group{{Name: "F"}}},
+	}
+	newCatsOriginal := copier.TestDeep(t, newCats)
+
+	diff := r.Diff(oldStreams, newStreams, oldCats, newCats)
+
+	assert.Exactly(t, oldStreamsOriginal, oldStreams, "should not modify the source streams")
+	assert.Exactly(t, newStreamsOriginal, newStreams, "should not modify the source streams")
+	assert.Exactly(t, oldCatsOriginal, oldCats, "should not modify the source categories")
+	assert.Exactly(t, newCatsOriginal, newCats, "should not modify the source categories")
+
+	expected := Diff{
+		Streams: []StreamDiff{
+			{
+				ID:                    "1",
+				Name:                  "Stream 1 new",
+				Rename:                &Change[string]{Old: "Stream 1", New: "Stream 1 new"},
+				Enabled:               &Change[bool]{Old: false, New: true},
+				AddedInputs:           []string{"http://h"},
+				RemovedDisabledInputs: []string{"http://d"},
+				AddedGroups:           map[string]string{"Cat 2": "Grp 3"},
+				RemovedGroups:         map[string]string{"Cat 2": "Grp 2"},
+			},
+			{
+				ID:              "2",
+				Name:            "Stream 2",
+				HTTPKeepActive:  &Change[string]{Old: "0", New: "10"},
+				Type:            &Change[string]{Old: "spts", New: "mpts"},
+				InputsReordered: true,
+			},
+			{
+				ID:   "3",
+				Name: "Stream 3",
+				Options: map[string]Change[any]{
+					"backup_type": {Old: "active", New: nil},
+					"output":      {Old: nil, New: []any{"udp://239.0.0.1:1234"}},
+					"timeout":     {Old: 10.0, New: 20.0},
+				},
+				OtherFieldsChanged: true,
+			},
+			{ID: "4", Name: "Stream 4", Removed: true},
+			{
+				ID:          "5",
+				Name:        "Stream 5",
+				Added:       true,
+				Enabled:     &Change[bool]{Old: false, New: true},
+				AddedInputs: []string{"http://i"},
+				AddedGroups: map[string]string{"All": "A"},
+			},
+		},
+		Categories: []CategoryDiff{
+			{Name: "Category 1", AddedGroups: []string{"E"}, RemovedGroups: []string{"B"}},
+			{Name: "Category 3", Removed: true},
+			{Name: "Category 4", Added: true, AddedGroups: []string{"F"}},
+		},
+	}
+	assert.Exactly(t, expected, diff, "should return these changes")
+
+	// Test no changes
+	diff = r.Diff(oldStreams, oldStreams, oldCats, oldCats)
+	assert.True(t, diff.IsEmpty(), "should not contain changes")
+
+	// Test log output
+	out := capturer.CaptureStderr(func() {
+		r := newDefRepo()
+		_ = r.Diff(nil, nil, nil, nil)
+	})
+	assert.Contains(t, out, "Building diff of planned changes")
+}
+
+func TestDiffString(t *testing.T) {
+	diff := Diff{
+		Streams: []StreamDiff{
+			{
+				ID:                    "1",
+				Name:                  "Stream 1 new",
+				Rename:                &Change[string]{Old: "Stream 1", New: "Stream 1 new"},
+				Enabled:               &Change[bool]{Old: false, New: true},
+				HTTPKeepActive:        &Change[string]{Old: "0", New: "10"},
+				Type:                  &Change[string]{Old: "spts", New: "mpts"},
+				AddedInputs:           []string{"http://h"},
+				RemovedInputs:         []string{"http://c"},
+				AddedDisabledInputs:   []string{"http://c"},
+				RemovedDisabledInputs: []string{"http://d"},
+				AddedGroups:           map[string]string{"Cat 2": "Grp 3", "Cat 1": "Grp 1"},
+				RemovedGroups:         map[string]string{"Cat 2": "Grp 2"},
+				Options: map[string]Change[any]{
+					"timeout":     {Old: 10.0, New: 20.0},
+					"backup_type": {Old: "active", New: nil},
+				},
+				OtherFieldsChanged: true,
+			},
+			{ID: "2", Name: "Stream 2", InputsReordered: true},
+			{ID: "4", Name: "Stream 4", Removed: true},
+			{ID: "5", Name: "Stream 5", Added: true, AddedInputs: []string{"http://i"}},
+		},
+		Categories: []CategoryDiff{
+			{Name: "Category 1", AddedGroups: []string{"E"}, RemovedGroups: []string{"B"}},
+			{Name: "Category 3", Removed: true},
+			{Name: "Category 4", Added: true, AddedGroups: []string{"F"}},
+		},
+	}
+
+	expected := "Streams:\n" +
+		"  ~ [1] Stream 1 new\n" +
+		"      name: \"Stream 1\" -> \"Stream 1 new\"\n" +
+		"      enabled: false -> true\n" +
+		"      http_keep_active: \"0\" -> \"10\"\n" +
+		"      type: \"spts\" -> \"mpts\"\n" +
+		"      + input: http://h\n" +
+		"      - input: http://c\n" +
+		"      + disabled input: http://c\n" +
+		"      - disabled input: http://d\n" +
+		"      + group: Cat 1: Grp 1\n" +
+		"      + group: Cat 2: Grp 3\n" +
+		"      - group: Cat 2: Grp 2\n" +
+		"      backup_type: \"active\" -> (default)\n" +
+		"      timeout: 10 -> 20\n" +
+		"      other fields changed\n" +
+		"  ~ [2] Stream 2\n" +
+		"      inputs reordered\n" +
+		"  - [4] Stream 4 (removed)\n" +
+		"  + [5] Stream 5 (added)\n" +
+		"      + input: http://i\n" +
+		"Categories:\n" +
+		"  ~ Category 1\n" +
+		"      + group: E\n" +
+		"      - group: B\n" +
+		"  - Category 3 (removed)\n" +
+		"  + Category 4 (added)\n" +
+		"      + group: F\n" +
+		"Summary: 1 streams added, 2 changed, 1 removed; 3 categories changed\n"
+	assert.Exactly(t, expected, diff.String(), "should return this report")
+
+	expected = "Streams:\n" +
+		"  No changes\n" +
+		"Categories:\n" +
+		"  No changes\n" +
+		"Summary: 0 streams added, 0 changed, 0 removed; 0 categories changed\n"
+	assert.Exactly(t, expected, Diff{}.String(), "should return this report")
+}
+
+func TestDiffJSON(t *testing.T) {
+	diff := Diff{
+		Streams: []StreamDiff{
+			{ID: "1", Name: "Stream 1", Enabled: &Change[bool]{Old: false, New: true}},
+			{ID: "2", Name: "Stream 2", Removed: true},
+		},
+		Categories: []CategoryDiff{{Name: "Category 1", Added: true, AddedGroups: []string{"A"}}},
+	}
+
+	actual, err := diff.JSON()
+	assert.NoError(t, err, "should not return error")
+
+	expected := `{
+  "streams": [
+    {
+      "id": "1",
+      "name": "Stream 1",
+      "enabled": {
+        "old": false,
+        "new": true
+      }
+    },
+    {
+      "id": "2",
+      "name": "Stream 2",
+      "removed": true
+    }
+  ],
+  "categories": [
+    {
+      "name": "Category 1",
+      "added": true,
+      "added_groups": [
+        "A"
+      ]
+    }
+  ]
+}`
+	assert.Exactly(t, expected, string(actual), "should return this report")
+}
@@ -0,0 +1,183 @@
+package astra
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"m3u_merge_astra/cfg"
+
+	json "github.com/SCP002/jsonexraw"
+	"github.com/cockroachdb/errors"
+	"github.com/samber/lo"
+)
+
+// HealthCheck represents result of astra stream input check
+type HealthCheck struct {
+	Time   time.Time `json:"time"`
+	Reason string    `json:"reason,omitempty"` // Reason why input is considered dead or empty if check succeeded
+}
+
+// InputHealth represents check history of astra stream input
+type InputHealth struct {
+	LastCheck            time.Time     `json:"last_check"`
+	Checks               []HealthCheck `json:"checks"` // Checks within cfg.Streams.DeadInputWindow, oldest first
+	ConsecutiveFailures  int           `json:"consecutive_failures"`
+	ConsecutiveSuccesses int           `json:"consecutive_successes"`
+	Dead                 bool          `json:"dead"`
+	DisabledIn           []string      `json:"disabled_in,omitempty"` // IDs of streams input was disabled in by program
+}
+
+// failureRatio returns ratio of failed checks to all checks of the input or 0 if there are no checks
+func (h InputHealth) failureRatio() float64 {
+	if len(h.Checks) == 0 {
+		return 0
+	}
+	failed := lo.CountBy(h.Checks, func(c HealthCheck) bool { return c.Reason != "" })
+	return float64(failed) / float64(len(h.Checks))
+}
+
+// HealthStore represents check history of astra stream inputs shared between runs of the program
+type HealthStore struct {
+	Inputs map[string]InputHealth `json:"inputs"` // Key: Input URL
+	mut    sync.Mutex
+}
+
+// NewHealthStore returns new empty health store
+func NewHealthStore() *HealthStore {
+	return &HealthStore{Inputs: map[string]InputHealth{}}
+}
+
+// Get returns check history of input <inp> and true or empty history and false if input was never checked
+func (h *HealthStore) Get(inp string) (InputHealth, bool) {
+	h.mut.Lock()
+	defer h.mut.Unlock()
+	health, found := h.Inputs[inp]
+	return health, found
+}
+
+// Record adds result of input <inp> check made at <now> to the history and returns updated history of the input.
+//
+// <reason> is the reason why check failed or empty string if check succeeded.
+//
+// Input is considered dead after cfg.DeadInputFailures consecutive failures or if ratio of failures within
+// cfg.DeadInputWindow reached cfg.DeadInputFailureRatio. Dead input is considered alive again after
+// cfg.AliveInputSuccesses consecutive successes.
+func (h *HealthStore) Record(cfg cfg.Streams, inp, reason string, now time.Time) InputHealth {
+	h.mut.Lock()
+	defer h.mut.Unlock()
+
+	health := h.Inputs[inp]
+	health.LastCheck = now
+	health.Checks = append(lo.Filter(health.Checks, func(c HealthCheck, _ int) bool {
+		return now.Sub(c.Time) < cfg.DeadInputWindow
+	}), HealthCheck{Time: now, Reason: reason})
+
+	if reason == "" {
+		health.ConsecutiveFailures = 0
+		health.ConsecutiveSuccesses++
+		if health.Dead && health.ConsecutiveSuccesses >= cfg.AliveInputSuccesses {
+			health.Dead = false
+		}
+	} else {
+		health.ConsecutiveSuccesses = 0
+		health.ConsecutiveFailures++
+		if health.ConsecutiveFailures >= cfg.DeadInputFailures {
+			health.Dead = true
+		}
+		if cfg.DeadInputFailureRatio > 0 && len(health.Checks) >= cfg.DeadInputFailures &&
+			health.failureRatio() >= cfg.DeadInputFailureRatio {
+			health.Dead = true
+		}
+	}
+
+	h.Inputs[inp] = health
+	return health
+}
+
+// MarkDisabled records that input <inp> was disabled by this program in stream with ID <streamID>
+func (h *HealthStore) MarkDisabled(inp, streamID string) {
+	h.mut.Lock()
+	defer h.mut.Unlock()
+	health := h.Inputs[inp]
+	health.DisabledIn = lo.Uniq(append(health.DisabledIn, streamID))
+	h.Inputs[inp] = health
+}
+
+// UnmarkDisabled removes records that input <inp> was disabled by this program in streams with IDs <streamIDs>
+func (h *HealthStore) UnmarkDisabled(inp string, streamIDs ...string) {
+	h.mut.Lock()
+	defer h.mut.Unlock()
+	health, found := h.Inputs[inp]
+	if !found {
+		return
+	}
+	health.DisabledIn = lo.Without(health.DisabledIn, streamIDs...)
+	h.Inputs[inp] = health
+}
+
+// prune removes history of inputs which were not checked within <ttl> before <now>
+func (h *HealthStore) prune(ttl time.Duration, now time.Time) {
+	h.mut.Lock()
+	defer h.mut.Unlock()
+	h.Inputs = lo.OmitBy(h.Inputs, func(_ string, health InputHealth) bool {
+		return now.Sub(health.LastCheck) >= ttl
+	})
+}
+
+// ReadHealth returns health store read from JSON file at <path> or empty store if file does not exist
+func (r repo) ReadHealth(path string) (*HealthStore, error) {
+	r.log.InfoFi("Reading health of inputs", "path", path)
+
+	healthBytes, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return NewHealthStore(), nil
+	}
+	if err != nil {
+		return NewHealthStore(), errors.Wrap(err, "Read health of inputs")
+	}
+
+	store := NewHealthStore()
+	if err := json.Unmarshal(healthBytes, store); err != nil {
+		return NewHealthStore(), errors.Wrap(err, "Decode health of inputs")
+	}
+	if store.Inputs == nil {
+		store.Inputs = map[string]InputHealth{}
+	}
+
+	return store, nil
+}
+
+// SaveHealth writes <store> without inputs not checked within cfg.Streams.InputHealthTTL to JSON file at <path>.
+//
+// File is replaced atomically so concurrent runs of the program never read partially written file.
+func (r repo) SaveHealth(path string, store *HealthStore) error {
+	store.prune(r.cfg.Streams.InputHealthTTL, time.Now())
+	r.log.InfoFi("Saving health of inputs", "path", path, "inputs", len(store.Inputs))
+
+	store.mut.Lock()
+	healthBytes, err := json.MarshalIndent(store, "", "  ")
+	store.mut.Unlock()
+	if err != nil {
+		return errors.Wrap(err, "Encode health of inputs")
+	}
+
+	tmpFile, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return errors.Wrap(err, "Create temporary file for health of inputs")
+	}
+	defer os.Remove(tmpFile.Name())
+	if _, err := tmpFile.Write(healthBytes); err != nil {
+		tmpFile.Close()
+		return errors.Wrap(err, "Write health of inputs")
+	}
+	if err := tmpFile.Close(); err != nil {
+		return errors.Wrap(err, "Write health of inputs")
+	}
+	if err := os.Rename(tmpFile.Name(), path); err != nil {
+		return errors.Wrap(err, "Replace health of inputs")
+	}
+
+	return nil
+}
@@ -0,0 +1,138 @@
+package astra
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"m3u_merge_astra/cfg"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHealthStoreRecord(t *testing.T) {
+	streamsCfg := cfg.NewDefCfg().Streams
+	streamsCfg.DeadInputFailures = 3
+	streamsCfg.AliveInputSuccesses = 2
+	streamsCfg.DeadInputWindow = time.Hour
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	store := NewHealthStore()
+	health := store.Record(streamsCfg, "http://input/1", "Timeout", now)
+	assert.False(t, health.Dead, "should not consider input dead after the first failure")
+	health = store.Record(streamsCfg, "http://input/1", "Timeout", now.Add(time.Minute))
+	assert.False(t, health.Dead, "should not consider input dead after the second failure")
+	health = store.Record(streamsCfg, "http://input/1", "Timeout", now.Add(time.Minute*2))
+	assert.True(t, health.Dead, "should consider input dead after the third consecutive failure")
+	assert.Exactly(t, 3, health.ConsecutiveFailures, "should count consecutive failures")
+
+	health = store.Record(streamsCfg, "http://input/1", "", now.Add(time.Minute*3))
+	assert.True(t, health.Dead, "should not consider input alive after the first success")
+	assert.Exactly(t, 0, health.ConsecutiveFailures, "should reset consecutive failures")
+	health = store.Record(streamsCfg, "http://input/1", "", now.Add(time.Minute*4))
+	assert.False(t, health.Dead, "should consider input alive after the second consecutive success")
+	assert.Len(t, health.Checks, 5, "should keep all checks within the window")
+
+	actual, found := store.Get("http://input/1")
+	assert.True(t, found, "should find checked input")
+	assert.Exactly(t, health, actual, "should return the same history")
+	_, found = store.Get("http://input/2")
+	assert.False(t, found, "should not find input which was never checked")
+
+	// Test failures interrupted by success
+	store = NewHealthStore()
+	store.Record(streamsCfg, "http://input/1", "Timeout", now)
+	store.Record(streamsCfg, "http://input/1", "Timeout", now.Add(time.Minute))
+	store.Record(streamsCfg, "http://input/1", "", now.Add(time.Minute*2))
+	health = store.Record(streamsCfg, "http://input/1", "Timeout", now.Add(time.Minute*3))
+	assert.False(t, health.Dead, "should count only consecutive failures")
+
+	// Test failure ratio
+	streamsCfg.DeadInputFailureRatio = 0.5
+	store = NewHealthStore()
+	store.Record(streamsCfg, "http://input/1", "", now)
+	store.Record(streamsCfg, "http://input/1", "Timeout", now.Add(time.Minute))
+	health = store.Record(streamsCfg, "http://input/1", "", now.Add(time.Minute*2))
+	assert.False(t, health.Dead, "should not consider input dead below the ratio")
+	health = store.Record(streamsCfg, "http://input/1", "Timeout", now.Add(time.Minute*3))
+	assert.True(t, health.Dead, "should consider input dead as half of checks failed")
+
+	// Test window
+	store = NewHealthStore()
+	store.Record(streamsCfg, "http://input/1", "Timeout", now)
+	store.Record(streamsCfg, "http://input/1", "", now.Add(time.Minute))
+	store.Record(streamsCfg, "http://input/1", "", now.Add(time.Minute*2))
+	health = store.Record(streamsCfg, "http://input/1", "Timeout", now.Add(time.Hour+time.Second))
+	assert.Len(t, health.Checks, 3, "should remove checks outside of the window")
+	assert.False(t, health.Dead, "should not count failures outside of the window")
+}
+
+func TestHealthStoreMarkDisabled(t *testing.T) {
+	store := NewHealthStore()
+	store.MarkDisabled("http://input/1", "0000")
+	store.MarkDisabled("http://input/1", "0001")
+	store.MarkDisabled("http://input/1", "0000")
+	assert.Exactly(t, []string{"0000", "0001"}, store.Inputs["http://input/1"].DisabledIn,
+		"should record every stream once")
+
+	store.UnmarkDisabled("http://input/1", "0000")
+	assert.Exactly(t, []string{"0001"}, store.Inputs["http://input/1"].DisabledIn, "should remove this stream")
+
+	store.UnmarkDisabled("http://input/2", "0000")
+	_, found := store.Get("http://input/2")
+	assert.False(t, found, "should not add unknown input")
+}
+
+func TestHealthStorePrune(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	store := NewHealthStore()
+	store.Inputs["http://input/1"] = InputHealth{LastCheck: now.Add(-time.Hour * 2)}
+	store.Inputs["http://input/2"] = InputHealth{LastCheck: now.Add(-time.Minute)}
+
+	store.prune(time.Hour, now)
+	assert.Exactly(t, map[string]InputHealth{"http://input/2": {LastCheck: now.Add(-time.Minute)}}, store.Inputs,
+		"should remove inputs not checked within TTL")
+}
+
+func TestSaveReadHealth(t *testing.T) {
+	r := newDefRepo()
+	r.cfg.Streams.InputHealthTTL = time.Hour
+
+	path := filepath.Join(t.TempDir(), "health.json")
+	store, err := r.ReadHealth(path)
+	assert.NoError(t, err, "should not return error if file does not exist")
+	assert.Empty(t, store.Inputs, "should return empty store if file does not exist")
+
+	now := time.Now().UTC().Truncate(time.Second)
+	store.Record(r.cfg.Streams, "http://input/1", "Timeout", now)
+	store.Inputs["http://input/2"] = InputHealth{LastCheck: now.Add(-time.Hour * 2)}
+
+	err = r.SaveHealth(path, store)
+	assert.NoError(t, err, "should not return error")
+
+	actual, err := r.ReadHealth(path)
+	assert.NoError(t, err, "should not return error")
+	expected := map[string]InputHealth{
+		"http://input/1": {
+			LastCheck:           now,
+			Checks:              []HealthCheck{{Time: now, Reason: "Timeout"}},
+			ConsecutiveFailures: 1,
+			Dead:                true,
+		},
+	}
+	assert.Exactly(t, expected, actual.Inputs, "should read the same history without outdated inputs")
+
+	matches, _ := filepath.Glob(filepath.Join(filepath.Dir(path), "*.tmp"))
+	assert.Empty(t, matches, "should not leave temporary files")
+
+	// Test reading damaged file
+	err = os.WriteFile(path, []byte("{"), 0644)
+	assert.NoError(t, err, "should write damaged file")
+	_, err = r.ReadHealth(path)
+	assert.Error(t, err, "should return error")
+
+	// Test writing to missing directory
+	err = r.SaveHealth(filepath.Join(path, "missing", "health.json"), store)
+	assert.Error(t, err, "should return error")
+}
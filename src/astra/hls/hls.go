@@ -0,0 +1,311 @@
+package hls
+
+import (
+	"bufio"
+	"encoding/binary"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cockroachdb/errors"
+	"github.com/samber/lo"
+)
+
+// maxPlaylistSize represents maximum amount of bytes of playlist to read
+const maxPlaylistSize = 1 << 20
+
+// segmentProbeSize represents amount of bytes of segment to read to detect it's format (two MPEG-TS packets)
+const segmentProbeSize = 188 * 2
+
+// fmp4BoxTypes represents types of boxes fMP4 segment can start with
+var fmp4BoxTypes = []string{"ftyp", "styp", "moof", "sidx", "emsg", "prft", "free"}
+
+// bandwidthRx represents regular expression to get bandwidth from EXT-X-STREAM-INF tag
+var bandwidthRx = regexp.MustCompile(`(?:^|,)BANDWIDTH=(\d+)`)
+
+// keyMethodRx represents regular expression to get encryption method from EXT-X-KEY tag
+var keyMethodRx = regexp.MustCompile(`(?:^|,)METHOD=([^,]+)`)
+
+// variant represents variant stream of master playlist
+type variant struct {
+	URL       string
+	Bandwidth int
+}
+
+// mediaPlaylist represents media playlist
+type mediaPlaylist struct {
+	Sequence       int
+	TargetDuration time.Duration
+	Segments       []string // Absolute URLs
+	Ended          bool
+	Encrypted      bool
+}
+
+// Checker represents HLS stream checker
+type Checker struct {
+	httpClient     *http.Client
+	refreshTimeout time.Duration
+}
+
+// New returns new HLS stream checker which makes requests using <httpClient> and waits up to <refreshTimeout> for
+// media playlist to update.
+func New(httpClient *http.Client, refreshTimeout time.Duration) Checker {
+	return Checker{httpClient: httpClient, refreshTimeout: refreshTimeout}
+}
+
+// Check returns error describing why stream at <streamURL> is considered dead or nil if it is alive.
+//
+// If <streamURL> responds with HLS playlist, it is considered alive if any variant of master playlist (in order of
+// bandwidth, lowest first) or the media playlist itself is fresh and it's last segment is MPEG-TS or fMP4.
+//
+// Media playlist is fresh if it has no EXT-X-ENDLIST tag and it's media sequence advances (or segments are added)
+// within 3 target durations or refresh timeout, whichever is less.
+//
+// If <streamURL> responds with anything else, only response status is checked.
+func (c Checker) Check(streamURL string) error {
+	playlist, isPlaylist, err := c.fetch(streamURL)
+	if err != nil {
+		return err
+	}
+	if !isPlaylist {
+		return nil
+	}
+
+	if !strings.Contains(playlist, "#EXT-X-STREAM-INF") {
+		return c.checkMedia(streamURL, playlist)
+	}
+
+	variants, err := parseMaster(streamURL, playlist)
+	if err != nil {
+		return err
+	}
+	if len(variants) == 0 {
+		return errors.New("Master playlist has no variants")
+	}
+	slices.SortStableFunc(variants, func(a, b variant) int {
+		return a.Bandwidth - b.Bandwidth
+	})
+	for _, v := range variants {
+		if err = c.checkMedia(v.URL, ""); err == nil {
+			return nil
+		}
+	}
+	return errors.Wrap(err, "No alive variants, last one")
+}
+
+// checkMedia returns error if media playlist at <playlistURL> is not fresh or it's last segment is not valid.
+//
+// If <playlist> is empty, it is fetched from <playlistURL>.
+func (c Checker) checkMedia(playlistURL string, playlist string) error {
+	if playlist == "" {
+		var isPlaylist bool
+		var err error
+		if playlist, isPlaylist, err = c.fetch(playlistURL); err != nil {
+			return errors.Wrap(err, "Fetch media playlist")
+		}
+		if !isPlaylist {
+			return errors.Newf("Variant %v is not a playlist", playlistURL)
+		}
+	}
+
+	media, err := parseMedia(playlistURL, playlist)
+	if err != nil {
+		return err
+	}
+	if media.Ended {
+		return errors.New("Playlist has ended")
+	}
+	if len(media.Segments) == 0 {
+		return errors.New("Playlist has no segments")
+	}
+	if media, err = c.waitUpdate(playlistURL, media); err != nil {
+		return err
+	}
+
+	return c.checkSegment(media.Segments[len(media.Segments)-1], media.Encrypted)
+}
+
+// waitUpdate returns updated media playlist at <playlistURL> or error if it did not update since <media> in time
+func (c Checker) waitUpdate(playlistURL string, media mediaPlaylist) (mediaPlaylist, error) {
+	interval := lo.Ternary(media.TargetDuration > 0, media.TargetDuration/2, time.Second)
+	timeout := lo.Ternary(media.TargetDuration > 0, min(media.TargetDuration*3, c.refreshTimeout), c.refreshTimeout)
+
+	for waited := interval; ; waited += interval {
+		time.Sleep(interval)
+		playlist, isPlaylist, err := c.fetch(playlistURL)
+		if err != nil {
+			return media, errors.Wrap(err, "Refresh media playlist")
+		}
+		if !isPlaylist {
+			return media, errors.New("Refreshed media playlist is not a playlist")
+		}
+		updated, err := parseMedia(playlistURL, playlist)
+		if err != nil {
+			return media, err
+		}
+		if updated.Ended {
+			return media, errors.New("Playlist has ended")
+		}
+		if updated.Sequence > media.Sequence ||
+			(updated.Sequence == media.Sequence && len(updated.Segments) > len(media.Segments)) {
+			return updated, nil
+		}
+		if waited >= timeout {
+			return media, errors.Newf("Media sequence %v did not advance within %v", media.Sequence, timeout)
+		}
+	}
+}
+
+// checkSegment returns error if segment at <segmentURL> can't be downloaded or does not start with MPEG-TS sync byte
+// or fMP4 box.
+//
+// Format of <encrypted> segment is not checked.
+func (c Checker) checkSegment(segmentURL string, encrypted bool) error {
+	resp, err := c.httpClient.Get(segmentURL)
+	if err != nil {
+		return errors.Wrap(err, "Fetch segment")
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 400 {
+		return errors.Newf("Segment responded with: %v", resp.Status)
+	}
+
+	head := make([]byte, segmentProbeSize)
+	n, err := io.ReadFull(resp.Body, head)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) && !errors.Is(err, io.EOF) {
+		return errors.Wrap(err, "Read segment")
+	}
+	head = head[:n]
+
+	if len(head) == 0 {
+		return errors.New("Segment is empty")
+	}
+	if encrypted || isTS(head) || isFMP4(head) {
+		return nil
+	}
+	return errors.New("Segment is neither MPEG-TS nor fMP4")
+}
+
+// fetch returns body of response from <playlistURL> and true if it is HLS playlist or empty string and false
+// otherwise.
+//
+// Returns error if request failed or response status is >= 400.
+func (c Checker) fetch(playlistURL string) (string, bool, error) {
+	resp, err := c.httpClient.Get(playlistURL)
+	if err != nil {
+		return "", false, err
+	}
+	defer resp.Body.Close()
+	// Not checking Content-Type header as servers often respond with wrong one
+	if resp.StatusCode >= 400 {
+		return "", false, errors.Newf("Responded with: %v", resp.Status)
+	}
+
+	// Only peek at the beginning of the body as it can be endless stream
+	reader := bufio.NewReader(io.LimitReader(resp.Body, maxPlaylistSize))
+	head, _ := reader.Peek(len("\ufeff#EXTM3U"))
+	if !strings.HasPrefix(strings.TrimPrefix(string(head), "\ufeff"), "#EXTM3U") {
+		return "", false, nil
+	}
+	playlist, err := io.ReadAll(reader)
+	if err != nil {
+		return "", true, errors.Wrap(err, "Read playlist")
+	}
+
+	return string(playlist), true, nil
+}
+
+// parseMaster returns variants of master <playlist> at <playlistURL>
+func parseMaster(playlistURL string, playlist string) (variants []variant, err error) {
+	var streamInf string
+	for _, line := range lines(playlist) {
+		if tag, found := strings.CutPrefix(line, "#EXT-X-STREAM-INF:"); found {
+			streamInf = tag
+			continue
+		}
+		if strings.HasPrefix(line, "#") || streamInf == "" {
+			continue
+		}
+		variantURL, err := resolve(playlistURL, line)
+		if err != nil {
+			return nil, errors.Wrap(err, "Parse variant URL")
+		}
+		var bandwidth int
+		if match := bandwidthRx.FindStringSubmatch(streamInf); match != nil {
+			bandwidth, _ = strconv.Atoi(match[1])
+		}
+		variants = append(variants, variant{URL: variantURL, Bandwidth: bandwidth})
+		streamInf = ""
+	}
+	return
+}
+
+// parseMedia returns media <playlist> at <playlistURL> parsed
+func parseMedia(playlistURL string, playlist string) (media mediaPlaylist, err error) {
+	for _, line := range lines(playlist) {
+		switch {
+		case strings.HasPrefix(line, "#EXT-X-MEDIA-SEQUENCE:"):
+			if media.Sequence, err = strconv.Atoi(strings.TrimPrefix(line, "#EXT-X-MEDIA-SEQUENCE:")); err != nil {
+				return media, errors.Wrap(err, "Parse media sequence")
+			}
+		case strings.HasPrefix(line, "#EXT-X-TARGETDURATION:"):
+			seconds, err := strconv.ParseFloat(strings.TrimPrefix(line, "#EXT-X-TARGETDURATION:"), 64)
+			if err != nil {
+				return media, errors.Wrap(err, "Parse target duration")
+			}
+			media.TargetDuration = time.Duration(seconds * float64(time.Second))
+		case line == "#EXT-X-ENDLIST":
+			media.Ended = true
+		case strings.HasPrefix(line, "#EXT-X-KEY:"):
+			if match := keyMethodRx.FindStringSubmatch(strings.TrimPrefix(line, "#EXT-X-KEY:")); match != nil {
+				media.Encrypted = match[1] != "NONE"
+			}
+		case !strings.HasPrefix(line, "#"):
+			segmentURL, err := resolve(playlistURL, line)
+			if err != nil {
+				return media, errors.Wrap(err, "Parse segment URL")
+			}
+			media.Segments = append(media.Segments, segmentURL)
+		}
+	}
+	return
+}
+
+// lines returns not empty lines of <playlist> without leading and trailing white space
+func lines(playlist string) []string {
+	return lo.Compact(lo.Map(strings.Split(playlist, "\n"), func(line string, _ int) string {
+		return strings.TrimSpace(line)
+	}))
+}
+
+// resolve returns <ref> URL resolved against <base> URL
+func resolve(base string, ref string) (string, error) {
+	baseURL, err := url.Parse(base)
+	if err != nil {
+		return "", err
+	}
+	refURL, err := url.Parse(ref)
+	if err != nil {
+		return "", err
+	}
+	return baseURL.ResolveReference(refURL).String(), nil
+}
+
+// isTS returns true if <head> starts with MPEG-TS packet
+func isTS(head []byte) bool {
+	return head[0] == 0x47 && (len(head) <= 188 || head[188] == 0x47)
+}
+
+// isFMP4 returns true if <head> starts with box fMP4 segment can start with
+func isFMP4(head []byte) bool {
+	if len(head) < 8 {
+		return false
+	}
+	size := binary.BigEndian.Uint32(head[:4])
+	return (size == 1 || size >= 8) && lo.Contains(fmp4BoxTypes, string(head[4:8]))
+}
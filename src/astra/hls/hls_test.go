@@ -0,0 +1,156 @@
+package hls
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/samber/lo"
+	"github.com/stretchr/testify/assert"
+)
+
+// tsSegment represents beginning of MPEG-TS segment
+var tsSegment = func() []byte {
+	data := make([]byte, 188*2)
+	data[0], data[188] = 0x47, 0x47
+	return data
+}()
+
+// fmp4Segment represents beginning of fMP4 segment
+var fmp4Segment = []byte{0, 0, 0, 24, 's', 't', 'y', 'p', 'm', 's', 'd', 'h', 0, 0, 0, 0, 'm', 's', 'd', 'h', 'm', 's',
+	'i', 'x'}
+
+// newMediaHandler returns handler responding with media playlist which has segments with <segmentPath> and media
+// sequence advancing on every request if <live> is true
+func newMediaHandler(segmentPath string, live bool) http.HandlerFunc {
+	var sequence atomic.Int64
+	return func(w http.ResponseWriter, req *http.Request) {
+		seq := lo.Ternary(live, sequence.Add(1), 0)
+		fmt.Fprintf(w, "#EXTM3U\n#EXT-X-VERSION:3\n#EXT-X-TARGETDURATION:1\n#EXT-X-MEDIA-SEQUENCE:%v\n", seq)
+		fmt.Fprintf(w, "#EXTINF:1.0,\n%v?n=%v\n#EXTINF:1.0,\n%v?n=%v\n", segmentPath, seq, segmentPath, seq+1)
+	}
+}
+
+func TestNew(t *testing.T) {
+	httpClient := &http.Client{}
+	checker := New(httpClient, time.Second)
+	assert.Same(t, httpClient, checker.httpClient, "should set HTTP client")
+	assert.Exactly(t, time.Second, checker.refreshTimeout, "should set refresh timeout")
+}
+
+func TestCheck(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/master.m3u8", func(w http.ResponseWriter, req *http.Request) {
+		fmt.Fprint(w, "#EXTM3U\n#EXT-X-STREAM-INF:BANDWIDTH=2000000,RESOLUTION=1920x1080\nhi/media.m3u8\n"+
+			"#EXT-X-STREAM-INF:BANDWIDTH=500000\n/dead.m3u8\n#EXT-X-STREAM-INF:BANDWIDTH=1000000\nmid/media.m3u8\n")
+	})
+	mux.HandleFunc("/hi/media.m3u8", newMediaHandler("/segment.ts", true))
+	mux.HandleFunc("/mid/media.m3u8", newMediaHandler("../segment.ts", true))
+	mux.HandleFunc("/media.m3u8", newMediaHandler("segment.ts", true))
+	mux.HandleFunc("/fmp4.m3u8", newMediaHandler("segment.m4s", true))
+	mux.HandleFunc("/stale.m3u8", newMediaHandler("segment.ts", false))
+	mux.HandleFunc("/html.m3u8", newMediaHandler("segment.html", true))
+	mux.HandleFunc("/vod.m3u8", func(w http.ResponseWriter, req *http.Request) {
+		fmt.Fprint(w, "#EXTM3U\n#EXT-X-TARGETDURATION:1\n#EXTINF:1.0,\nsegment.ts\n#EXT-X-ENDLIST\n")
+	})
+	mux.HandleFunc("/empty.m3u8", func(w http.ResponseWriter, req *http.Request) {
+		fmt.Fprint(w, "#EXTM3U\n#EXT-X-TARGETDURATION:1\n")
+	})
+	mux.HandleFunc("/segment.ts", func(w http.ResponseWriter, req *http.Request) { _, _ = w.Write(tsSegment) })
+	mux.HandleFunc("/segment.m4s", func(w http.ResponseWriter, req *http.Request) { _, _ = w.Write(fmp4Segment) })
+	mux.HandleFunc("/segment.html", func(w http.ResponseWriter, req *http.Request) {
+		fmt.Fprint(w, "<html>Not found</html>")
+	})
+	mux.HandleFunc("/stream.ts", func(w http.ResponseWriter, req *http.Request) { _, _ = w.Write(tsSegment) })
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	checker := New(srv.Client(), time.Second)
+
+	err := checker.Check(srv.URL + "/media.m3u8")
+	assert.NoError(t, err, "should consider live media playlist with MPEG-TS segments alive")
+
+	err = checker.Check(srv.URL + "/fmp4.m3u8")
+	assert.NoError(t, err, "should consider live media playlist with fMP4 segments alive")
+
+	err = checker.Check(srv.URL + "/master.m3u8")
+	assert.NoError(t, err, "should consider master playlist with alive variant alive")
+
+	err = checker.Check(srv.URL + "/stream.ts")
+	assert.NoError(t, err, "should consider not playlist alive if it responds")
+
+	err = checker.Check(srv.URL + "/stale.m3u8")
+	assert.ErrorContains(t, err, "Media sequence 0 did not advance within 1s")
+
+	err = checker.Check(srv.URL + "/html.m3u8")
+	assert.ErrorContains(t, err, "Segment is neither MPEG-TS nor fMP4")
+
+	err = checker.Check(srv.URL + "/vod.m3u8")
+	assert.ErrorContains(t, err, "Playlist has ended")
+
+	err = checker.Check(srv.URL + "/empty.m3u8")
+	assert.ErrorContains(t, err, "Playlist has no segments")
+
+	err = checker.Check(srv.URL + "/dead.m3u8")
+	assert.ErrorContains(t, err, "Responded with: 404 Not Found")
+}
+
+func TestParseMaster(t *testing.T) {
+	playlist := "#EXTM3U\r\n#EXT-X-VERSION:3\r\n" +
+		"#EXT-X-STREAM-INF:BANDWIDTH=2000000,AVERAGE-BANDWIDTH=1500000,CODECS=\"avc1.4d401f,mp4a.40.2\"\r\n" +
+		"hi/media.m3u8\r\n\r\n" +
+		"#EXT-X-STREAM-INF:RESOLUTION=640x360\r\n" +
+		"http://other/lo.m3u8?token=1\r\n" +
+		"#EXT-X-I-FRAME-STREAM-INF:BANDWIDTH=100000,URI=\"iframe.m3u8\"\r\n"
+
+	variants, err := parseMaster("http://host/live/master.m3u8", playlist)
+	assert.NoError(t, err, "should not return error")
+	expected := []variant{
+		{URL: "http://host/live/hi/media.m3u8", Bandwidth: 2000000},
+		{URL: "http://other/lo.m3u8?token=1", Bandwidth: 0},
+	}
+	assert.Exactly(t, expected, variants, "should return variants with absolute URLs")
+}
+
+func TestParseMedia(t *testing.T) {
+	playlist := "#EXTM3U\n#EXT-X-TARGETDURATION:6\n#EXT-X-MEDIA-SEQUENCE:42\n" +
+		"#EXT-X-KEY:METHOD=AES-128,URI=\"key.bin\"\n" +
+		"#EXTINF:6.0,\nsegment_42.ts\n#EXTINF:6.0,\n/abs/segment_43.ts\n"
+
+	media, err := parseMedia("http://host/live/media.m3u8", playlist)
+	assert.NoError(t, err, "should not return error")
+	expected := mediaPlaylist{
+		Sequence:       42,
+		TargetDuration: time.Second * 6,
+		Segments:       []string{"http://host/live/segment_42.ts", "http://host/abs/segment_43.ts"},
+		Ended:          false,
+		Encrypted:      true,
+	}
+	assert.Exactly(t, expected, media, "should parse media playlist")
+
+	media, err = parseMedia("http://host/media.m3u8", "#EXTM3U\n#EXT-X-KEY:METHOD=NONE\nsegment.ts\n#EXT-X-ENDLIST")
+	assert.NoError(t, err, "should not return error")
+	assert.True(t, media.Ended, "should detect end of playlist")
+	assert.False(t, media.Encrypted, "should not consider segments without encryption method encrypted")
+
+	_, err = parseMedia("http://host/media.m3u8", "#EXTM3U\n#EXT-X-MEDIA-SEQUENCE:abc\n")
+	assert.ErrorContains(t, err, "Parse media sequence")
+}
+
+func TestIsTS(t *testing.T) {
+	assert.True(t, isTS(tsSegment), "should detect MPEG-TS by sync bytes")
+	assert.True(t, isTS([]byte{0x47, 0, 0}), "should detect short MPEG-TS by the first sync byte")
+	assert.False(t, isTS(append([]byte{0x47}, make([]byte, 200)...)), "should check the second sync byte")
+	assert.False(t, isTS([]byte("<html>")), "should not detect other data as MPEG-TS")
+}
+
+func TestIsFMP4(t *testing.T) {
+	assert.True(t, isFMP4(fmp4Segment), "should detect fMP4 by box")
+	assert.True(t, isFMP4([]byte{0, 0, 0, 1, 'm', 'o', 'o', 'f'}), "should detect box with large size")
+	assert.False(t, isFMP4([]byte{0, 0, 0, 4, 'm', 'o', 'o', 'f'}), "should not detect box with invalid size")
+	assert.False(t, isFMP4([]byte{0, 0, 0, 8, 'm', 'd', 'a', 't'}), "should not detect box segment can't start with")
+	assert.False(t, isFMP4([]byte{0, 0, 0}), "should not detect too short data")
+}
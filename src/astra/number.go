@@ -0,0 +1,48 @@
+package astra
+
+import (
+	"math"
+	"strconv"
+	"strings"
+
+	json "github.com/SCP002/jsonexraw"
+	"github.com/cockroachdb/errors"
+)
+
+// Number represents integer field of astra config which astra stores either as a JSON number or as a string
+type Number int
+
+// UnmarshalJSON sets <n> from JSON number, numeric string or null in <data>.
+//
+// Empty string is not accepted as astra treats it as not set rather than as zero.
+func (n *Number) UnmarshalJSON(data []byte) error {
+	var val any
+	if err := json.Unmarshal(data, &val); err != nil {
+		return errors.Wrap(err, "Decode number")
+	}
+
+	switch val := val.(type) {
+	case nil:
+		*n = 0
+	case float64:
+		if val != math.Trunc(val) {
+			return errors.Newf("Decode number: %v is not an integer", val)
+		}
+		*n = Number(val)
+	case string:
+		i, err := strconv.Atoi(strings.TrimSpace(val))
+		if err != nil {
+			return errors.Wrap(err, "Decode number")
+		}
+		*n = Number(i)
+	default:
+		return errors.Newf("Decode number: unexpected value %v", string(data))
+	}
+
+	return nil
+}
+
+// MarshalJSON returns <n> as JSON number
+func (n Number) MarshalJSON() ([]byte, error) {
+	return []byte(strconv.Itoa(int(n))), nil
+}
@@ -0,0 +1,43 @@
+package astra
+
+import (
+	"testing"
+
+	json "github.com/SCP002/jsonexraw"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNumberUnmarshalJSON(t *testing.T) {
+	test := func(input string, expected Number) {
+		var n Number
+		err := json.Unmarshal([]byte(input), &n)
+		assert.NoError(t, err, "should not return error for "+input)
+		assert.Exactly(t, expected, n, "should decode "+input)
+	}
+	test(`10`, 10)
+	test(`10.0`, 10)
+	test(`-5`, -5)
+	test(`"10"`, 10)
+	test(`" 10 "`, 10)
+	test(`null`, 0)
+
+	testErr := func(input string) {
+		var n Number
+		err := json.Unmarshal([]byte(input), &n)
+		assert.Error(t, err, "should return error for "+input)
+	}
+	testErr(`10.5`)
+	testErr(`"abc"`)
+	testErr(`""`)
+	testErr(`true`)
+	testErr(`[1]`)
+}
+
+func TestNumberMarshalJSON(t *testing.T) {
+	out, err := json.Marshal(struct {
+		A Number `json:"a"`
+		B Number `json:"b,omitempty"`
+	}{A: 10})
+	assert.NoError(t, err, "should not return error")
+	assert.Exactly(t, `{"a":10}`, string(out), "should encode as JSON number and omit zero value")
+}
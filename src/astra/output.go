@@ -0,0 +1,169 @@
+package astra
+
+import (
+	"fmt"
+	"net/netip"
+	"net/url"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+
+	"m3u_merge_astra/cfg"
+	"m3u_merge_astra/util/slice"
+
+	"github.com/cockroachdb/errors"
+	"github.com/samber/lo"
+)
+
+// ipPortRx represents regular expression matching IPv4 address with port, such as in "udp://eth0@239.0.0.1:1234"
+var ipPortRx = regexp.MustCompile(`\d{1,3}(?:\.\d{1,3}){3}:\d+`)
+
+// multicastAllocator represents allocator of multicast addresses not used by outputs of astra streams
+type multicastAllocator struct {
+	prefix netip.Prefix
+	port   int
+	next   netip.Addr
+	used   map[string]bool // "address:port" pairs
+}
+
+// newMulticastAllocator returns new allocator of addresses from <cidr> range with <port>, skipping network address of
+// the range (unless it has no more than two addresses) and addresses used by outputs of <streams>.
+//
+// Returns error if <cidr> is not a valid range.
+func newMulticastAllocator(cidr string, port int, streams []Stream) (*multicastAllocator, error) {
+	prefix, err := netip.ParsePrefix(cidr)
+	if err != nil {
+		return nil, errors.Wrap(err, "Parse multicast range")
+	}
+	prefix = prefix.Masked()
+
+	used := map[string]bool{}
+	for _, s := range streams {
+		for _, output := range s.Outputs {
+			for _, ipPort := range ipPortRx.FindAllString(output, -1) {
+				used[ipPort] = true
+			}
+		}
+	}
+
+	next := prefix.Addr()
+	if prefix.Bits() < next.BitLen()-1 {
+		next = next.Next()
+	}
+
+	return &multicastAllocator{prefix: prefix, port: port, next: next, used: used}, nil
+}
+
+// allocate returns next unused "address:port" pair from the range and true or empty string and false if all addresses
+// of the range are used
+func (a *multicastAllocator) allocate() (string, bool) {
+	for ; a.next.IsValid() && a.prefix.Contains(a.next); a.next = a.next.Next() {
+		ipPort := fmt.Sprintf("%v:%v", a.next, a.port)
+		if !a.used[ipPort] {
+			a.used[ipPort] = true
+			a.next = a.next.Next()
+			return ipPort, true
+		}
+	}
+	return "", false
+}
+
+// SetOutputs returns shallow copy of <streams> with outputs set according to cfg.Streams.*ToOutputsMap rules.
+//
+// Only first matching rule applies per stream in the priority: By inputs -> By name -> By group.
+//
+// Placeholders {id}, {name} and {group} in outputs are replaced with ID, URL encoded name and URL encoded group
+// (without category) of the stream. Placeholder {multicast} is replaced with address from
+// cfg.Streams.OutputMulticastRange which is not used by outputs of any of <streams>, unless stream already has output
+// matching the template.
+func (r repo) SetOutputs(streams []Stream) (out []Stream) {
+	r.log.Info("Setting outputs on streams")
+
+	allocator, err := newMulticastAllocator(r.cfg.Streams.OutputMulticastRange, r.cfg.Streams.OutputMulticastPort,
+		streams)
+	if err != nil {
+		r.log.Error(err)
+	}
+
+	for _, s := range streams {
+		if s.Remove {
+			out = append(out, s)
+			continue
+		}
+		var rule cfg.OutputsSetRule
+		var found bool
+		if rule, found = lo.Find(r.cfg.Streams.InputToOutputsMap, func(rule cfg.OutputsSetRule) bool {
+			return slice.RxMatchAny(rule.By, s.Inputs...)
+		}); !found {
+			if rule, found = lo.Find(r.cfg.Streams.NameToOutputsMap, func(rule cfg.OutputsSetRule) bool {
+				return rule.By.MatchString(s.Name)
+			}); !found {
+				rule, found = lo.Find(r.cfg.Streams.GroupToOutputsMap, func(rule cfg.OutputsSetRule) bool {
+					return rule.By.MatchString(s.FirstGroup())
+				})
+			}
+		}
+		if found {
+			s = r.setOutputs(s, rule, allocator)
+		}
+		out = append(out, s)
+	}
+
+	return
+}
+
+// setOutputs returns shallow copy of stream <s> with outputs set according to <rule> using <allocator> for
+// {multicast} placeholder
+func (r repo) setOutputs(s Stream, rule cfg.OutputsSetRule, allocator *multicastAllocator) Stream {
+	replacer := strings.NewReplacer("{id}", s.ID, "{name}", url.PathEscape(s.Name), "{group}",
+		url.PathEscape(s.firstGroupName()))
+
+	outputs := []string{}
+	for _, tmpl := range rule.Outputs {
+		output := replacer.Replace(tmpl)
+		if strings.Contains(output, "{multicast}") {
+			// Reuse address of existing output matching the template to keep outputs stable between runs
+			rx := regexp.MustCompile("^" + strings.ReplaceAll(regexp.QuoteMeta(output), `\{multicast\}`,
+				"("+ipPortRx.String()+")") + "$")
+			existing, found := lo.Find(s.Outputs, func(o string) bool { return rx.MatchString(o) })
+			switch {
+			case found:
+				output = existing
+			case allocator == nil:
+				continue
+			default:
+				ipPort, ok := allocator.allocate()
+				if !ok {
+					r.log.ErrorFi("No free multicast address left for output of stream", "ID", s.ID, "name", s.Name,
+						"output", tmpl, "range", r.cfg.Streams.OutputMulticastRange)
+					continue
+				}
+				output = strings.ReplaceAll(output, "{multicast}", ipPort)
+			}
+		}
+		outputs = append(outputs, output)
+	}
+
+	if !rule.Replace {
+		outputs = lo.Uniq(append(append([]string{}, s.Outputs...), outputs...))
+	}
+	if !slices.Equal(s.Outputs, outputs) {
+		r.log.InfoFi("Setting outputs of stream", "ID", s.ID, "name", s.Name, "group", s.FirstGroup(), "outputs",
+			strings.Join(outputs, ", "))
+		s.Outputs = outputs
+	}
+
+	return s
+}
+
+// firstGroupName returns group (without category) of alphabetically first "category: group" pair or empty string if
+// groups are empty
+func (s Stream) firstGroupName() string {
+	if len(s.Groups) == 0 {
+		return ""
+	}
+	cats := lo.Keys(s.Groups)
+	sort.Strings(cats)
+	return s.Groups[cats[0]]
+}
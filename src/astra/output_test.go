@@ -0,0 +1,156 @@
+package astra
+
+import (
+	"regexp"
+	"testing"
+
+	"m3u_merge_astra/cfg"
+	"m3u_merge_astra/util/copier"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/zenizh/go-capturer"
+)
+
+// newOutputsRepo returns default repo with outputs rules set up for tests
+func newOutputsRepo() repo {
+	r := newDefRepo()
+	r.cfg.Streams.NameToOutputsMap = []cfg.OutputsSetRule{
+		{By: *regexp.MustCompile(`Known name 1`), Outputs: []string{"http://0:8000/play/{id}"}, Replace: true},
+	}
+	r.cfg.Streams.GroupToOutputsMap = []cfg.OutputsSetRule{
+		{By: *regexp.MustCompile(`Known group 1`), Outputs: []string{"http://0:8000/{group}/{name}"}},
+	}
+	r.cfg.Streams.InputToOutputsMap = []cfg.OutputsSetRule{
+		{By: *regexp.MustCompile(`http://known/input/1`), Outputs: []string{"udp://eth0@{multicast}"}},
+	}
+	r.cfg.Streams.OutputMulticastRange = "239.0.0.0/30"
+	r.cfg.Streams.OutputMulticastPort = 1234
+	return r
+}
+
+func TestSetOutputs(t *testing.T) {
+	cat := newDefRepo().cfg.Streams.GroupsCategoryForNew
+
+	sl1 := []Stream{
+		{ // Index 0. Known name 1 and group 1, replace outputs
+			ID:      "0000",
+			Name:    "Known name 1",
+			Groups:  map[string]string{cat: "Known group 1"},
+			Inputs:  []string{"http://other/input/1"},
+			Outputs: []string{"http://old/output"},
+		},
+		{ // Index 1. Known group 1, add outputs
+			ID:      "0001",
+			Name:    "Other name",
+			Groups:  map[string]string{cat: "Known group 1"},
+			Inputs:  []string{"http://other/input/1"},
+			Outputs: []string{"http://old/output"},
+		},
+		{ // Index 2. Known input 1 and name 1, allocate multicast address not used by index 3
+			ID:     "0002",
+			Name:   "Known name 1",
+			Inputs: []string{"http://known/input/1"},
+		},
+		{ // Index 3. No matches, but uses multicast address from the range
+			ID:      "0003",
+			Name:    "Other name",
+			Inputs:  []string{"http://other/input/1"},
+			Outputs: []string{"udp://eth1@239.0.0.0:1234"},
+		},
+		{ // Index 4. Known input 1, reuse existing multicast address
+			ID:      "0004",
+			Name:    "Other name",
+			Inputs:  []string{"http://known/input/1"},
+			Outputs: []string{"udp://eth0@239.0.0.3:1234"},
+		},
+		{ // Index 5. Known input 1, take last free multicast address
+			ID:     "0005",
+			Name:   "Other name",
+			Inputs: []string{"http://known/input/1"},
+		},
+		{ // Index 6. Known input 1, no free multicast address left
+			ID:     "0006",
+			Name:   "Other name",
+			Inputs: []string{"http://known/input/1"},
+		},
+		{ // Index 7. Known name 1, but removed
+			ID:     "0007",
+			Name:   "Known name 1",
+			Inputs: []string{"http://other/input/1"},
+			Remove: true,
+		},
+		{ // Index 8. Known group 1, outputs already set
+			ID:      "0008",
+			Name:    "Other name",
+			Groups:  map[string]string{cat: "Known group 1"},
+			Inputs:  []string{"http://other/input/1"},
+			Outputs: []string{"http://0:8000/Known%20group%201/Other%20name"},
+		},
+	}
+	sl1Original := copier.TestDeep(t, sl1)
+
+	var sl2 []Stream
+	out := capturer.CaptureStderr(func() {
+		sl2 = newOutputsRepo().SetOutputs(sl1)
+	})
+	assert.NotSame(t, &sl1, &sl2, "should return copy of streams")
+	assert.Exactly(t, sl1Original, sl1, "should not modify the source")
+	assert.Len(t, sl2, len(sl1), "amount of output streams should stay the same")
+
+	assert.Exactly(t, []string{"http://0:8000/play/0000"}, sl2[0].Outputs,
+		"should replace outputs by name rule as it has priority over group rule")
+	assert.Exactly(t, []string{"http://old/output", "http://0:8000/Known%20group%201/Other%20name"}, sl2[1].Outputs,
+		"should add outputs by group rule with URL encoded group and name")
+	assert.Exactly(t, []string{"udp://eth0@239.0.0.1:1234"}, sl2[2].Outputs,
+		"should add output by input rule as it has priority over name rule, skipping used multicast address")
+	assert.Exactly(t, sl1[3], sl2[3], "should not change stream without matches")
+	assert.Exactly(t, sl1[4], sl2[4], "should reuse existing multicast address")
+	assert.Exactly(t, []string{"udp://eth0@239.0.0.2:1234"}, sl2[5].Outputs, "should take last free multicast address")
+	assert.Exactly(t, sl1[6], sl2[6], "should not add output if no free multicast address left")
+	assert.Exactly(t, sl1[7], sl2[7], "should not change removed stream")
+	assert.Exactly(t, sl1[8], sl2[8], "should not change stream with outputs already set")
+
+	assert.Contains(t, out, `No free multicast address left for output of stream: ID "0006", name "Other name", `+
+		`output "udp://eth0@{multicast}", range "239.0.0.0/30"`)
+	assert.Contains(t, out, `Setting outputs of stream: ID "0000", name "Known name 1", group "`+cat+
+		`: Known group 1", outputs "http://0:8000/play/0000"`)
+	assert.NotContains(t, out, `ID "0003"`, "should not log anything for stream without matches")
+	assert.NotContains(t, out, `ID "0008"`, "should not log anything for stream with outputs already set")
+
+	// Invalid range
+	out = capturer.CaptureStderr(func() {
+		r := newOutputsRepo()
+		r.cfg.Streams.OutputMulticastRange = "invalid"
+		sl2 = r.SetOutputs(sl1)
+	})
+	assert.Contains(t, out, "Parse multicast range")
+	assert.Exactly(t, sl1[5], sl2[5], "should not add multicast output if range is invalid")
+	assert.Exactly(t, []string{"http://0:8000/play/0000"}, sl2[0].Outputs, "should still apply other outputs")
+}
+
+func TestMulticastAllocatorAllocate(t *testing.T) {
+	streams := []Stream{{Outputs: []string{"udp://eth0@239.1.0.1:5000", "udp://239.1.0.2:1234"}}}
+	a, err := newMulticastAllocator("239.1.0.1/30", 5000, streams)
+	assert.NoError(t, err, "should not return error for valid range")
+
+	var got []string
+	for {
+		ipPort, ok := a.allocate()
+		if !ok {
+			break
+		}
+		got = append(got, ipPort)
+	}
+	assert.Exactly(t, []string{"239.1.0.2:5000", "239.1.0.3:5000"}, got,
+		"should allocate addresses of masked range with given port, skipping network address and used address and port "+
+			"pairs")
+
+	a, err = newMulticastAllocator("239.1.0.1/32", 5000, nil)
+	assert.NoError(t, err, "should not return error for single address range")
+	ipPort, ok := a.allocate()
+	assert.True(t, ok, "should allocate the only address")
+	assert.Exactly(t, "239.1.0.1:5000", ipPort, "should not skip the only address")
+
+	_, err = newMulticastAllocator("239.1.0.1", 5000, streams)
+	assert.Error(t, err, "should return error for address without prefix length")
+}
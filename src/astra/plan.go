@@ -0,0 +1,88 @@
+package astra
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"time"
+
+	json "github.com/SCP002/jsonexraw"
+	"github.com/cockroachdb/errors"
+	"github.com/samber/lo"
+)
+
+// Plan represents changes to astra config computed in advance to be sent later
+type Plan struct {
+	Created     time.Time      `json:"created"`
+	Fingerprint string         `json:"fingerprint"` // Fingerprint of astra config the changes were computed from
+	Categories  []PlanCategory `json:"categories"`
+	Streams     []Stream       `json:"streams"`
+}
+
+// PlanCategory represents entry of changed categories (see ChangedCategories) in a plan
+type PlanCategory struct {
+	Idx      int      `json:"idx"`
+	Category Category `json:"category"`
+}
+
+// NewPlan returns new plan of sending <idxCategoryMap> and <streams> computed from <astraCfg>
+func NewPlan(astraCfg Cfg, idxCategoryMap []lo.Entry[int, Category], streams []Stream) (Plan, error) {
+	fingerprint, err := Fingerprint(astraCfg)
+	if err != nil {
+		return Plan{}, errors.Wrap(err, "Create plan")
+	}
+	cats := lo.Map(idxCategoryMap, func(entry lo.Entry[int, Category], _ int) PlanCategory {
+		return PlanCategory{Idx: entry.Key, Category: entry.Value}
+	})
+	return Plan{Created: time.Now(), Fingerprint: fingerprint, Categories: cats, Streams: streams}, nil
+}
+
+// CategoryMap returns categories of the plan in the format of ChangedCategories
+func (p Plan) CategoryMap() []lo.Entry[int, Category] {
+	return lo.Map(p.Categories, func(pc PlanCategory, _ int) lo.Entry[int, Category] {
+		return lo.Entry[int, Category]{Key: pc.Idx, Value: pc.Category}
+	})
+}
+
+// Fingerprint returns hex encoded SHA-256 hash of <astraCfg> encoded as JSON, including unknown fields
+func Fingerprint(astraCfg Cfg) (string, error) {
+	cfgBytes, err := json.Marshal(astraCfg)
+	if err != nil {
+		return "", errors.Wrap(err, "Encode astra config to get fingerprint")
+	}
+	hash := sha256.Sum256(cfgBytes)
+	return hex.EncodeToString(hash[:]), nil
+}
+
+// SavePlan writes <plan> to JSON file at <path>
+func (r repo) SavePlan(path string, plan Plan) error {
+	r.log.InfoFi("Saving plan", "path", path, "categories", len(plan.Categories), "streams", len(plan.Streams))
+
+	planBytes, err := json.MarshalIndent(plan, "", "  ")
+	if err != nil {
+		return errors.Wrap(err, "Encode plan")
+	}
+
+	if err := os.WriteFile(path, planBytes, 0644); err != nil {
+		return errors.Wrap(err, "Write plan")
+	}
+
+	return nil
+}
+
+// ReadPlan returns plan read from JSON file at <path>
+func (r repo) ReadPlan(path string) (Plan, error) {
+	r.log.InfoFi("Reading plan", "path", path)
+
+	planBytes, err := os.ReadFile(path)
+	if err != nil {
+		return Plan{}, errors.Wrap(err, "Read plan")
+	}
+
+	var plan Plan
+	if err := json.Unmarshal(planBytes, &plan); err != nil {
+		return Plan{}, errors.Wrap(err, "Decode plan")
+	}
+
+	return plan, nil
+}
@@ -0,0 +1,79 @@
+package astra
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/samber/lo"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewPlan(t *testing.T) {
+	astraCfg := Cfg{
+		Categories: []Category{{Name: "Category 1"}},
+		Streams:    []Stream{{ID: "0", Name: "Stream 0"}},
+	}
+	catMap := []lo.Entry[int, Category]{
+		{Key: -1, Value: Category{Name: "Category 2"}},
+		{Key: 0, Value: Category{Name: "Category 1", Remove: true}},
+	}
+	streams := []Stream{{ID: "0", Name: "Stream 0 renamed"}}
+
+	plan, err := NewPlan(astraCfg, catMap, streams)
+	assert.NoError(t, err, "should not return error")
+	fingerprint, _ := Fingerprint(astraCfg)
+	assert.Exactly(t, fingerprint, plan.Fingerprint, "should have fingerprint of astra config")
+	assert.False(t, plan.Created.IsZero(), "should have creation time")
+	assert.Exactly(t, streams, plan.Streams, "should have changed streams")
+	assert.Exactly(t, catMap, plan.CategoryMap(), "should have changed categories in the same order")
+}
+
+func TestFingerprint(t *testing.T) {
+	astraCfg := Cfg{
+		Categories: []Category{{Name: "Category 1"}},
+		Streams: []Stream{
+			{ID: "0", Name: "Stream 0", Unknown: map[string]any{"b": "val", "a": 1.0, "c": []any{"x", "y"}}},
+		},
+		Unknown: map[string]any{"users": map[string]any{"admin": "x"}, "gid": 1.0},
+	}
+
+	fingerprint, err := Fingerprint(astraCfg)
+	assert.NoError(t, err, "should not return error")
+	assert.Len(t, fingerprint, 64, "should return hex encoded SHA-256 hash")
+	for range 10 {
+		actual, _ := Fingerprint(astraCfg)
+		assert.Exactly(t, fingerprint, actual, "should return the same fingerprint for the same config")
+	}
+
+	astraCfg.Streams[0].Unknown["b"] = "changed"
+	actual, _ := Fingerprint(astraCfg)
+	assert.NotEqual(t, fingerprint, actual, "should return different fingerprint if unknown fields changed")
+}
+
+func TestSaveReadPlan(t *testing.T) {
+	r := newDefRepo()
+
+	path := filepath.Join(t.TempDir(), "plan.json")
+	plan, _ := NewPlan(Cfg{}, []lo.Entry[int, Category]{{Key: -1, Value: Category{Name: "Category 1"}}},
+		[]Stream{{ID: "0", Name: "Stream 0", Unknown: map[string]any{"key": "val"}}})
+
+	err := r.SavePlan(path, plan)
+	assert.NoError(t, err, "should not return error")
+
+	actual, err := r.ReadPlan(path)
+	assert.NoError(t, err, "should not return error")
+	assert.True(t, plan.Created.Equal(actual.Created), "should read the same creation time")
+	actual.Created = plan.Created
+	assert.Exactly(t, plan, actual, "should read the same plan including unknown fields")
+
+	// Test reading damaged plan
+	err = os.WriteFile(path, []byte("{"), 0644)
+	assert.NoError(t, err, "should write damaged plan")
+	_, err = r.ReadPlan(path)
+	assert.Error(t, err, "should return error")
+
+	// Test writing to missing directory
+	err = r.SavePlan(filepath.Join(path, "missing", "plan.json"), plan)
+	assert.Error(t, err, "should return error")
+}
@@ -0,0 +1,190 @@
+package astra
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+
+	"m3u_merge_astra/util/copier"
+
+	json "github.com/SCP002/jsonexraw"
+	"github.com/google/go-cmp/cmp"
+	"github.com/samber/lo"
+)
+
+// Conflict represents stream or category changed both by the program and in astra since astra config was fetched
+type Conflict struct {
+	StreamID string   // Empty for categories
+	Name     string   // Name of the stream or category
+	Fields   []string // Conflicting JSON fields of the stream or "groups" of the category
+	Reason   string
+}
+
+// RebaseStreams returns deep copy of <changed> streams, which were built from <original> streams, rebased on top of
+// <current> streams with three-way merge, and streams which can not be rebased because of conflicts.
+//
+// Every field of a stream changed only by the program or only in astra is taken from the side where it was changed.
+// Stream is a conflict if the same field is changed differently on both sides, if it was removed in astra but changed
+// by the program or if it was changed in astra but removed by the program. Conflicts are not included into output.
+//
+// Streams which became equal to the current ones after rebasing are not included into output.
+func (r repo) RebaseStreams(original, current, changed []Stream) (out []Stream, conflicts []Conflict) {
+	r.log.Info("Rebasing changed streams on top of the current astra config")
+
+	for _, chStream := range copier.MustDeep(changed) {
+		origStream, origFound := lo.Find(original, func(s Stream) bool { return s.ID == chStream.ID })
+		currStream, currFound := lo.Find(current, func(s Stream) bool { return s.ID == chStream.ID })
+
+		conflict := Conflict{StreamID: chStream.ID, Name: chStream.Name}
+		switch {
+		case chStream.Remove && !currFound:
+			r.log.DebugFi("Stream to remove is already removed", "ID", chStream.ID, "name", chStream.Name)
+			continue
+		case chStream.Remove && !cmp.Equal(origStream, currStream):
+			conflict.Reason = "Changed in astra but removed by the program"
+		case chStream.Remove:
+			out = append(out, chStream)
+			continue
+		case origFound && !currFound:
+			conflict.Reason = "Removed in astra but changed by the program"
+		default:
+			merged, fields := mergeStream(lo.Ternary(origFound, origStream, Stream{}), currStream, chStream)
+			if len(fields) == 0 {
+				if !currFound || !reflect.DeepEqual(streamToMap(merged), streamToMap(currStream)) {
+					out = append(out, merged)
+				}
+				continue
+			}
+			conflict.Fields = fields
+			conflict.Reason = "Changed differently in astra and by the program"
+		}
+		r.log.WarnFi("Skipping stream changed concurrently in astra", "ID", conflict.StreamID, "name",
+			conflict.Name, "fields", strings.Join(conflict.Fields, ", "), "reason", conflict.Reason)
+		conflicts = append(conflicts, conflict)
+	}
+
+	return
+}
+
+// RebaseCategories returns deep copy of <changed> categories, which were built from <original> categories, with
+// indexes re-resolved by name in <current> categories and groups rebased with three-way merge, and categories which
+// can not be rebased because of conflicts.
+//
+// Groups added or removed by the program are added to or removed from the current category, groups added or removed
+// in astra are kept. Category is a conflict if it was removed in astra but changed by the program or if it was changed
+// in astra but removed by the program. Conflicts are not included into output.
+//
+// Key (index) in output is the same as in ChangedCategories.
+func (r repo) RebaseCategories(original, current []Category,
+	changed []lo.Entry[int, Category]) (out []lo.Entry[int, Category], conflicts []Conflict) {
+	r.log.Info("Rebasing changed categories on top of the current astra config")
+
+	for _, entry := range copier.MustDeep(changed) {
+		chCat := entry.Value
+		origCat, origFound := lo.Find(original, func(c Category) bool { return c.Name == chCat.Name })
+		currCat, currIdx, currFound := lo.FindIndexOf(current, func(c Category) bool { return c.Name == chCat.Name })
+
+		conflict := Conflict{Name: chCat.Name}
+		switch {
+		case !currFound && chCat.Remove:
+			r.log.DebugFi("Category to remove is already removed", "name", chCat.Name)
+			continue
+		case !currFound && origFound:
+			conflict.Reason = "Removed in astra but changed by the program"
+		case !currFound:
+			chCat.Groups = lo.Reject(chCat.Groups, func(g Group, _ int) bool { return g.Remove })
+			out = append(out, lo.Entry[int, Category]{Key: -1, Value: chCat})
+			continue
+		case chCat.Remove && !cmp.Equal(origCat, currCat):
+			conflict.Fields = []string{"groups"}
+			conflict.Reason = "Changed in astra but removed by the program"
+		case chCat.Remove:
+			out = append(out, lo.Entry[int, Category]{Key: currIdx, Value: chCat})
+			continue
+		default:
+			chCat.Groups = mergeGroups(origCat.Groups, currCat.Groups, chCat.Groups)
+			if !cmp.Equal(chCat, currCat) {
+				out = append(out, lo.Entry[int, Category]{Key: currIdx, Value: chCat})
+			}
+			continue
+		}
+		r.log.WarnFi("Skipping category changed concurrently in astra", "name", conflict.Name, "reason",
+			conflict.Reason)
+		conflicts = append(conflicts, conflict)
+	}
+
+	// Keep the order of ChangedCategories: categories to remove at the end with indexes in decreasing order
+	sort.SliceStable(out, func(i, j int) bool {
+		if !out[i].Value.Remove && out[j].Value.Remove {
+			return true
+		}
+		if out[i].Value.Remove && out[j].Value.Remove {
+			return out[i].Key > out[j].Key
+		}
+		return false
+	})
+
+	return
+}
+
+// mergeStream returns result of three-way merge of JSON fields of <curr> and <changed> streams with common ancestor
+// <orig> and names of conflicting fields.
+func mergeStream(orig, curr, changed Stream) (Stream, []string) {
+	origMap, currMap, chMap := streamToMap(orig), streamToMap(curr), streamToMap(changed)
+
+	merged := map[string]any{}
+	conflicts := []string{}
+	keys := lo.Uniq(lo.Flatten([][]string{lo.Keys(origMap), lo.Keys(currMap), lo.Keys(chMap)}))
+	sort.Strings(keys)
+	for _, key := range keys {
+		origVal, currVal, chVal := origMap[key], currMap[key], chMap[key]
+		var val any
+		switch {
+		case reflect.DeepEqual(chVal, origVal):
+			val = currVal
+		case reflect.DeepEqual(currVal, origVal) || reflect.DeepEqual(currVal, chVal):
+			val = chVal
+		default:
+			conflicts = append(conflicts, key)
+			continue
+		}
+		if val != nil {
+			merged[key] = val
+		}
+	}
+
+	var out Stream
+	mergedBytes, _ := json.Marshal(merged)
+	_ = json.Unmarshal(mergedBytes, &out)
+	out.MarkAdded, out.MarkDisabled = changed.MarkAdded, changed.MarkDisabled
+
+	return out, conflicts
+}
+
+// streamToMap returns JSON fields of stream <s> as a map
+func streamToMap(s Stream) map[string]any {
+	out := map[string]any{}
+	sBytes, _ := json.Marshal(s)
+	_ = json.Unmarshal(sBytes, &out)
+	return out
+}
+
+// mergeGroups returns result of three-way merge of <curr> and <changed> groups with common ancestor <orig>.
+//
+// Groups of <changed> with Remove field set to true are considered as removed.
+func mergeGroups(orig, curr, changed []Group) (out []Group) {
+	hasGroup := func(groups []Group, name string) bool {
+		return lo.ContainsBy(groups, func(g Group) bool { return g.Name == name && !g.Remove })
+	}
+	for _, g := range curr {
+		removedByProgram := hasGroup(orig, g.Name) && !hasGroup(changed, g.Name)
+		out = append(out, Group{Name: g.Name, Remove: removedByProgram})
+	}
+	for _, g := range changed {
+		addedByProgram := !g.Remove && !hasGroup(orig, g.Name)
+		if addedByProgram && !hasGroup(curr, g.Name) {
+			out = append(out, g)
+		}
+	}
+	return
+}
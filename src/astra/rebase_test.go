@@ -0,0 +1,138 @@
+package astra
+
+import (
+	"testing"
+
+	"m3u_merge_astra/util/copier"
+
+	"github.com/samber/lo"
+	"github.com/stretchr/testify/assert"
+	"github.com/zenizh/go-capturer"
+)
+
+func TestRebaseStreams(t *testing.T) {
+	r := newDefRepo()
+
+	original := []Stream{
+		{ID: "0", Name: "Stream 0", Inputs: []string{"http://a"}},
+		{ID: "1", Name: "Stream 1", Inputs: []string{"http://b"}},
+		{ID: "2", Name: "Stream 2", Inputs: []string{"http://c"}},
+		{ID: "3", Name: "Stream 3"},
+		{ID: "4", Name: "Stream 4"},
+		{ID: "5", Name: "Stream 5"},
+		{ID: "6", Name: "Stream 6", Inputs: []string{"http://g"}},
+	}
+	current := []Stream{
+		{ID: "0", Name: "Stream 0 renamed in astra", Inputs: []string{"http://a"}},        // Other field changed
+		{ID: "1", Name: "Stream 1", Inputs: []string{"http://b2"}},                        // Same field changed
+		{ID: "3", Name: "Stream 3"},                                                       // Unchanged
+		{ID: "4", Name: "Stream 4", Enabled: true},                                        // Changed but removed by us
+		{ID: "5", Name: "Stream 5"},                                                       // Removed by us
+		{ID: "6", Name: "Stream 6", Inputs: []string{"http://g", "http://h"}},             // Changed the same way
+		{ID: "7", Name: "Stream 7 added in astra", Unknown: map[string]any{"key": "val"}}, // Not changed by us
+	}
+	changed := []Stream{
+		{ID: "0", Name: "Stream 0", Inputs: []string{"http://a", "http://a2"}},
+		{ID: "1", Name: "Stream 1", Inputs: []string{"http://b3"}},
+		{ID: "2", Name: "Stream 2 renamed", Inputs: []string{"http://c"}}, // Removed in astra
+		{ID: "3", Name: "Stream 3", Remove: true, MarkDisabled: true},
+		{ID: "4", Name: "Stream 4", Remove: true},
+		{ID: "5", Name: "Stream 5", Remove: true},
+		{ID: "6", Name: "Stream 6", Inputs: []string{"http://g", "http://h"}},
+		{ID: "8", Name: "Stream 8", Inputs: []string{"http://i"}, MarkAdded: true}, // New
+		{ID: "9", Name: "Stream 9", Remove: true},                                  // Already removed
+	}
+	currentOriginal := copier.TestDeep(t, current)
+	changedOriginal := copier.TestDeep(t, changed)
+
+	var out []Stream
+	var conflicts []Conflict
+	log := capturer.CaptureStderr(func() {
+		r := newDefRepo()
+		out, conflicts = r.RebaseStreams(original, current, changed)
+	})
+
+	expected := []Stream{
+		{ID: "0", Name: "Stream 0 renamed in astra", Inputs: []string{"http://a", "http://a2"}},
+		{ID: "3", Name: "Stream 3", Remove: true, MarkDisabled: true},
+		{ID: "5", Name: "Stream 5", Remove: true},
+		{ID: "8", Name: "Stream 8", Inputs: []string{"http://i"}, MarkAdded: true},
+	}
+	assert.Exactly(t, expected, out, "should return streams rebased on top of the current ones")
+
+	expectedConflicts := []Conflict{
+		{StreamID: "1", Name: "Stream 1", Fields: []string{"input"},
+			Reason: "Changed differently in astra and by the program"},
+		{StreamID: "2", Name: "Stream 2 renamed", Reason: "Removed in astra but changed by the program"},
+		{StreamID: "4", Name: "Stream 4", Reason: "Changed in astra but removed by the program"},
+	}
+	assert.Exactly(t, expectedConflicts, conflicts, "should return conflicting streams")
+
+	assert.Exactly(t, currentOriginal, current, "should not modify the source")
+	assert.Exactly(t, changedOriginal, changed, "should not modify the source")
+
+	assert.Contains(t, log, `Skipping stream changed concurrently in astra: ID "1", name "Stream 1", fields "input", `+
+		`reason "Changed differently in astra and by the program"`)
+	assert.NotContains(t, log, `Skipping stream changed concurrently in astra: ID "9"`,
+		"should not report streams which are already removed as conflicts")
+
+	// Test nothing changed concurrently
+	out, conflicts = r.RebaseStreams(original, original, changed[:2])
+	assert.Exactly(t, changed[:2], out, "should return changed streams as is")
+	assert.Empty(t, conflicts, "should not return conflicts")
+}
+
+func TestRebaseCategories(t *testing.T) {
+	r := newDefRepo()
+
+	original := []Category{
+		{Name: "Category 1", Groups: []Group{{Name: "Group 1"}, {Name: "Group 2"}}},
+		{Name: "Category 2", Groups: []Group{{Name: "Group 1"}}},
+		{Name: "Category 3", Groups: []Group{{Name: "Group 1"}}},
+		{Name: "Category 4", Groups: []Group{{Name: "Group 1"}}},
+	}
+	current := []Category{
+		{Name: "Category 0 added in astra"},
+		{Name: "Category 1", Groups: []Group{{Name: "Group 1"}, {Name: "Group 2"}, {Name: "Group 3 added in astra"}}},
+		{Name: "Category 3", Groups: []Group{{Name: "Group 1"}, {Name: "Group 2 added in astra"}}},
+		{Name: "Category 4", Groups: []Group{{Name: "Group 1"}}},
+		{Name: "Category 5", Groups: []Group{{Name: "Group 1"}}},
+	}
+	changed := []lo.Entry[int, Category]{
+		{Key: 0, Value: Category{Name: "Category 1", Groups: []Group{
+			{Name: "Group 1"}, {Name: "Group 2", Remove: true}, {Name: "Group 4"},
+		}}},
+		{Key: 1, Value: Category{Name: "Category 2", Groups: []Group{{Name: "Group 1"}, {Name: "Group 2"}}}},
+		{Key: -1, Value: Category{Name: "Category 5", Groups: []Group{{Name: "Group 2"}}}},
+		{Key: -1, Value: Category{Name: "Category 6", Groups: []Group{
+			{Name: "Group 1"}, {Name: "Group 2", Remove: true},
+		}}},
+		{Key: 3, Value: Category{Name: "Category 4", Remove: true}},
+		{Key: 2, Value: Category{Name: "Category 3", Remove: true}},
+	}
+	changedOriginal := copier.TestDeep(t, changed)
+
+	out, conflicts := r.RebaseCategories(original, current, changed)
+
+	expected := []lo.Entry[int, Category]{
+		{Key: 1, Value: Category{Name: "Category 1", Groups: []Group{
+			{Name: "Group 1"}, {Name: "Group 2", Remove: true}, {Name: "Group 3 added in astra"}, {Name: "Group 4"},
+		}}},
+		{Key: 4, Value: Category{Name: "Category 5", Groups: []Group{{Name: "Group 1"}, {Name: "Group 2"}}}},
+		{Key: -1, Value: Category{Name: "Category 6", Groups: []Group{{Name: "Group 1"}}}},
+		{Key: 3, Value: Category{Name: "Category 4", Remove: true}},
+	}
+	assert.Exactly(t, expected, out, "should return categories with indexes and groups rebased on the current ones")
+
+	expectedConflicts := []Conflict{
+		{Name: "Category 2", Reason: "Removed in astra but changed by the program"},
+		{Name: "Category 3", Fields: []string{"groups"}, Reason: "Changed in astra but removed by the program"},
+	}
+	assert.Exactly(t, expectedConflicts, conflicts, "should return conflicting categories")
+	assert.Exactly(t, changedOriginal, changed, "should not modify the source")
+
+	// Test category to remove is already removed
+	out, conflicts = r.RebaseCategories(original, original[:1], changed[4:])
+	assert.Empty(t, out, "should not return categories which are already removed")
+	assert.Empty(t, conflicts, "should not return conflicts")
+}
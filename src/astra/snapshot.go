@@ -0,0 +1,131 @@
+package astra
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"m3u_merge_astra/util/copier"
+
+	"github.com/cockroachdb/errors"
+	"github.com/samber/lo"
+)
+
+// SaveSnapshot writes <astraCfg> to a new timestamped file in <dir> and returns path to that file.
+//
+// If <keep> is greater than 0, only <keep> newest snapshots are kept in <dir>, older ones are removed.
+func (r repo) SaveSnapshot(dir string, keep int, astraCfg Cfg) (string, error) {
+	r.log.InfoFi("Saving snapshot of astra config", "directory", dir)
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", errors.Wrap(err, "Create astra config snapshot directory")
+	}
+
+	path := filepath.Join(dir, "astra_cfg_"+time.Now().Format("2006-01-02_15-04-05.000")+".json")
+	if err := WriteCfgFile(path, astraCfg); err != nil {
+		return "", errors.Wrap(err, "Save astra config snapshot")
+	}
+
+	r.log.InfoFi("Saved snapshot of astra config", "path", path)
+
+	if keep > 0 {
+		r.removeOldSnapshots(dir, keep)
+	}
+
+	return path, nil
+}
+
+// removeOldSnapshots removes all but <keep> newest snapshots in <dir>
+func (r repo) removeOldSnapshots(dir string, keep int) {
+	paths, err := filepath.Glob(filepath.Join(dir, "astra_cfg_*.json"))
+	if err != nil {
+		r.log.Error(errors.Wrap(err, "List astra config snapshots"))
+		return
+	}
+	// Timestamps in file names sort in chronological order
+	sort.Strings(paths)
+	for _, path := range paths[:max(len(paths)-keep, 0)] {
+		r.log.InfoFi("Removing old snapshot of astra config", "path", path)
+		if err := os.Remove(path); err != nil {
+			r.log.Error(errors.Wrap(err, "Remove old astra config snapshot"))
+		}
+	}
+}
+
+// ReadSnapshot returns astra config read from snapshot file at <path>
+func (r repo) ReadSnapshot(path string) (Cfg, error) {
+	r.log.InfoFi("Reading snapshot of astra config", "path", path)
+
+	astraCfg, err := ReadCfgFile(path)
+	return astraCfg, errors.Wrap(err, "Read astra config snapshot")
+}
+
+// RestoreStreams returns deep copy of streams which should be sent to astra to turn <current> streams into <snapshot>
+// streams.
+//
+// Streams which are not in <snapshot> has Remove field set to true.
+func (r repo) RestoreStreams(current, snapshot []Stream) []Stream {
+	r.log.Info("Building list of streams to restore")
+
+	restored := []Stream{}
+	for _, currStream := range current {
+		snapStream, found := lo.Find(snapshot, func(snapStream Stream) bool {
+			return currStream.ID == snapStream.ID
+		})
+		if found {
+			restored = append(restored, snapStream)
+		} else {
+			r.log.InfoFi("Removing stream absent in snapshot", "ID", currStream.ID, "name", currStream.Name)
+			currStream.Remove = true
+			restored = append(restored, currStream)
+		}
+	}
+	for _, snapStream := range snapshot {
+		if !lo.ContainsBy(current, func(currStream Stream) bool { return currStream.ID == snapStream.ID }) {
+			r.log.InfoFi("Recreating stream from snapshot", "ID", snapStream.ID, "name", snapStream.Name)
+			restored = append(restored, snapStream)
+		}
+	}
+
+	return copier.MustDeep(r.ChangedStreams(current, restored))
+}
+
+// RestoreCategories returns deep copy of categories which should be sent to astra to turn <current> categories into
+// <snapshot> categories.
+//
+// Key (index) in output is the same as in ChangedCategories.
+//
+// Categories and groups which are not in <snapshot> has Remove field set to true.
+func (r repo) RestoreCategories(current, snapshot []Category) []lo.Entry[int, Category] {
+	r.log.Info("Building list of categories to restore")
+
+	restored := []Category{}
+	for _, currCat := range current {
+		snapCat, found := lo.Find(snapshot, func(snapCat Category) bool {
+			return currCat.Name == snapCat.Name
+		})
+		if !found {
+			r.log.InfoFi("Removing category absent in snapshot", "name", currCat.Name)
+			currCat.Remove = true
+			restored = append(restored, currCat)
+			continue
+		}
+		snapCat = copier.MustDeep(snapCat)
+		for _, currGroup := range currCat.Groups {
+			if !lo.ContainsBy(snapCat.Groups, func(g Group) bool { return g.Name == currGroup.Name }) {
+				r.log.InfoFi("Removing group absent in snapshot", "category", currCat.Name, "group", currGroup.Name)
+				snapCat.Groups = append(snapCat.Groups, Group{Name: currGroup.Name, Remove: true})
+			}
+		}
+		restored = append(restored, snapCat)
+	}
+	for _, snapCat := range snapshot {
+		if !lo.ContainsBy(current, func(currCat Category) bool { return currCat.Name == snapCat.Name }) {
+			r.log.InfoFi("Recreating category from snapshot", "name", snapCat.Name)
+			restored = append(restored, snapCat)
+		}
+	}
+
+	return copier.MustDeep(r.ChangedCategories(current, restored))
+}
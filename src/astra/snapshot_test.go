@@ -0,0 +1,147 @@
+package astra
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"m3u_merge_astra/util/copier"
+
+	"github.com/samber/lo"
+	"github.com/stretchr/testify/assert"
+	"github.com/zenizh/go-capturer"
+)
+
+func TestSaveReadSnapshot(t *testing.T) {
+	r := newDefRepo()
+
+	dir := filepath.Join(t.TempDir(), "backup")
+	astraCfg := Cfg{
+		Categories: []Category{{Name: "Category 1", Groups: []Group{{Name: "Group 1"}}}},
+		Streams: []Stream{
+			{ID: "0", Name: "Stream 0", Enabled: true, Inputs: []string{"http://a"}, DisabledInputs: []string{"http://b"},
+				Groups: map[string]string{"Category 1": "Group 1"}, Unknown: map[string]any{"key": "val"}},
+			{ID: "1", Name: "Stream 1"},
+		},
+	}
+	astraCfgOriginal := copier.TestDeep(t, astraCfg)
+
+	path, err := r.SaveSnapshot(dir, 0, astraCfg)
+	assert.NoError(t, err, "should not return error")
+	assert.Exactly(t, astraCfgOriginal, astraCfg, "should not modify the source config")
+	assert.FileExists(t, path, "should create snapshot file")
+	assert.Exactly(t, dir, filepath.Dir(path), "should create snapshot file in this directory")
+	assert.Regexp(t, `^astra_cfg_\d{4}-\d{2}-\d{2}_\d{2}-\d{2}-\d{2}\.\d{3}\.json$`, filepath.Base(path))
+
+	actual, err := r.ReadSnapshot(path)
+	assert.NoError(t, err, "should not return error")
+	assert.Exactly(t, astraCfg, actual, "should read the same config including unknown fields")
+
+	// Test reading damaged snapshot
+	err = os.WriteFile(path, []byte("{"), 0644)
+	assert.NoError(t, err, "should write damaged snapshot")
+	_, err = r.ReadSnapshot(path)
+	assert.Error(t, err, "should return error")
+
+	// Test reading missing snapshot
+	_, err = r.ReadSnapshot(filepath.Join(dir, "missing.json"))
+	assert.Error(t, err, "should return error")
+}
+
+func TestSaveSnapshotKeep(t *testing.T) {
+	r := newDefRepo()
+
+	dir := t.TempDir()
+	old := []string{"astra_cfg_2024-01-01_00-00-00.000.json", "astra_cfg_2024-01-02_00-00-00.000.json",
+		"astra_cfg_2024-01-03_00-00-00.000.json", "other.json"}
+	for _, name := range old {
+		err := os.WriteFile(filepath.Join(dir, name), []byte("{}"), 0644)
+		assert.NoError(t, err, "should write old snapshot")
+	}
+
+	path, err := r.SaveSnapshot(dir, 2, Cfg{})
+	assert.NoError(t, err, "should not return error")
+
+	matches, _ := filepath.Glob(filepath.Join(dir, "*.json"))
+	expected := []string{filepath.Join(dir, "astra_cfg_2024-01-03_00-00-00.000.json"), path,
+		filepath.Join(dir, "other.json")}
+	assert.ElementsMatch(t, expected, matches, "should keep only newest snapshots and unrelated files")
+}
+
+func TestRestoreStreams(t *testing.T) {
+	r := newDefRepo()
+
+	current := []Stream{
+		{ID: "0", Name: "Stream 0", Inputs: []string{"http://a"}},
+		{ID: "1", Name: "Stream 1 changed", Inputs: []string{}},
+		{ID: "3", Name: "Stream 3", Inputs: []string{"http://d"}},
+	}
+	currentOriginal := copier.TestDeep(t, current)
+	snapshot := []Stream{
+		{ID: "0", Name: "Stream 0", Inputs: []string{"http://a"}},
+		{ID: "1", Name: "Stream 1", Inputs: []string{"http://b"}, Unknown: map[string]any{"key": "val"}},
+		{ID: "2", Name: "Stream 2", Inputs: []string{"http://c"}},
+	}
+	snapshotOriginal := copier.TestDeep(t, snapshot)
+
+	actual := r.RestoreStreams(current, snapshot)
+
+	assert.Exactly(t, currentOriginal, current, "should not modify the source streams")
+	assert.Exactly(t, snapshotOriginal, snapshot, "should not modify the source streams")
+
+	expected := []Stream{
+		{ID: "1", Name: "Stream 1", Inputs: []string{"http://b"}, Unknown: map[string]any{"key": "val"}},
+		{ID: "3", Name: "Stream 3", Inputs: []string{"http://d"}, Remove: true},
+		{ID: "2", Name: "Stream 2", Inputs: []string{"http://c"}},
+	}
+	assert.Exactly(t, expected, actual, "should revert changed, remove new and recreate removed streams")
+
+	// Test log output
+	out := capturer.CaptureStderr(func() {
+		r := newDefRepo()
+		_ = r.RestoreStreams([]Stream{{ID: "0", Name: "A"}}, []Stream{{ID: "1", Name: "B"}})
+	})
+	assert.Contains(t, out, `Removing stream absent in snapshot: ID "0", name "A"`)
+	assert.Contains(t, out, `Recreating stream from snapshot: ID "1", name "B"`)
+}
+
+func TestRestoreCategories(t *testing.T) {
+	r := newDefRepo()
+
+	current := []Category{
+		{Name: "Category 0", Groups: []Group{{Name: "A"}}},
+		{Name: "Category 1", Groups: []Group{{Name: "B"}, {Name: "C"}}},
+		{Name: "Category 2", Groups: []Group{{Name: "D"}}},
+		{Name: "Category 3", Groups: []Group{{Name: "E"}}},
+	}
+	currentOriginal := copier.TestDeep(t, current)
+	snapshot := []Category{
+		{Name: "Category 0", Groups: []Group{{Name: "A"}}},
+		{Name: "Category 1", Groups: []Group{{Name: "B"}}},
+		{Name: "Category 4", Groups: []Group{{Name: "F"}}},
+	}
+	snapshotOriginal := copier.TestDeep(t, snapshot)
+
+	actual := r.RestoreCategories(current, snapshot)
+
+	assert.Exactly(t, currentOriginal, current, "should not modify the source categories")
+	assert.Exactly(t, snapshotOriginal, snapshot, "should not modify the source categories")
+
+	expected := []lo.Entry[int, Category]{
+		{Key: 1, Value: Category{Name: "Category 1", Groups: []Group{{Name: "B"}, {Name: "C", Remove: true}}}},
+		{Key: -1, Value: Category{Name: "Category 4", Groups: []Group{{Name: "F"}}}},
+		{Key: 3, Value: Category{Name: "Category 3", Groups: []Group{{Name: "E"}}, Remove: true}},
+		{Key: 2, Value: Category{Name: "Category 2", Groups: []Group{{Name: "D"}}, Remove: true}},
+	}
+	assert.Exactly(t, expected, actual, "should revert changed, remove new and recreate removed categories")
+
+	// Test log output
+	out := capturer.CaptureStderr(func() {
+		r := newDefRepo()
+		_ = r.RestoreCategories([]Category{{Name: "A", Groups: []Group{{Name: "G"}}}, {Name: "B"}},
+			[]Category{{Name: "A"}, {Name: "C"}})
+	})
+	assert.Contains(t, out, `Removing group absent in snapshot: category "A", group "G"`)
+	assert.Contains(t, out, `Removing category absent in snapshot: name "B"`)
+	assert.Contains(t, out, `Recreating category from snapshot: name "C"`)
+}
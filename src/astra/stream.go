@@ -2,7 +2,10 @@ package astra
 
 import (
 	"fmt"
+	"math"
 	"net/http"
+	"regexp"
+	"slices"
 	"sort"
 	"strconv"
 	"strings"
@@ -10,6 +13,7 @@ import (
 	"time"
 
 	"m3u_merge_astra/astra/analyzer"
+	"m3u_merge_astra/astra/hls"
 	"m3u_merge_astra/cfg"
 	"m3u_merge_astra/deps"
 	"m3u_merge_astra/util/copier"
@@ -20,36 +24,105 @@ import (
 	"m3u_merge_astra/util/slice/find"
 	urlUtil "m3u_merge_astra/util/url"
 
+	json "github.com/SCP002/jsonexraw"
 	"github.com/alitto/pond"
+	"github.com/cockroachdb/errors"
 	"github.com/go-co-op/gocron"
 	"github.com/google/go-cmp/cmp"
 	"github.com/samber/lo"
 )
 
-// Stream represents astra stream object
+// Stream represents astra stream object.
+//
+// Stream options (see optionKeys) are omitted from JSON if they are not set, so astra uses it's defaults for them.
+// Numeric and boolean options are pointers to keep explicit zero values read from astra.
 type Stream struct {
-	DisabledInputs []string          `json:"_input,omitempty"`
-	Enabled        bool              `json:"enable"`
-	Groups         map[string]string `json:"groups,omitempty"`
-	HTTPKeepActive string            `json:"http_keep_active,omitempty"`
-	ID             string            `json:"id,omitempty"`
-	Inputs         []string          `json:"input,omitempty"`
-	Name           string            `json:"name,omitempty"`
-	Remove         bool              `json:"remove,omitempty"` // Used by API to remove stream.
-	Type           string            `json:"type,omitempty"`
-	Unknown        map[string]any    `json:"-" jsonex:"true"` // All unknown fields go here.
-	MarkAdded      bool              `json:"-"`               // Set added name prefix after processing?
-	MarkDisabled   bool              `json:"-"`               // Set disabled name prefix after processing?
-}
-
-// NewStream returns new stream with default config
-func NewStream(cfg cfg.Streams, id, name, group string, inputs []string) Stream {
+	DisabledInputs    []string          `json:"_input,omitempty"`
+	BackupReturnDelay *Number           `json:"backup_return_delay,omitempty"` // Seconds
+	BackupStartDelay  *Number           `json:"backup_start_delay,omitempty"`  // Seconds
+	BackupType        string            `json:"backup_type,omitempty"`         // One of backupTypes
+	Enabled           bool              `json:"enable"`
+	Filter            string            `json:"filter,omitempty"` // Comma separated PIDs to drop
+	Groups            map[string]string `json:"groups,omitempty"`
+	HTTPKeepActive    string            `json:"http_keep_active,omitempty"`
+	ID                string            `json:"id,omitempty"`
+	Inputs            []string          `json:"input,omitempty"`
+	Map               string            `json:"map,omitempty"` // Comma separated PID remapping, e.g. "video=101"
+	Name              string            `json:"name,omitempty"`
+	Outputs           []string          `json:"output,omitempty"`
+	PassEIT           *bool             `json:"pass_eit,omitempty"`
+	PassSDT           *bool             `json:"pass_sdt,omitempty"`
+	Remove            bool              `json:"remove,omitempty"` // Used by API to remove stream.
+	ServiceName       string            `json:"service_name,omitempty"`
+	ServiceProvider   string            `json:"service_provider,omitempty"`
+	SetPNR            *Number           `json:"set_pnr,omitempty"`  // Program number
+	SetTSID           *Number           `json:"set_tsid,omitempty"` // Transport stream ID
+	Timeout           *Number           `json:"timeout,omitempty"`  // Seconds
+	Type              string            `json:"type,omitempty"`
+	Unknown           map[string]any    `json:"-" jsonex:"true"` // All unknown fields go here.
+	MarkAdded         bool              `json:"-"`               // Set added name prefix after processing?
+	MarkDisabled      bool              `json:"-"`               // Set disabled name prefix after processing?
+}
+
+// strictStream represents Stream decoded without falling back to unknown fields on unexpected value types
+type strictStream Stream
+
+// UnmarshalJSON sets <s> from astra stream config in <data>.
+//
+// Fields with values of unexpected type (e.g. bool as string or non-integer number) are kept as is in unknown fields
+// instead of failing to decode the whole stream.
+func (s *Stream) UnmarshalJSON(data []byte) error {
+	out := strictStream(*s)
+	if err := json.Unmarshal(data, &out); err == nil {
+		*s = Stream(out)
+		return nil
+	}
+
+	var fields map[string]any
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return errors.Wrap(err, "Decode stream")
+	}
+	out = strictStream(*s)
+	raw := map[string]any{}
+	for key, val := range fields {
+		delete(out.Unknown, key)
+		fieldBytes, _ := json.Marshal(map[string]any{key: val})
+		// Decode into a copy so pointer fields allocated before failure stay unset
+		next := out
+		if err := json.Unmarshal(fieldBytes, &next); err != nil {
+			raw[key] = val
+			continue
+		}
+		out = next
+	}
+	if len(raw) > 0 {
+		out.Unknown = lo.Assign(out.Unknown, raw)
+	}
+	*s = Stream(out)
+	return nil
+}
+
+// optionKeys represents JSON keys of stream options: fields of Stream which are not managed by merge itself
+var optionKeys = []string{"backup_return_delay", "backup_start_delay", "backup_type", "filter", "map", "output",
+	"pass_eit", "pass_sdt", "service_name", "service_provider", "set_pnr", "set_tsid", "timeout"}
+
+// backupTypes represents valid values of Stream.BackupType
+var backupTypes = []string{"active", "active_stop", "passive", "disable"}
+
+// NewStream returns new stream with default config.
+//
+// Fields of the first template from cfg.NameToNewStreamTemplateMap matching <name> or, if not found, from
+// cfg.GroupToNewStreamTemplateMap matching <group> are set on the stream, except ID, name and inputs. Groups of the
+// template are added to the groups of the stream.
+//
+// Returns stream with default config and error if template can not be applied.
+func NewStream(cfg cfg.Streams, id, name, group string, inputs []string) (Stream, error) {
 	var groups map[string]string = nil
 	if cfg.AddGroupsToNew {
 		groups = map[string]string{cfg.GroupsCategoryForNew: group}
 	}
 
-	return Stream{
+	s := Stream{
 		DisabledInputs: []string{},
 		Enabled:        cfg.MakeNewEnabled,
 		Groups:         groups,
@@ -60,6 +133,100 @@ func NewStream(cfg cfg.Streams, id, name, group string, inputs []string) Stream
 		Type:           string(cfg.NewType),
 		MarkAdded:      true,
 	}
+
+	// applyTemplate returns stream with fields of template <tmpl> matched by <by> set
+	applyTemplate := func(by regexp.Regexp, tmpl map[string]any) (Stream, error) {
+		out, err := s.SetFields(tmpl)
+		if err != nil {
+			return s, errors.Wrapf(err, "Apply template %q to new stream", by.String())
+		}
+		return out, nil
+	}
+	// By name
+	for _, tmpl := range cfg.NameToNewStreamTemplateMap {
+		if tmpl.By.MatchString(name) {
+			return applyTemplate(tmpl.By, tmpl.Stream)
+		}
+	}
+	// By group
+	for _, tmpl := range cfg.GroupToNewStreamTemplateMap {
+		if tmpl.By.MatchString(group) {
+			return applyTemplate(tmpl.By, tmpl.Stream)
+		}
+	}
+
+	return s, nil
+}
+
+// ValidateTemplates returns error if any template of cfg.NameToNewStreamTemplateMap or
+// cfg.GroupToNewStreamTemplateMap can not be applied to a new stream or results in invalid stream
+func ValidateTemplates(cfg cfg.Streams) error {
+	for _, tmpl := range append(slices.Clone(cfg.NameToNewStreamTemplateMap), cfg.GroupToNewStreamTemplateMap...) {
+		s, err := Stream{}.SetFields(tmpl.Stream)
+		if err == nil {
+			err = s.Validate()
+		}
+		if err != nil {
+			return errors.Wrapf(err, "New stream template %q", tmpl.By.String())
+		}
+	}
+	return nil
+}
+
+// SetFields returns deep copy of stream with <fields> set, where keys are JSON keys of astra stream config, except ID,
+// name, inputs and remove flag.
+//
+// Returns stream as is and error if fields can not be set.
+func (s Stream) SetFields(fields map[string]any) (Stream, error) {
+	fields = lo.OmitByKeys(fields, []string{"id", "name", "input", "remove"})
+	data, err := json.Marshal(fields)
+	if err != nil {
+		return s, errors.Wrap(err, "Encode fields")
+	}
+	out := strictStream(copier.MustDeep(s))
+	// Drop values of unexpected type kept by UnmarshalJSON so they do not override fields being set
+	if out.Unknown != nil {
+		out.Unknown = lo.OmitByKeys(out.Unknown, lo.Keys(fields))
+	}
+	if err := json.Unmarshal(data, &out); err != nil {
+		return s, errors.Wrap(err, "Decode fields")
+	}
+	return Stream(out), nil
+}
+
+// Validate returns error describing every invalid field of the stream or nil if all fields are valid
+func (s Stream) Validate() error {
+	var errs []error
+	if !lo.Contains([]string{"", string(cfg.SPTS), string(cfg.MPTS)}, s.Type) {
+		errs = append(errs, errors.Newf("type %q is not one of %v, %v", s.Type, cfg.SPTS, cfg.MPTS))
+	}
+	if s.BackupType != "" && !lo.Contains(backupTypes, s.BackupType) {
+		errs = append(errs, errors.Newf("backup_type %q is not one of %v", s.BackupType,
+			strings.Join(backupTypes, ", ")))
+	}
+	for key, val := range map[string]*Number{"timeout": s.Timeout, "backup_start_delay": s.BackupStartDelay,
+		"backup_return_delay": s.BackupReturnDelay} {
+		if val != nil && *val < 0 {
+			errs = append(errs, errors.Newf("%v %v is negative", key, *val))
+		}
+	}
+	for key, val := range map[string]*Number{"set_pnr": s.SetPNR, "set_tsid": s.SetTSID} {
+		if val != nil && (*val < 0 || *val > math.MaxUint16) {
+			errs = append(errs, errors.Newf("%v %v is out of range 0-%v", key, *val, math.MaxUint16))
+		}
+	}
+	for _, output := range s.Outputs {
+		if !strings.Contains(output, "://") {
+			errs = append(errs, errors.Newf("output %q has no scheme", output))
+		}
+	}
+	sort.Slice(errs, func(i, j int) bool { return errs[i].Error() < errs[j].Error() })
+	return errors.Wrapf(errors.Join(errs...), "Validate stream %v (%v)", s.ID, s.Name)
+}
+
+// options returns stream options (see optionKeys) which are set by JSON keys
+func (s Stream) options() map[string]any {
+	return lo.PickByKeys(streamToMap(s), optionKeys)
 }
 
 // GetName used to satisfy util/slice.Named interface
@@ -67,6 +234,25 @@ func (s Stream) GetName() string {
 	return s.Name
 }
 
+// GetTVGID returns tvg-id of the stream taken from cfg.StreamTVGIDMap or group of cfg.TVGIDCategory.
+//
+// Used to satisfy util/slice/find.Identified interface.
+func (s Stream) GetTVGID(cfg cfg.General) string {
+	if tvgID := cfg.StreamTVGIDMap[s.ID]; tvgID != "" {
+		return tvgID
+	}
+	if cfg.TVGIDCategory == "" {
+		return ""
+	}
+	return s.Groups[cfg.TVGIDCategory]
+}
+
+// SetTVGID returns shallow copy of stream with group of cfg.TVGIDCategory set to <tvgID>
+func (s Stream) SetTVGID(cfg cfg.General, tvgID string) Stream {
+	s.Groups = lo.Assign(s.Groups, map[string]string{cfg.TVGIDCategory: tvgID})
+	return s
+}
+
 // FirstGroup returns alphabetically first "category: group" pair or empty string if groups are empty
 func (s Stream) FirstGroup() string {
 	if len(s.Groups) == 0 {
@@ -178,7 +364,7 @@ func (s Stream) removeInputs(tInp string) Stream {
 }
 
 // Disable disables stream and sets MarkDisabled field to true
-func (s Stream) disable() Stream {
+func (s Stream) Disable() Stream {
 	s.Enabled = false
 	s.MarkDisabled = true
 	return s
@@ -382,13 +568,16 @@ func (r repo) RemoveDuplicatedInputsByRx(streams []Stream) (out []Stream) {
 
 // UniteInputs returns deep copy of <streams> with inputs of every equally named stream moved to the first stream found.
 //
+// Streams with different tvg-id are not considered equal (see util/slice/find.EveryMatching).
+//
 // If cfg.Streams.EnableOnInputUpdate is enabled in config, it also enables every stream with new inputs.
 func (r repo) UniteInputs(streams []Stream) (out []Stream) {
 	r.log.Info("Uniting inputs of streams")
 
 	out = copier.MustDeep(streams)
 	for currIdx, currStream := range out {
-		find.EverySimilar(r.cfg.General, out, currStream.Name, currIdx+1, func(nextStream Stream, nextIdx int) {
+		tvgID := currStream.GetTVGID(r.cfg.General)
+		find.EveryMatching(r.cfg.General, out, tvgID, currStream.Name, currIdx+1, func(nextStream Stream, nextIdx int) {
 			for _, nextInput := range nextStream.Inputs {
 				r.log.InfoFi("Uniting inputs of streams", "from ID", nextStream.ID, "from name", nextStream.Name,
 					"input", nextInput, "to ID", currStream.ID, "to name", currStream.Name,
@@ -418,23 +607,24 @@ func (r repo) SortInputs(streams []Stream) (out []Stream) {
 	out = copier.MustDeep(streams)
 	for _, s := range out {
 		sort.SliceStable(s.Inputs, func(i, j int) bool {
-			// Set default weight
-			leftInpWeight := r.cfg.Streams.UnknownInputWeight
-			rightInpWeight := r.cfg.Streams.UnknownInputWeight
-
-			for weight, rx := range r.cfg.Streams.InputWeightToTypeMap {
-				// Assign weight from map if match found
-				leftInpWeight = lo.Ternary(rx.MatchString(s.Inputs[i]), weight, leftInpWeight)
-				rightInpWeight = lo.Ternary(rx.MatchString(s.Inputs[j]), weight, rightInpWeight)
-			}
-
-			return leftInpWeight < rightInpWeight
+			return r.inputWeight(s.Inputs[i]) < r.inputWeight(s.Inputs[j])
 		})
 	}
 
 	return
 }
 
+// inputWeight returns weight of input <inp> defined by InputWeightToTypeMap in config or UnknownInputWeight if
+// input does not match any type
+func (r repo) inputWeight(inp string) int {
+	inpWeight := r.cfg.Streams.UnknownInputWeight
+	for weight, rx := range r.cfg.Streams.InputWeightToTypeMap {
+		// Assign weight from map if match found
+		inpWeight = lo.Ternary(rx.MatchString(inp), weight, inpWeight)
+	}
+	return inpWeight
+}
+
 // RemoveDeadInputs returns deep copy of <streams> without dead inputs.
 //
 // For detailed description, see removeDeadInputs method.
@@ -451,6 +641,96 @@ func (r repo) DisableDeadInputs(httpClient *http.Client, analyzer analyzer.Analy
 	return r.removeDeadInputs(httpClient, analyzer, streams, true)
 }
 
+// ReviveDisabledInputs returns deep copy of <streams> with disabled inputs which are alive again moved back to
+// enabled inputs.
+//
+// Only inputs disabled by DisableDeadInputs are checked and revived only in streams they were disabled in by it, which
+// are known from the history of checks in cfg.Streams.InputHealthFile. Inputs disabled by other means, including the
+// same input disabled by hand in other streams, are left as is.
+//
+// Input is considered alive after cfg.Streams.AliveInputSuccesses consecutive successful checks (see
+// HealthStore.Record). Revived input is placed before the first enabled input with higher weight defined by
+// InputWeightToTypeMap in config.
+//
+// Inputs are checked using <httpClient> or <analyzer> the same way as in removeDeadInputs method: every distinct input
+// is checked once and checks which could not be run are not recorded.
+func (r repo) ReviveDisabledInputs(httpClient *http.Client, analyzer analyzer.Analyzer,
+	streams []Stream) (out []Stream) {
+	r.log.Info("Reviving disabled inputs of streams")
+
+	out = copier.MustDeep(streams)
+	if r.cfg.Streams.InputHealthFile == "" {
+		r.log.Warn("Input health file is not set, can not tell which inputs are disabled by this program, skipping")
+		return
+	}
+	health, err := r.ReadHealth(r.cfg.Streams.InputHealthFile)
+	if err != nil {
+		r.log.Error(err)
+		return
+	}
+
+	disabledInputs := lo.Uniq(lo.FlatMap(out, func(s Stream, _ int) []string { return s.DisabledInputs }))
+	pool := pond.New(r.cfg.Streams.InputMaxConns, 0, pond.MinWorkers(0))
+	var mut sync.Mutex
+	for _, inp := range disabledInputs {
+		inpHealth, found := health.Get(inp)
+		if !found || !inpHealth.Dead || !r.canCheckInput(inp) {
+			continue
+		}
+		// Only streams where input is still disabled and it was disabled by this program
+		disabledBy := func(s Stream) bool {
+			return lo.Contains(s.DisabledInputs, inp) && lo.Contains(inpHealth.DisabledIn, s.ID)
+		}
+		if !lo.ContainsBy(out, disabledBy) {
+			continue
+		}
+		pool.Submit(func() {
+			removalReason, checked := r.checkInput(httpClient, analyzer, inp)
+			if !checked {
+				return
+			}
+			inpHealth := health.Record(r.cfg.Streams, inp, removalReason, time.Now())
+			if inpHealth.Dead {
+				r.log.DebugFi("Disabled input is still dead", "input", inp, "reason", removalReason, "successes",
+					fmt.Sprintf("%v / %v", inpHealth.ConsecutiveSuccesses, r.cfg.Streams.AliveInputSuccesses))
+				return
+			}
+			mut.Lock()
+			for sIdx, s := range out {
+				if !disabledBy(s) {
+					continue
+				}
+				r.log.InfoFi("Enabling alive input of stream", "ID", s.ID, "name", s.Name, "group", s.FirstGroup(),
+					"input", inp)
+				out[sIdx] = s.reviveInput(inp, r.inputWeight)
+			}
+			mut.Unlock()
+			health.UnmarkDisabled(inp, inpHealth.DisabledIn...)
+		})
+	}
+	pool.StopAndWait()
+
+	if err := r.SaveHealth(r.cfg.Streams.InputHealthFile, health); err != nil {
+		r.log.Error(err)
+	}
+
+	return
+}
+
+// reviveInput returns shallow copy of stream with input <inp> moved from disabled inputs to enabled ones and placed
+// before the first enabled input with higher weight returned by <weight>
+func (s Stream) reviveInput(inp string, weight func(string) int) Stream {
+	s.DisabledInputs = lo.Without(s.DisabledInputs, inp)
+	if lo.Contains(s.Inputs, inp) {
+		return s
+	}
+	idx := slices.IndexFunc(s.Inputs, func(enabled string) bool {
+		return weight(enabled) > weight(inp)
+	})
+	s.Inputs = slices.Insert(slices.Clone(s.Inputs), lo.Ternary(idx == -1, len(s.Inputs), idx), inp)
+	return s
+}
+
 // AddHashes returns deep copy of <streams> with hashes added to every input as defined in config with *ToInputHashMap
 func (r repo) AddHashes(streams []Stream) (out []Stream) {
 	r.log.Info("Adding hashes to inputs of streams")
@@ -608,7 +888,7 @@ func (r repo) DisableWithoutInputs(streams []Stream) (out []Stream) {
 	for _, s := range streams {
 		if s.Enabled && s.hasNoInputs() {
 			r.log.InfoFi("Disabling stream without inputs", "ID", s.ID, "name", s.Name, "group", s.FirstGroup())
-			s = s.disable()
+			s = s.Disable()
 		}
 		out = append(out, s)
 	}
@@ -639,6 +919,12 @@ func (r repo) AddNamePrefixes(streams []Stream) (out []Stream) {
 	return
 }
 
+// ignoreMarks represents cmp option to ignore fields of Stream which are not sent to astra
+var ignoreMarks = cmp.FilterPath(func(p cmp.Path) bool {
+	lastPathItem := p.Last().String()
+	return lastPathItem == ".MarkAdded" || lastPathItem == ".MarkDisabled"
+}, cmp.Ignore())
+
 // ChangedStreams returns new and changed streams from <newStreams>, which are not in <oldStreams>
 func (r repo) ChangedStreams(oldStreams, newStreams []Stream) (out []Stream) {
 	r.log.Info("Building changed streams list")
@@ -648,11 +934,7 @@ func (r repo) ChangedStreams(oldStreams, newStreams []Stream) (out []Stream) {
 			return newStream.ID == oldStream.ID
 		})
 		if found {
-			cmpOption := cmp.FilterPath(func(p cmp.Path) bool {
-				lastPathItem := p.Last().String()
-				return lastPathItem == ".MarkAdded" || lastPathItem == ".MarkDisabled"
-			}, cmp.Ignore())
-			if !cmp.Equal(oldStream, newStream, cmpOption) {
+			if !cmp.Equal(oldStream, newStream, ignoreMarks) {
 				out = append(out, newStream)
 			}
 		} else {
@@ -671,6 +953,9 @@ func (r repo) ChangedStreams(oldStreams, newStreams []Stream) (out []Stream) {
 //
 // It removes inputs which do not respond in time or respond with status code >= 400 using <httpClient>.
 //
+// If cfg.Streams.CheckHLS is true, it also removes HLS inputs with media playlist which is not updated or with invalid
+// segments (see hls.Checker.Check).
+//
 // Supports HTTP(S).
 //
 // If cfg.Streams.UseAnalyzer is true:
@@ -678,79 +963,47 @@ func (r repo) ChangedStreams(oldStreams, newStreams []Stream) (out []Stream) {
 // It removes inputs with bitrate lower than specified in config or with amount of errors higher than specified in
 // config using <analyzer>.
 //
-// Supports HTTP(S), UDP, RTP, RTSP.
+// Supports HTTP(S), UDP, RTP, RTSP (RTSP is not supported if cfg.Streams.UseNativeAnalyzer is true).
+//
+// If cfg.Streams.InputHealthFile is set, results of the checks are recorded to the file and input is removed only
+// after it is considered dead by the history of the checks (see HealthStore.Record). Dead input which passed the
+// check but is not considered alive again is removed as well.
+//
+// Every distinct input is checked once, even if it is used by many streams, and the result applies to all of them.
 func (r repo) removeDeadInputs(httpClient *http.Client, analyzer analyzer.Analyzer, streams []Stream,
 	disable bool) (out []Stream) {
-	// canCheck returns true if <inp> can be checked
-	canCheck := func(inp string) bool {
-		if slice.AnyRxMatch(r.cfg.Streams.DeadInputsCheckBlacklist, inp) {
-			return false
-		}
-		if slice.HasAnyPrefix(inp, "http://", "https://") {
-			return true
-		}
-		if r.cfg.Streams.UseAnalyzer && slice.HasAnyPrefix(inp, "udp://", "rtp://", "rtsp://") {
-			return true
+	health := NewHealthStore()
+	if r.cfg.Streams.InputHealthFile != "" {
+		var err error
+		if health, err = r.ReadHealth(r.cfg.Streams.InputHealthFile); err != nil {
+			r.log.Error(err)
 		}
-		return false
 	}
 
-	// getRemovalReason returns reason why <inp> should be removed
-	getRemovalReason := func(inp string) string {
-		if r.cfg.Streams.UseAnalyzer {
-			result, err := analyzer.Check(r.cfg.Streams.AnalyzerWatchTime, r.cfg.Streams.AnalyzerMaxAttempts, inp)
-			if err != nil {
-				r.log.Errorf("Failed to run analyzer: %v. Ignoring input %v", err, inp)
-				return ""
-			}
-			// Check bitrate
-			hasVideoOnly := result.HasVideo && !result.HasAudio
-			hasAudioOnly := !result.HasVideo && result.HasAudio
-			bitrate := result.Bitrate
-			if hasVideoOnly {
-				if bitrate < r.cfg.Streams.AnalyzerVideoOnlyBitrateThreshold {
-					return fmt.Sprintf("Bitrate %v < %v", bitrate, r.cfg.Streams.AnalyzerVideoOnlyBitrateThreshold)
-				}
-			} else if hasAudioOnly {
-				if bitrate < r.cfg.Streams.AnalyzerAudioOnlyBitrateThreshold {
-					return fmt.Sprintf("Bitrate %v < %v", bitrate, r.cfg.Streams.AnalyzerAudioOnlyBitrateThreshold)
-				}
-			} else if bitrate < r.cfg.Streams.AnalyzerBitrateThreshold {
-				return fmt.Sprintf("Bitrate %v < %v", bitrate, r.cfg.Streams.AnalyzerBitrateThreshold)
-			}
-			// Check errors
-			ccErrorsThreshold := r.cfg.Streams.AnalyzerCCErrorsThreshold
-			pcrErrorsThreshold := r.cfg.Streams.AnalyzerPCRErrorsThreshold
-			pesErrorsThreshold := r.cfg.Streams.AnalyzerPESErrorsThreshold
-			if ccErrorsThreshold >= 0 && result.CCErrors > ccErrorsThreshold {
-				return fmt.Sprintf("CC errors %v > %v", result.CCErrors, ccErrorsThreshold)
-			}
-			if pcrErrorsThreshold >= 0 && result.PCRErrors > pcrErrorsThreshold {
-				return fmt.Sprintf("PCR errors %v > %v", result.PCRErrors, pcrErrorsThreshold)
-			}
-			if pesErrorsThreshold >= 0 && result.PESErrors > pesErrorsThreshold {
-				return fmt.Sprintf("PES errors %v > %v", result.PESErrors, pesErrorsThreshold)
-			}
-		} else {
-			resp, err := httpClient.Get(inp)
-			if err == nil {
-				defer resp.Body.Close()
-			}
-			// Not checking Content-Type header as server can return text/html but stream still will be playable
-			// Not checking response body as some streams can periodically respond with no content but still be playable
-			if err != nil {
-				errType := network.GetErrType(err)
-				return lo.Ternary(errType == network.Unknown, err.Error(), string(errType))
-			} else if resp.StatusCode >= 400 {
-				return fmt.Sprintf("Responded with: %v", resp.Status)
-			}
+	// getDeadReason records result of <inp> check to the health store and returns reason why <inp> is considered
+	// dead or empty string if it is considered alive. <removalReason> is the result of the check.
+	getDeadReason := func(inp string, removalReason string) string {
+		if r.cfg.Streams.InputHealthFile == "" {
+			return removalReason
 		}
-		return ""
+		inpHealth := health.Record(r.cfg.Streams, inp, removalReason, time.Now())
+		if removalReason != "" && !inpHealth.Dead {
+			r.log.InfoFi("Input failed the check but is not considered dead yet", "input", inp, "reason",
+				removalReason, "failures", fmt.Sprintf("%v / %v", inpHealth.ConsecutiveFailures,
+					r.cfg.Streams.DeadInputFailures))
+			return ""
+		}
+		if removalReason == "" && inpHealth.Dead {
+			return fmt.Sprintf("Not recovered yet, successes %v / %v", inpHealth.ConsecutiveSuccesses,
+				r.cfg.Streams.AliveInputSuccesses)
+		}
+		return removalReason
 	}
 
 	pool := pond.New(r.cfg.Streams.InputMaxConns, 0, pond.MinWorkers(0))
 	var mut sync.Mutex
-	inputsAmount := getInputsAmount(streams)
+	inputs := getDistinctInputs(streams)
+	inputsAmount := len(inputs)
 	inputsDone := 0
 
 	// getProgress returns formatted progress of inputs processed
@@ -773,44 +1026,172 @@ func (r repo) removeDeadInputs(httpClient *http.Client, analyzer analyzer.Analyz
 	progressScheduler.StartAsync()
 
 	out = copier.MustDeep(streams)
-	for sIdx, s := range out {
-		for _, inp := range s.Inputs {
-			pool.Submit(func() {
-				r.log.DebugFi("Start checking input", "stream ID", s.ID, "stream name", s.Name, "stream index", sIdx,
-					"input", inp)
-				if canCheck(inp) {
-					removalReason := getRemovalReason(inp)
-					if removalReason != "" {
-						msg := lo.Ternary(disable, "Disabling dead input of stream", "Removing dead input from stream")
-						r.log.WarnFi(msg, "ID", s.ID, "name", s.Name, "group", s.FirstGroup(), "input", inp,
-							"reason", removalReason)
-						mut.Lock()
-						out[sIdx].Inputs = slice.RemoveLast(out[sIdx].Inputs, inp)
-						mut.Unlock()
-						if disable {
-							out[sIdx].DisabledInputs = append(out[sIdx].DisabledInputs, inp)
-						}
+	for _, inp := range inputs {
+		pool.Submit(func() {
+			r.log.DebugFi("Start checking input", "input", inp)
+			removalReason, checked := "", false
+			if r.canCheckInput(inp) {
+				removalReason, checked = r.checkInput(httpClient, analyzer, inp)
+			}
+			// Not recording checks which could not be run as they tell nothing about the input
+			if checked {
+				removalReason = getDeadReason(inp, removalReason)
+			}
+			if removalReason != "" {
+				msg := lo.Ternary(disable, "Disabling dead input of stream", "Removing dead input from stream")
+				mut.Lock()
+				for sIdx, s := range out {
+					if !lo.Contains(s.Inputs, inp) {
+						continue
+					}
+					r.log.WarnFi(msg, "ID", s.ID, "name", s.Name, "group", s.FirstGroup(), "input", inp,
+						"reason", removalReason)
+					out[sIdx].Inputs = lo.Without(s.Inputs, inp)
+					if disable {
+						out[sIdx].DisabledInputs = append(out[sIdx].DisabledInputs, inp)
+						health.MarkDisabled(inp, s.ID)
 					}
 				}
-
-				mut.Lock()
-				inputsDone++
 				mut.Unlock()
-				r.log.DebugFi("End checking input", "stream ID", s.ID, "stream name", s.Name, "stream index", sIdx,
-					"input", inp)
-			})
-		}
+			}
+
+			mut.Lock()
+			inputsDone++
+			mut.Unlock()
+			r.log.DebugFi("End checking input", "input", inp)
+		})
 	}
 
 	pool.StopAndWait()
 	progressScheduler.Stop()
 
+	if r.cfg.Streams.InputHealthFile != "" {
+		if err := r.SaveHealth(r.cfg.Streams.InputHealthFile, health); err != nil {
+			r.log.Error(err)
+		}
+	}
+
 	return
 }
 
-// getInputsAmount returns total amount of inputs in <streams>
-func getInputsAmount(streams []Stream) int {
-	return lo.SumBy(streams, func(s Stream) int {
-		return len(s.Inputs)
+// canCheckInput returns true if input <inp> can be checked for being dead
+func (r repo) canCheckInput(inp string) bool {
+	if slice.AnyRxMatch(r.cfg.Streams.DeadInputsCheckBlacklist, inp) {
+		return false
+	}
+	if slice.HasAnyPrefix(inp, "http://", "https://") {
+		return true
+	}
+	if r.cfg.Streams.UseAnalyzer && slice.HasAnyPrefix(inp, "udp://", "rtp://") {
+		return true
+	}
+	if r.cfg.Streams.UseAnalyzer && !r.cfg.Streams.UseNativeAnalyzer && strings.HasPrefix(inp, "rtsp://") {
+		return true
+	}
+	return false
+}
+
+// checkInput returns reason why input <inp> is considered dead or empty string if it is alive and true, or empty
+// string and false if the check could not be run (e.g. analyzer is not reachable).
+//
+// Uses <analyzer> if cfg.Streams.UseAnalyzer is true or <httpClient> otherwise, following HLS playlists if
+// cfg.Streams.CheckHLS is true.
+func (r repo) checkInput(httpClient *http.Client, analyzer analyzer.Analyzer, inp string) (string, bool) {
+	if r.cfg.Streams.UseAnalyzer {
+		result, err := analyzer.Check(r.cfg.Streams.AnalyzerWatchTime, r.cfg.Streams.AnalyzerMaxAttempts, inp)
+		if err != nil {
+			r.log.Errorf("Failed to run analyzer: %v. Ignoring input %v", err, inp)
+			return "", false
+		}
+		// Check bitrate
+		hasVideoOnly := result.HasVideo && !result.HasAudio
+		hasAudioOnly := !result.HasVideo && result.HasAudio
+		bitrate := result.Bitrate
+		if hasVideoOnly {
+			if bitrate < r.cfg.Streams.AnalyzerVideoOnlyBitrateThreshold {
+				return fmt.Sprintf("Bitrate %v < %v", bitrate, r.cfg.Streams.AnalyzerVideoOnlyBitrateThreshold), true
+			}
+		} else if hasAudioOnly {
+			if bitrate < r.cfg.Streams.AnalyzerAudioOnlyBitrateThreshold {
+				return fmt.Sprintf("Bitrate %v < %v", bitrate, r.cfg.Streams.AnalyzerAudioOnlyBitrateThreshold), true
+			}
+		} else if bitrate < r.cfg.Streams.AnalyzerBitrateThreshold {
+			return fmt.Sprintf("Bitrate %v < %v", bitrate, r.cfg.Streams.AnalyzerBitrateThreshold), true
+		}
+		// Check errors
+		ccErrorsThreshold := r.cfg.Streams.AnalyzerCCErrorsThreshold
+		pcrErrorsThreshold := r.cfg.Streams.AnalyzerPCRErrorsThreshold
+		pesErrorsThreshold := r.cfg.Streams.AnalyzerPESErrorsThreshold
+		if ccErrorsThreshold >= 0 && result.CCErrors > ccErrorsThreshold {
+			return fmt.Sprintf("CC errors %v > %v", result.CCErrors, ccErrorsThreshold), true
+		}
+		if pcrErrorsThreshold >= 0 && result.PCRErrors > pcrErrorsThreshold {
+			return fmt.Sprintf("PCR errors %v > %v", result.PCRErrors, pcrErrorsThreshold), true
+		}
+		if pesErrorsThreshold >= 0 && result.PESErrors > pesErrorsThreshold {
+			return fmt.Sprintf("PES errors %v > %v", result.PESErrors, pesErrorsThreshold), true
+		}
+		// Check stream info
+		if reason := r.checkStreamInfo(result); reason != "" {
+			return reason, true
+		}
+	} else if r.cfg.Streams.CheckHLS {
+		if err := hls.New(httpClient, r.cfg.Streams.HLSRefreshTimeout).Check(inp); err != nil {
+			errType := network.GetErrType(err)
+			return lo.Ternary(errType == network.Unknown, err.Error(), string(errType)), true
+		}
+	} else {
+		resp, err := httpClient.Get(inp)
+		if err == nil {
+			defer resp.Body.Close()
+		}
+		// Not checking Content-Type header as server can return text/html but stream still will be playable
+		// Not checking response body as some streams can periodically respond with no content but still be playable
+		if err != nil {
+			errType := network.GetErrType(err)
+			return lo.Ternary(errType == network.Unknown, err.Error(), string(errType)), true
+		} else if resp.StatusCode >= 400 {
+			return fmt.Sprintf("Responded with: %v", resp.Status), true
+		}
+	}
+	return "", true
+}
+
+// checkStreamInfo returns reason why input with analyzer <result> is dead according to config or empty string if it's
+// elementary streams and scrambling are fine
+func (r repo) checkStreamInfo(result analyzer.Result) string {
+	if r.cfg.Streams.AnalyzerDeadIfScrambled && result.Scrambled {
+		return "Scrambled"
+	}
+	if r.cfg.Streams.AnalyzerDeadIfNoVideo && !result.HasVideo {
+		return "No video"
+	}
+	audioLangs := lo.FlatMap(result.Streams, func(es analyzer.ElementaryStream, _ int) []string {
+		return lo.Ternary(es.Kind == analyzer.AudioKind, es.Languages, nil)
 	})
+	for _, lang := range r.cfg.Streams.AnalyzerAudioLanguages {
+		if !lo.ContainsBy(audioLangs, func(l string) bool { return strings.EqualFold(l, lang) }) {
+			return fmt.Sprintf("Missing audio language %v", lang)
+		}
+	}
+	if len(r.cfg.Streams.AnalyzerAllowedCodecs) == 0 {
+		return ""
+	}
+	for _, es := range result.Streams {
+		if es.Kind != analyzer.VideoKind && es.Kind != analyzer.AudioKind {
+			continue
+		}
+		isAllowed := func(codec string) bool { return strings.EqualFold(codec, es.Codec) }
+		if !lo.ContainsBy(r.cfg.Streams.AnalyzerAllowedCodecs, isAllowed) {
+			return fmt.Sprintf("Codec %v of PID %v is not allowed", es.Codec, es.PID)
+		}
+	}
+	return ""
+}
+
+// getDistinctInputs returns distinct inputs of <streams> in order of appearance
+func getDistinctInputs(streams []Stream) []string {
+	return lo.Uniq(lo.FlatMap(streams, func(s Stream, _ int) []string {
+		return s.Inputs
+	}))
 }
@@ -7,40 +7,233 @@ import (
 	"m3u_merge_astra/util/copier"
 	"m3u_merge_astra/util/logger"
 	"m3u_merge_astra/util/network"
-	"m3u_merge_astra/util/slice"
 	"net/http"
+	"path/filepath"
 	"regexp"
 	"strconv"
 	"strings"
 	"testing"
 	"time"
 
+	json "github.com/SCP002/jsonexraw"
 	"github.com/cockroachdb/errors"
+	"github.com/samber/lo"
 	"github.com/stretchr/testify/assert"
 	"github.com/zenizh/go-capturer"
 )
 
 func TestNewStream(t *testing.T) {
-	cfg := newDefRepo().cfg.Streams
-	s := NewStream(cfg, "0000", "Name", "Group", []string{"http://url"})
+	streamsCfg := newDefRepo().cfg.Streams
+	s, err := NewStream(streamsCfg, "0000", "Name", "Group", []string{"http://url"})
+	assert.NoError(t, err, "should not return error")
 
 	expected := Stream{
 		DisabledInputs: make([]string, 0),
-		Enabled:        cfg.MakeNewEnabled,
-		HTTPKeepActive: strconv.Itoa(cfg.NewKeepActive),
+		Enabled:        streamsCfg.MakeNewEnabled,
+		HTTPKeepActive: strconv.Itoa(streamsCfg.NewKeepActive),
 		ID:             "0000",
 		Inputs:         []string{"http://url"},
 		Name:           "Name",
-		Type:           string(cfg.NewType),
+		Type:           string(streamsCfg.NewType),
 		MarkAdded:      true,
 	}
 	assert.Exactly(t, expected, s, "should create this stream")
 
-	cfg.AddGroupsToNew = true
-	s = NewStream(cfg, "0000", "Name", "Group", []string{"http://url"})
+	streamsCfg.AddGroupsToNew = true
+	s, err = NewStream(streamsCfg, "0000", "Name", "Group", []string{"http://url"})
+	assert.NoError(t, err, "should not return error")
 
-	expected.Groups = map[string]string{cfg.GroupsCategoryForNew: "Group"}
+	expected.Groups = map[string]string{streamsCfg.GroupsCategoryForNew: "Group"}
 	assert.Exactly(t, expected, s, "should create this stream")
+
+	// Templates
+	streamsCfg.NameToNewStreamTemplateMap = []cfg.NewStreamTemplate{
+		{By: *regexp.MustCompile(`(?i)radio`), Stream: map[string]any{"enable": false, "type": "mpts"}},
+	}
+	streamsCfg.GroupToNewStreamTemplateMap = []cfg.NewStreamTemplate{
+		{By: *regexp.MustCompile(`^HD$`), Stream: map[string]any{
+			"id":          "ffff",
+			"name":        "Template name",
+			"input":       []any{"http://template/url"},
+			"output":      []any{"http://#/play/0000"},
+			"timeout":     "30",
+			"backup_type": "active",
+			"groups":      map[string]any{"Quality": "HD"},
+			"custom":      map[string]any{"key": "val"},
+		}},
+		{By: *regexp.MustCompile(`^Bad$`), Stream: map[string]any{"timeout": "abc"}},
+	}
+
+	s, err = NewStream(streamsCfg, "0000", "Radio FM", "HD", []string{"http://url"})
+	assert.NoError(t, err, "should not return error")
+	expected = Stream{
+		DisabledInputs: make([]string, 0),
+		Enabled:        false,
+		Groups:         map[string]string{streamsCfg.GroupsCategoryForNew: "HD"},
+		HTTPKeepActive: strconv.Itoa(streamsCfg.NewKeepActive),
+		ID:             "0000",
+		Inputs:         []string{"http://url"},
+		Name:           "Radio FM",
+		Type:           string(cfg.MPTS),
+		MarkAdded:      true,
+	}
+	assert.Exactly(t, expected, s, "should apply name template as it has priority over group template")
+
+	s, err = NewStream(streamsCfg, "0000", "Name", "HD", []string{"http://url"})
+	assert.NoError(t, err, "should not return error")
+	expected = Stream{
+		BackupType:     "active",
+		DisabledInputs: make([]string, 0),
+		Enabled:        streamsCfg.MakeNewEnabled,
+		Groups:         map[string]string{streamsCfg.GroupsCategoryForNew: "HD", "Quality": "HD"},
+		HTTPKeepActive: strconv.Itoa(streamsCfg.NewKeepActive),
+		ID:             "0000",
+		Inputs:         []string{"http://url"},
+		Name:           "Name",
+		Outputs:        []string{"http://#/play/0000"},
+		Timeout:        lo.ToPtr(Number(30)),
+		Type:           string(streamsCfg.NewType),
+		Unknown:        map[string]any{"custom": map[string]any{"key": "val"}},
+		MarkAdded:      true,
+	}
+	assert.Exactly(t, expected, s, "should apply group template except ID, name and inputs")
+
+	s, err = NewStream(streamsCfg, "0000", "Name", "Bad", []string{"http://url"})
+	assert.ErrorContains(t, err, `Apply template "^Bad$" to new stream`, "should return error for invalid template")
+	assert.Exactly(t, "Name", s.Name, "should return stream with default config")
+	assert.Nil(t, s.Timeout, "should return stream with default config")
+}
+
+func TestValidateTemplates(t *testing.T) {
+	streamsCfg := newDefRepo().cfg.Streams
+	streamsCfg.NameToNewStreamTemplateMap = []cfg.NewStreamTemplate{
+		{By: *regexp.MustCompile(`(?i)radio`), Stream: map[string]any{"type": "mpts", "timeout": "30"}},
+	}
+	streamsCfg.GroupToNewStreamTemplateMap = []cfg.NewStreamTemplate{
+		{By: *regexp.MustCompile(`^HD$`), Stream: map[string]any{"output": []any{"http://#/play/{id}"}}},
+	}
+	assert.NoError(t, ValidateTemplates(streamsCfg), "should not return error for valid templates")
+
+	streamsCfg.GroupToNewStreamTemplateMap = append(streamsCfg.GroupToNewStreamTemplateMap,
+		cfg.NewStreamTemplate{By: *regexp.MustCompile(`^Bad$`), Stream: map[string]any{"timeout": "abc"}})
+	err := ValidateTemplates(streamsCfg)
+	assert.ErrorContains(t, err, `New stream template "^Bad$": Decode fields`, "should return error for bad value")
+
+	streamsCfg.GroupToNewStreamTemplateMap[1].Stream = map[string]any{"backup_type": "abc"}
+	err = ValidateTemplates(streamsCfg)
+	assert.ErrorContains(t, err, `New stream template "^Bad$": Validate stream`, "should return error for invalid value")
+}
+
+func TestSetFields(t *testing.T) {
+	s1 := Stream{ID: "0000", Name: "Name", Inputs: []string{"http://url"}, Groups: map[string]string{"A": "B"}}
+	s1Original := copier.TestDeep(t, s1)
+
+	s2, err := s1.SetFields(map[string]any{"id": "ffff", "name": "Other", "input": []any{}, "remove": true,
+		"groups": map[string]any{"C": "D"}, "timeout": "10", "custom": 1})
+	assert.NoError(t, err, "should not return error")
+	assert.Exactly(t, s1Original, s1, "should not modify the source")
+	expected := Stream{
+		ID:      "0000",
+		Name:    "Name",
+		Inputs:  []string{"http://url"},
+		Groups:  map[string]string{"A": "B", "C": "D"},
+		Timeout: lo.ToPtr(Number(10)),
+		Unknown: map[string]any{"custom": float64(1)},
+	}
+	assert.Exactly(t, expected, s2, "should set fields except ID, name, inputs and remove flag")
+
+	s2, err = s1.SetFields(map[string]any{"timeout": "abc"})
+	assert.ErrorContains(t, err, "Decode fields", "should return error for invalid value")
+	assert.Exactly(t, s1, s2, "should return stream as is on error")
+}
+
+func TestStreamJSON(t *testing.T) {
+	input := `{"id":"a001","name":"Stream","enable":true,"input":["http://a"],"output":["udp://239.0.0.1:1234"],` +
+		`"timeout":"10","backup_type":"passive","backup_start_delay":5,"service_provider":"Provider",` +
+		`"set_pnr":"100","map":"video=101","filter":"200,201","pass_sdt":true,"custom":{"key":"val"}}`
+	var s Stream
+	err := json.Unmarshal([]byte(input), &s)
+	assert.NoError(t, err, "should not return error")
+
+	expected := Stream{
+		BackupStartDelay: lo.ToPtr(Number(5)),
+		BackupType:       "passive",
+		Enabled:          true,
+		Filter:           "200,201",
+		ID:               "a001",
+		Inputs:           []string{"http://a"},
+		Map:              "video=101",
+		Name:             "Stream",
+		Outputs:          []string{"udp://239.0.0.1:1234"},
+		PassSDT:          lo.ToPtr(true),
+		ServiceProvider:  "Provider",
+		SetPNR:           lo.ToPtr(Number(100)),
+		Timeout:          lo.ToPtr(Number(10)),
+		Unknown:          map[string]any{"custom": map[string]any{"key": "val"}},
+	}
+	assert.Exactly(t, expected, s, "should decode typed fields and keep the rest in unknown fields")
+
+	out, err := json.Marshal(s)
+	assert.NoError(t, err, "should not return error")
+	expectedOut := `{"backup_start_delay":5,"backup_type":"passive","enable":true,"filter":"200,201","id":"a001",` +
+		`"input":["http://a"],"map":"video=101","name":"Stream","output":["udp://239.0.0.1:1234"],"pass_sdt":true,` +
+		`"service_provider":"Provider","set_pnr":100,"timeout":10,"custom":{"key":"val"}}`
+	assert.Exactly(t, expectedOut, string(out), "should encode typed fields and unknown fields")
+
+	input = `{"id":"a001","name":"Stream","enable":false,"timeout":"abc","set_pnr":1.5,"pass_eit":"true","custom":1}`
+	s = Stream{}
+	err = json.Unmarshal([]byte(input), &s)
+	assert.NoError(t, err, "should not return error for values of unexpected type")
+	expected = Stream{
+		ID:      "a001",
+		Name:    "Stream",
+		Unknown: map[string]any{"timeout": "abc", "set_pnr": 1.5, "pass_eit": "true", "custom": float64(1)},
+	}
+	assert.Exactly(t, expected, s, "should keep values of unexpected type in unknown fields")
+
+	out, err = json.Marshal(s)
+	assert.NoError(t, err, "should not return error")
+	assert.JSONEq(t, input, string(out), "should encode values of unexpected type as is")
+
+	s, err = s.SetFields(map[string]any{"timeout": 10})
+	assert.NoError(t, err, "should not return error")
+	assert.Exactly(t, lo.ToPtr(Number(10)), s.Timeout, "should set typed field")
+	assert.NotContains(t, s.Unknown, "timeout", "should not keep replaced value in unknown fields")
+
+	input = `{"id":"a001","name":"Stream","enable":true,"timeout":0,"backup_start_delay":"0","pass_sdt":false,` +
+		`"set_pnr":"","custom":1}`
+	s = Stream{}
+	err = json.Unmarshal([]byte(input), &s)
+	assert.NoError(t, err, "should not return error")
+	assert.Exactly(t, lo.ToPtr(Number(0)), s.Timeout, "should keep explicit zero value")
+	assert.Exactly(t, lo.ToPtr(false), s.PassSDT, "should keep explicit false value")
+	assert.Nil(t, s.PassEIT, "should not set missing value")
+
+	out, err = json.Marshal(s)
+	assert.NoError(t, err, "should not return error")
+	expectedOut = `{"backup_start_delay":0,"enable":true,"id":"a001","name":"Stream","pass_sdt":false,"timeout":0,` +
+		`"custom":1,"set_pnr":""}`
+	assert.JSONEq(t, expectedOut, string(out), "should encode explicit zero values and keep empty value as is")
+}
+
+func TestValidate(t *testing.T) {
+	s := Stream{ID: "0", Name: "Stream", Type: "spts", BackupType: "active", Timeout: lo.ToPtr(Number(10)),
+		SetPNR: lo.ToPtr(Number(65535)), Outputs: []string{"udp://239.0.0.1:1234"}}
+	assert.NoError(t, s.Validate(), "should not return error for valid stream")
+	assert.NoError(t, Stream{}.Validate(), "should not return error for stream with defaults")
+
+	s = Stream{ID: "0", Name: "Stream", Type: "abc", BackupType: "abc", Timeout: lo.ToPtr(Number(-1)),
+		BackupStartDelay: lo.ToPtr(Number(-1)), SetTSID: lo.ToPtr(Number(65536)), Outputs: []string{"239.0.0.1:1234"}}
+	err := s.Validate()
+	assert.Error(t, err, "should return error for invalid stream")
+	expected := "Validate stream 0 (Stream): " +
+		"backup_start_delay -1 is negative\n" +
+		"backup_type \"abc\" is not one of active, active_stop, passive, disable\n" +
+		"output \"239.0.0.1:1234\" has no scheme\n" +
+		"set_tsid 65536 is out of range 0-65535\n" +
+		"timeout -1 is negative\n" +
+		"type \"abc\" is not one of spts, mpts"
+	assert.Exactly(t, expected, err.Error(), "should describe every invalid field")
 }
 
 func TestGetName(t *testing.T) {
@@ -48,6 +241,42 @@ func TestGetName(t *testing.T) {
 	assert.Exactly(t, s.Name, s.GetName(), "should return this name")
 }
 
+func TestGetTVGID(t *testing.T) {
+	cfg := cfg.General{
+		TVGIDCategory:  "TVG ID",
+		StreamTVGIDMap: map[string]string{"0": "from.map"},
+	}
+
+	s := Stream{ID: "0", Groups: map[string]string{"TVG ID": "from.group"}}
+	assert.Exactly(t, "from.map", s.GetTVGID(cfg), "should prioritize tvg-id from the map")
+
+	s = Stream{ID: "1", Groups: map[string]string{"TVG ID": "from.group"}}
+	assert.Exactly(t, "from.group", s.GetTVGID(cfg), "should return tvg-id from the group")
+
+	s = Stream{ID: "1", Groups: map[string]string{"Other": "from.group"}}
+	assert.Exactly(t, "", s.GetTVGID(cfg), "should return empty tvg-id if not found")
+
+	cfg.TVGIDCategory = ""
+	s = Stream{ID: "1", Groups: map[string]string{"": "from.group"}}
+	assert.Exactly(t, "", s.GetTVGID(cfg), "should return empty tvg-id if category is not set")
+}
+
+func TestSetTVGID(t *testing.T) {
+	cfg := cfg.General{TVGIDCategory: "TVG ID"}
+
+	s1 := Stream{Groups: map[string]string{"Category": "Group"}}
+	s1Original := copier.TestDeep(t, s1)
+	s2 := s1.SetTVGID(cfg, "id.tv")
+
+	assert.Exactly(t, s1Original, s1, "should not modify the source stream")
+	expected := Stream{Groups: map[string]string{"Category": "Group", "TVG ID": "id.tv"}}
+	assert.Exactly(t, expected, s2, "should add group with tvg-id")
+
+	s2 = Stream{}.SetTVGID(cfg, "id.tv")
+	expected = Stream{Groups: map[string]string{"TVG ID": "id.tv"}}
+	assert.Exactly(t, expected, s2, "should add group with tvg-id to stream without groups")
+}
+
 func TestFirstGroup(t *testing.T) {
 	s := Stream{}
 	assert.Empty(t, s.FirstGroup(), "should return empty group")
@@ -182,6 +411,35 @@ func TestAddInput(t *testing.T) {
 	assert.Exactly(t, expected, s2.Inputs, "should have these inputs")
 }
 
+func TestReviveInput(t *testing.T) {
+	weight := func(inp string) int { return lo.Ternary(strings.HasPrefix(inp, "udp://"), 1, 10) }
+	s1 := Stream{
+		Inputs:         []string{"udp://input/1", "http://input/2"},
+		DisabledInputs: []string{"udp://input/3", "http://input/4", "http://input/2"},
+	}
+	s1Original := copier.TestDeep(t, s1)
+
+	s2 := s1.reviveInput("udp://input/3", weight)
+	assert.NotSame(t, &s1, &s2, "should return copy of stream")
+	assert.Exactly(t, s1Original, s1, "should not modify the source")
+	expected := Stream{
+		Inputs:         []string{"udp://input/1", "udp://input/3", "http://input/2"},
+		DisabledInputs: []string{"http://input/4", "http://input/2"},
+	}
+	assert.Exactly(t, expected, s2, "should place input before inputs with higher weight")
+
+	s2 = s2.reviveInput("http://input/4", weight)
+	expected = Stream{
+		Inputs:         []string{"udp://input/1", "udp://input/3", "http://input/2", "http://input/4"},
+		DisabledInputs: []string{"http://input/2"},
+	}
+	assert.Exactly(t, expected, s2, "should place input last if there is no input with higher weight")
+
+	s2 = s2.reviveInput("http://input/2", weight)
+	expected.DisabledInputs = []string{}
+	assert.Exactly(t, expected, s2, "should not duplicate input which is already enabled")
+}
+
 func TestKnownInputs(t *testing.T) {
 	config := cfg.NewDefCfg().Streams
 
@@ -279,7 +537,7 @@ func TestDisableStream(t *testing.T) {
 	s1 := Stream{Enabled: false, MarkDisabled: false}
 	s1Original := copier.TestDeep(t, s1)
 
-	s2 := s1.disable()
+	s2 := s1.Disable()
 	assert.NotSame(t, &s1, &s2, "should return copy of stream")
 	assert.Exactly(t, s1Original, s1, "should not modify the source")
 
@@ -289,7 +547,7 @@ func TestDisableStream(t *testing.T) {
 	s1 = Stream{Enabled: true, MarkDisabled: false}
 	s1Original = copier.TestDeep(t, s1)
 
-	s2 = s1.disable()
+	s2 = s1.Disable()
 	assert.NotSame(t, &s1, &s2, "should return copy of stream")
 	assert.Exactly(t, s1Original, s1, "should not modify the source")
 
@@ -298,13 +556,13 @@ func TestDisableStream(t *testing.T) {
 
 	s1 = Stream{Enabled: false, MarkDisabled: true}
 
-	s2 = s1.disable()
+	s2 = s1.Disable()
 
 	assert.Exactly(t, s1, s2, "should not change the stream")
 
 	s1 = Stream{Enabled: true, MarkDisabled: true}
 
-	s2 = s1.disable()
+	s2 = s1.Disable()
 
 	expected = Stream{Enabled: false, MarkDisabled: true}
 	assert.Exactly(t, expected, s2, "should disable the stream")
@@ -1092,14 +1350,12 @@ func TestRemoveDeadInputs(t *testing.T) {
 		analyzerClient := analyzer.NewFake()
 		_ = r.RemoveDeadInputs(httpClient, analyzerClient, sl1)
 	})
-	msg := `Start checking input: stream ID "0", stream name "Name 1", stream index "0", ` +
-		`input "https://127.0.0.1:5656/dead/timeout/1"`
+	msg := `Start checking input: input "https://127.0.0.1:5656/dead/timeout/1"`
 	assert.Contains(t, out, msg)
 	msg = `Removing dead input from stream: ID "0", name "Name 1", group "Cat: Grp", ` +
 		`input "https://127.0.0.1:5656/dead/timeout/1", reason "Timeout"`
 	assert.Contains(t, out, msg)
-	msg = `End checking input: stream ID "0", stream name "Name 1", stream index "0", ` +
-		`input "https://127.0.0.1:5656/dead/timeout/1"`
+	msg = `End checking input: input "https://127.0.0.1:5656/dead/timeout/1"`
 	assert.Contains(t, out, msg)
 }
 
@@ -1220,17 +1476,54 @@ func TestAnalyzerRemoveDeadInputs(t *testing.T) {
 
 		_ = r.RemoveDeadInputs(httpClient, analyzerClient, sl1)
 	})
-	msg := `Start checking input: stream ID "0", stream name "Name 1", stream index "0", ` +
-		`input "https://dead/audio/50"`
+	msg := `Start checking input: input "https://dead/audio/50"`
 	assert.Contains(t, out, msg)
 	msg = `Removing dead input from stream: ID "0", name "Name 1", group "Cat: Grp", ` +
 		`input "https://dead/audio/50", reason "Bitrate 50 < 100"`
 	assert.Contains(t, out, msg)
-	msg = `End checking input: stream ID "0", stream name "Name 1", stream index "0", ` +
-		`input "https://dead/audio/50"`
+	msg = `End checking input: input "https://dead/audio/50"`
 	assert.Contains(t, out, msg)
 }
 
+func TestHealthRemoveDeadInputs(t *testing.T) {
+	r := newDefRepo()
+	r.cfg.Streams.UseAnalyzer = true
+	r.cfg.Streams.InputHealthFile = filepath.Join(t.TempDir(), "health.json")
+	r.cfg.Streams.DeadInputFailures = 2
+
+	analyzerClient := analyzer.NewFake()
+	analyzerClient.AddResult("http://alive/1", analyzer.Result{Bitrate: 1000, HasAudio: true, HasVideo: true})
+	analyzerClient.AddError("http://unknown/1", errors.New("Connection refused"))
+
+	// Input failed once before the analyzer went down
+	store := NewHealthStore()
+	store.Record(r.cfg.Streams, "http://unknown/1", "Timeout", time.Now())
+	err := r.SaveHealth(r.cfg.Streams.InputHealthFile, store)
+	assert.NoError(t, err, "should save health of inputs")
+
+	sl1 := []Stream{
+		{ID: "0000", Name: "Name 1", Inputs: []string{"http://alive/1", "http://dead/1", "http://unknown/1"}},
+		{ID: "0001", Name: "Name 2", Inputs: []string{"http://dead/1", "http://alive/1"}},
+	}
+
+	sl2 := r.RemoveDeadInputs(nil, analyzerClient, sl1)
+	assert.Exactly(t, sl1, sl2, "should not remove inputs before enough failed checks, counting shared input once")
+
+	sl2 = r.RemoveDeadInputs(nil, analyzerClient, sl1)
+	expected := []string{"http://alive/1", "http://unknown/1"}
+	assert.Exactly(t, expected, sl2[0].Inputs, "should remove input failed enough checks, keep unchecked input")
+	expected = []string{"http://alive/1"}
+	assert.Exactly(t, expected, sl2[1].Inputs, "should remove shared input from every stream")
+
+	store, err = r.ReadHealth(r.cfg.Streams.InputHealthFile)
+	assert.NoError(t, err, "should read health of inputs")
+	assert.Exactly(t, 2, store.Inputs["http://dead/1"].ConsecutiveFailures, "should record failed checks")
+	assert.Exactly(t, 2, store.Inputs["http://alive/1"].ConsecutiveSuccesses, "should record successful checks")
+	assert.Exactly(t, 1, store.Inputs["http://unknown/1"].ConsecutiveFailures,
+		"should not record checks which could not be run")
+	assert.Len(t, store.Inputs["http://unknown/1"].Checks, 1, "should not record checks which could not be run")
+}
+
 func TestProgressRemoveDeadInputs(t *testing.T) {
 	log := logger.New(logger.DebugLevel)
 
@@ -1265,7 +1558,9 @@ func TestProgressRemoveDeadInputs(t *testing.T) {
 		r.cfg.Streams.InputMaxConns = 1
 		r.cfg.Streams.UseAnalyzer = false
 
-		sl1 := []Stream{{Inputs: slice.Filled("http://127.0.0.1:3434/sleep/2sec", 20)}}
+		sl1 := []Stream{{Inputs: lo.Times(20, func(idx int) string {
+			return "http://127.0.0.1:3434/sleep/2sec?" + strconv.Itoa(idx)
+		})}}
 
 		httpClient := network.NewFakeHttpClient(time.Second * 3)
 		analyzerClient := analyzer.NewFake()
@@ -1275,6 +1570,58 @@ func TestProgressRemoveDeadInputs(t *testing.T) {
 	assert.Contains(t, out, `Removing dead inputs from streams: progress "14 / 20 (70%)"`)
 }
 
+func TestCheckStreamInfo(t *testing.T) {
+	r := newDefRepo()
+	result := analyzer.Result{
+		Scrambled: true,
+		HasAudio:  true,
+		Streams: []analyzer.ElementaryStream{
+			{PID: 256, Kind: analyzer.DataKind, Codec: "teletext", Languages: []string{"rus"}},
+			{PID: 257, Kind: analyzer.AudioKind, Codec: "mp2", Languages: []string{"ENG"}},
+			{PID: 258, Kind: analyzer.AudioKind, Codec: "ac3"},
+		},
+	}
+	assert.Exactly(t, "", r.checkStreamInfo(result), "should not check stream info by default")
+
+	r.cfg.Streams.AnalyzerDeadIfScrambled = true
+	assert.Exactly(t, "Scrambled", r.checkStreamInfo(result), "should consider scrambled input dead")
+	r.cfg.Streams.AnalyzerDeadIfScrambled = false
+
+	r.cfg.Streams.AnalyzerDeadIfNoVideo = true
+	assert.Exactly(t, "No video", r.checkStreamInfo(result), "should consider input without video dead")
+	r.cfg.Streams.AnalyzerDeadIfNoVideo = false
+
+	r.cfg.Streams.AnalyzerAudioLanguages = []string{"eng", "rus"}
+	assert.Exactly(t, "Missing audio language rus", r.checkStreamInfo(result),
+		"should consider input dead if any language is missing in audio streams")
+	resultAllLangs := copier.TestDeep(t, result)
+	resultAllLangs.Streams = append(resultAllLangs.Streams,
+		analyzer.ElementaryStream{PID: 259, Kind: analyzer.AudioKind, Codec: "mp2", Languages: []string{"rus"}})
+	assert.Exactly(t, "", r.checkStreamInfo(resultAllLangs), "should require audio of all languages")
+	r.cfg.Streams.AnalyzerAudioLanguages = []string{"eng"}
+	assert.Exactly(t, "", r.checkStreamInfo(result), "should compare languages ignoring case")
+
+	r.cfg.Streams.AnalyzerAllowedCodecs = []string{"H264", "mp2"}
+	assert.Exactly(t, "Codec ac3 of PID 258 is not allowed", r.checkStreamInfo(result),
+		"should consider input dead if codec of any audio or video stream is not allowed")
+	r.cfg.Streams.AnalyzerAllowedCodecs = []string{"mp2", "AC3"}
+	assert.Exactly(t, "", r.checkStreamInfo(result), "should compare codecs ignoring case and skip data streams")
+
+	// Test with RemoveDeadInputs
+	r = newDefRepo()
+	r.cfg.Streams.UseAnalyzer = true
+	r.cfg.Streams.AnalyzerDeadIfScrambled = true
+	sl1 := []Stream{{Name: "Name 1", Inputs: []string{"http://scrambled", "http://clear"}}}
+
+	httpClient := network.NewFakeHttpClient(time.Second * 3)
+	analyzerClient := analyzer.NewFake()
+	analyzerClient.AddResult("http://scrambled", analyzer.Result{Bitrate: 1000, Scrambled: true})
+	analyzerClient.AddResult("http://clear", analyzer.Result{Bitrate: 1000})
+
+	sl2 := r.RemoveDeadInputs(httpClient, analyzerClient, sl1)
+	assert.Exactly(t, []string{"http://clear"}, sl2[0].Inputs, "should remove scrambled input")
+}
+
 func TestDisableDeadInputs(t *testing.T) {
 	r := newDefRepo()
 	r.cfg.Streams.InputMaxConns = 1
@@ -1364,6 +1711,157 @@ func TestDisableDeadInputs(t *testing.T) {
 	msg := `Disabling dead input of stream: ID "0", name "Name 1", group "Cat: Grp", ` +
 		`input "http://dead/no_such_host/1", reason "No such host"`
 	assert.Contains(t, out, msg)
+
+	// Test recording streams inputs were disabled in
+	r.cfg.Streams.InputHealthFile = filepath.Join(t.TempDir(), "health.json")
+	r.cfg.Streams.DeadInputFailures = 1
+	sl1 = []Stream{
+		{ID: "0", Inputs: []string{"http://dead/no_such_host/1"}},
+		{ID: "1", Inputs: []string{"http://127.0.0.1:3434/alive/1", "http://dead/no_such_host/1"}},
+		{ID: "2", DisabledInputs: []string{"http://dead/no_such_host/1"}},
+	}
+	_ = r.DisableDeadInputs(httpClient, analyzerClient, sl1)
+	store, err := r.ReadHealth(r.cfg.Streams.InputHealthFile)
+	assert.NoError(t, err, "should read health of inputs")
+	assert.Exactly(t, []string{"0", "1"}, store.Inputs["http://dead/no_such_host/1"].DisabledIn,
+		"should record streams input was disabled in by this program")
+	assert.Empty(t, store.Inputs["http://127.0.0.1:3434/alive/1"].DisabledIn, "should not record alive inputs")
+}
+
+func TestReviveDisabledInputs(t *testing.T) {
+	r := newDefRepo()
+	r.cfg.Streams.UseAnalyzer = true
+	r.cfg.Streams.InputHealthFile = filepath.Join(t.TempDir(), "health.json")
+	r.cfg.Streams.AliveInputSuccesses = 2
+	r.cfg.Streams.InputWeightToTypeMap = map[int]regexp.Regexp{
+		1: *regexp.MustCompile(`^udp://`),
+		5: *regexp.MustCompile(`^http://alive/`),
+	}
+
+	analyzerClient := analyzer.NewFake()
+	alive := analyzer.Result{Bitrate: 1000, HasAudio: true, HasVideo: true}
+	analyzerClient.AddResult("http://alive/1", alive)
+	analyzerClient.AddResult("http://alive/2", alive)
+	analyzerClient.AddResult("http://alive/3", alive)
+
+	// Inputs disabled by this program are dead in history, other inputs are alive or never checked
+	store := NewHealthStore()
+	store.Record(r.cfg.Streams, "http://alive/1", "Timeout", time.Now())
+	store.Record(r.cfg.Streams, "http://dead/1", "Timeout", time.Now())
+	store.Record(r.cfg.Streams, "http://alive/2", "", time.Now())
+	store.MarkDisabled("http://alive/1", "0000")
+	store.MarkDisabled("http://dead/1", "0000")
+	err := r.SaveHealth(r.cfg.Streams.InputHealthFile, store)
+	assert.NoError(t, err, "should save health of inputs")
+
+	sl1 := []Stream{
+		{
+			ID:             "0000",
+			Name:           "Name 1",
+			Inputs:         []string{"udp://input/1", "http://other/1"},
+			DisabledInputs: []string{"http://alive/1", "http://dead/1", "http://alive/3"},
+		},
+		{
+			ID:             "0001",
+			Name:           "Name 2",
+			DisabledInputs: []string{"http://alive/2"},
+		},
+	}
+	sl1Original := copier.TestDeep(t, sl1)
+
+	var sl2 []Stream
+	out := capturer.CaptureStderr(func() {
+		sl2 = NewRepo(logger.New(logger.DebugLevel), r.cfg).ReviveDisabledInputs(nil, analyzerClient, sl1)
+	})
+	assert.NotSame(t, &sl1, &sl2, "should return copy of streams")
+	assert.Exactly(t, sl1Original, sl1, "should not modify the source")
+	assert.Exactly(t, sl1, sl2, "should not enable inputs before enough successful checks")
+	assert.NotContains(t, out, "Enabling alive input of stream")
+
+	out = capturer.CaptureStderr(func() {
+		sl2 = NewRepo(logger.New(logger.DebugLevel), r.cfg).ReviveDisabledInputs(nil, analyzerClient, sl1)
+	})
+	expected := []Stream{
+		{
+			ID:             "0000",
+			Name:           "Name 1",
+			Inputs:         []string{"udp://input/1", "http://alive/1", "http://other/1"},
+			DisabledInputs: []string{"http://dead/1", "http://alive/3"},
+		},
+		sl1[1],
+	}
+	assert.Exactly(t, expected, sl2, "should enable only alive inputs disabled by this program, respecting weights")
+	assert.Contains(t, out, `Enabling alive input of stream: ID "0000", name "Name 1", group "", `+
+		`input "http://alive/1"`)
+
+	store, err = r.ReadHealth(r.cfg.Streams.InputHealthFile)
+	assert.NoError(t, err, "should read health of inputs")
+	assert.False(t, store.Inputs["http://alive/1"].Dead, "should save revived input as alive")
+	assert.Empty(t, store.Inputs["http://alive/1"].DisabledIn, "should forget streams revived input was disabled in")
+	assert.Exactly(t, []string{"0000"}, store.Inputs["http://dead/1"].DisabledIn, "should keep streams of dead input")
+	assert.Exactly(t, 3, store.Inputs["http://dead/1"].ConsecutiveFailures, "should save failed checks")
+	_, found := store.Get("http://alive/3")
+	assert.False(t, found, "should not check inputs missing in history")
+
+	// Test analyzer which is not reachable and input shared by streams
+	r.cfg.Streams.AliveInputSuccesses = 1
+	analyzerClient.AddError("http://unknown/1", errors.New("Connection refused"))
+	analyzerClient.AddResult("http://alive/4", alive)
+	store = NewHealthStore()
+	store.Record(r.cfg.Streams, "http://unknown/1", "Timeout", time.Now())
+	store.Record(r.cfg.Streams, "http://alive/4", "Timeout", time.Now())
+	store.MarkDisabled("http://unknown/1", "0000")
+	store.MarkDisabled("http://unknown/1", "0001")
+	store.MarkDisabled("http://alive/4", "0000")
+	store.MarkDisabled("http://alive/4", "0001")
+	err = r.SaveHealth(r.cfg.Streams.InputHealthFile, store)
+	assert.NoError(t, err, "should save health of inputs")
+
+	sl1 = []Stream{
+		{ID: "0000", Name: "Name 1", DisabledInputs: []string{"http://unknown/1", "http://alive/4"}},
+		{ID: "0001", Name: "Name 2", DisabledInputs: []string{"http://alive/4", "http://unknown/1"}},
+	}
+	out = capturer.CaptureStderr(func() {
+		sl2 = NewRepo(logger.New(logger.DebugLevel), r.cfg).ReviveDisabledInputs(nil, analyzerClient, sl1)
+	})
+	expected = []Stream{
+		{ID: "0000", Name: "Name 1", Inputs: []string{"http://alive/4"}, DisabledInputs: []string{"http://unknown/1"}},
+		{ID: "0001", Name: "Name 2", Inputs: []string{"http://alive/4"}, DisabledInputs: []string{"http://unknown/1"}},
+	}
+	assert.Exactly(t, expected, sl2, "should enable shared alive input in every stream, keep inputs not checked")
+	assert.Contains(t, out, "Failed to run analyzer")
+
+	store, err = r.ReadHealth(r.cfg.Streams.InputHealthFile)
+	assert.NoError(t, err, "should read health of inputs")
+	assert.Len(t, store.Inputs["http://alive/4"].Checks, 2, "should check shared input once")
+	assert.True(t, store.Inputs["http://unknown/1"].Dead, "should keep input dead if it could not be checked")
+	assert.Len(t, store.Inputs["http://unknown/1"].Checks, 1, "should not record checks which could not be run")
+
+	// Test input shared by streams, disabled by this program only in one of them
+	store = NewHealthStore()
+	store.Record(r.cfg.Streams, "http://alive/4", "Timeout", time.Now())
+	store.MarkDisabled("http://alive/4", "0000")
+	err = r.SaveHealth(r.cfg.Streams.InputHealthFile, store)
+	assert.NoError(t, err, "should save health of inputs")
+
+	sl1 = []Stream{
+		{ID: "0000", Name: "Name 1", DisabledInputs: []string{"http://alive/4"}},
+		{ID: "0001", Name: "Name 2", DisabledInputs: []string{"http://alive/4"}},
+	}
+	sl2 = NewRepo(logger.New(logger.DebugLevel), r.cfg).ReviveDisabledInputs(nil, analyzerClient, sl1)
+	expected = []Stream{
+		{ID: "0000", Name: "Name 1", Inputs: []string{"http://alive/4"}, DisabledInputs: []string{}},
+		sl1[1],
+	}
+	assert.Exactly(t, expected, sl2, "should not enable input disabled by hand in other stream")
+
+	// Test without health file
+	r.cfg.Streams.InputHealthFile = ""
+	out = capturer.CaptureStderr(func() {
+		sl2 = NewRepo(logger.New(logger.DebugLevel), r.cfg).ReviveDisabledInputs(nil, analyzerClient, sl1)
+	})
+	assert.Exactly(t, sl1, sl2, "should not change streams")
+	assert.Contains(t, out, "Input health file is not set")
 }
 
 func TestAddHashes(t *testing.T) {
@@ -1995,7 +2493,7 @@ func TestChangedStreams(t *testing.T) {
 	assert.Exactly(t, expected, actual, "should return that changed streams")
 }
 
-func TestGetInputsAmount(t *testing.T) {
+func TestGetDistinctInputs(t *testing.T) {
 	sl1 := []Stream{
 		{Inputs: []string{"http://input/1"}},
 		{Inputs: []string{"http://input/1", "http://input/1"}},
@@ -2004,5 +2502,6 @@ func TestGetInputsAmount(t *testing.T) {
 		{},
 	}
 
-	assert.Exactly(t, 6, getInputsAmount(sl1), "should be 6 inputs in total")
+	expected := []string{"http://input/1", "http://input/2"}
+	assert.Exactly(t, expected, getDistinctInputs(sl1), "should return distinct inputs in order of appearance")
 }
@@ -0,0 +1,97 @@
+package astra
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	"github.com/samber/lo"
+)
+
+// Drift represents stream or category which astra does not hold in the state it was sent in
+type Drift struct {
+	StreamID string   // Empty for categories
+	Name     string   // Name of the stream or category
+	Fields   []string // Differing JSON fields of the stream or groups of the category
+	Reason   string
+}
+
+// VerifyStreams returns drift between <sent> streams and <actual> streams refetched from astra after sending.
+//
+// Streams are compared the same way as in ChangedStreams. Streams with Remove field set to true should be absent in
+// <actual>.
+func (r repo) VerifyStreams(sent, actual []Stream) (out []Drift) {
+	r.log.Info("Verifying streams sent to astra")
+
+	for _, sentStream := range sent {
+		actualStream, found := lo.Find(actual, func(s Stream) bool { return s.ID == sentStream.ID })
+		drift := Drift{StreamID: sentStream.ID, Name: sentStream.Name}
+		switch {
+		case sentStream.Remove && found:
+			drift.Reason = "Not removed"
+		case sentStream.Remove:
+			continue
+		case !found:
+			drift.Reason = "Missing in astra"
+		case cmp.Equal(sentStream, actualStream, ignoreMarks, cmpopts.EquateEmpty()):
+			continue
+		default:
+			drift.Fields = diffStreamFields(sentStream, actualStream)
+			drift.Reason = "Fields differ"
+		}
+		r.log.WarnFi("Stream in astra differs from the sent one", "ID", drift.StreamID, "name", drift.Name,
+			"fields", strings.Join(drift.Fields, ", "), "reason", drift.Reason)
+		out = append(out, drift)
+	}
+
+	return
+}
+
+// VerifyCategories returns drift between <sent> categories and <actual> categories refetched from astra after sending.
+//
+// Categories are matched by name. Categories and groups with Remove field set to true should be absent in <actual>.
+func (r repo) VerifyCategories(sent []lo.Entry[int, Category], actual []Category) (out []Drift) {
+	r.log.Info("Verifying categories sent to astra")
+
+	for _, entry := range sent {
+		sentCat := entry.Value
+		actualCat, found := lo.Find(actual, func(c Category) bool { return c.Name == sentCat.Name })
+		drift := Drift{Name: sentCat.Name}
+		switch {
+		case sentCat.Remove && found:
+			drift.Reason = "Not removed"
+		case sentCat.Remove:
+			continue
+		case !found:
+			drift.Reason = "Missing in astra"
+		default:
+			expected := lo.FilterMap(sentCat.Groups, func(g Group, _ int) (string, bool) { return g.Name, !g.Remove })
+			actualGroups := lo.Map(actualCat.Groups, func(g Group, _ int) string { return g.Name })
+			missing, unexpected := lo.Difference(lo.Uniq(expected), lo.Uniq(actualGroups))
+			if len(missing) == 0 && len(unexpected) == 0 {
+				continue
+			}
+			drift.Fields = append(missing, unexpected...)
+			drift.Reason = "Groups differ"
+		}
+		r.log.WarnFi("Category in astra differs from the sent one", "name", drift.Name, "groups",
+			strings.Join(drift.Fields, ", "), "reason", drift.Reason)
+		out = append(out, drift)
+	}
+
+	return
+}
+
+// diffStreamFields returns sorted names of JSON fields which differ in streams <a> and <b>
+func diffStreamFields(a, b Stream) (out []string) {
+	aMap, bMap := streamToMap(a), streamToMap(b)
+	for _, key := range lo.Uniq(append(lo.Keys(aMap), lo.Keys(bMap)...)) {
+		if !reflect.DeepEqual(aMap[key], bMap[key]) {
+			out = append(out, key)
+		}
+	}
+	sort.Strings(out)
+	return
+}
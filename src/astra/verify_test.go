@@ -0,0 +1,69 @@
+package astra
+
+import (
+	"testing"
+
+	"github.com/samber/lo"
+	"github.com/stretchr/testify/assert"
+	"github.com/zenizh/go-capturer"
+)
+
+func TestVerifyStreams(t *testing.T) {
+	r := newDefRepo()
+
+	sent := []Stream{
+		{ID: "0", Name: "Stream 0", DisabledInputs: []string{}, MarkAdded: true},
+		{ID: "1", Name: "Stream 1", Inputs: []string{"http://a", "http://b"}, HTTPKeepActive: "0"},
+		{ID: "2", Name: "Stream 2"},
+		{ID: "3", Name: "Stream 3", Remove: true},
+		{ID: "4", Name: "Stream 4", Remove: true},
+	}
+	actual := []Stream{
+		{ID: "0", Name: "Stream 0"},
+		{ID: "1", Name: "Stream 1", Inputs: []string{"http://a"}},
+		{ID: "4", Name: "Stream 4"},
+	}
+
+	var out []Drift
+	log := capturer.CaptureStderr(func() {
+		r := newDefRepo()
+		out = r.VerifyStreams(sent, actual)
+	})
+	expected := []Drift{
+		{StreamID: "1", Name: "Stream 1", Fields: []string{"http_keep_active", "input"}, Reason: "Fields differ"},
+		{StreamID: "2", Name: "Stream 2", Reason: "Missing in astra"},
+		{StreamID: "4", Name: "Stream 4", Reason: "Not removed"},
+	}
+	assert.Exactly(t, expected, out, "should return drift ignoring marks and empty fields")
+	assert.Contains(t, log, `Stream in astra differs from the sent one: ID "1", name "Stream 1", `+
+		`fields "http_keep_active, input", reason "Fields differ"`)
+
+	assert.Empty(t, r.VerifyStreams(nil, actual), "should not return drift if nothing was sent")
+}
+
+func TestVerifyCategories(t *testing.T) {
+	r := newDefRepo()
+
+	sent := []lo.Entry[int, Category]{
+		{Key: 0, Value: Category{Name: "Category 1", Groups: []Group{{Name: "Group 1"}, {Name: "Group 2"}}}},
+		{Key: 1, Value: Category{Name: "Category 2", Groups: []Group{
+			{Name: "Group 1"}, {Name: "Group 2", Remove: true}, {Name: "Group 3"},
+		}}},
+		{Key: -1, Value: Category{Name: "Category 3"}},
+		{Key: 3, Value: Category{Name: "Category 4", Remove: true}},
+		{Key: 2, Value: Category{Name: "Category 5", Remove: true}},
+	}
+	actual := []Category{
+		{Name: "Category 1", Groups: []Group{{Name: "Group 2"}, {Name: "Group 1"}}},
+		{Name: "Category 2", Groups: []Group{{Name: "Group 1"}, {Name: "Group 2"}}},
+		{Name: "Category 5"},
+	}
+
+	out := r.VerifyCategories(sent, actual)
+	expected := []Drift{
+		{Name: "Category 2", Fields: []string{"Group 3", "Group 2"}, Reason: "Groups differ"},
+		{Name: "Category 3", Reason: "Missing in astra"},
+		{Name: "Category 5", Reason: "Not removed"},
+	}
+	assert.Exactly(t, expected, out, "should return drift ignoring order of groups")
+}
@@ -4,6 +4,7 @@ import (
 	_ "embed"
 	"fmt"
 	"io/fs"
+	"net/netip"
 	"os"
 	"reflect"
 	"regexp"
@@ -19,6 +20,7 @@ import (
 	"github.com/samber/lo"
 
 	"m3u_merge_astra/util/logger"
+	"m3u_merge_astra/util/network"
 	"m3u_merge_astra/util/parse"
 	"m3u_merge_astra/util/simplify"
 	yamlUtil "m3u_merge_astra/util/yaml"
@@ -76,8 +78,58 @@ type General struct {
 	// AstraAPIRespTimeout represents astra API response timeout
 	AstraAPIRespTimeout time.Duration `koanf:"astra_api_resp_timeout"`
 
+	// AstraAPIMaxConns represents maximum amount of simultaneous requests to astra API setting streams.
+	//
+	// Categories are always set one by one as astra addresses them by index.
+	AstraAPIMaxConns int `koanf:"astra_api_max_conns"`
+
+	// AstraAPIMaxRPS represents maximum amount of requests per second to astra API setting streams. Zero or negative
+	// value disables the limit.
+	AstraAPIMaxRPS float64 `koanf:"astra_api_max_rps"`
+
+	// AstraAPIMaxRetries represents maximum amount of retries of request to astra API setting stream failed with
+	// network error such as timeout or refused connection.
+	AstraAPIMaxRetries int `koanf:"astra_api_max_retries"`
+
+	// AstraAPIRetryDelay represents delay before the first retry of request to astra API. It doubles on every next
+	// retry.
+	AstraAPIRetryDelay time.Duration `koanf:"astra_api_retry_delay"`
+
 	// MergeCategories specifies if duplicated categories should be removed with unique groups combined per category
 	MergeCategories bool `koanf:"merge_categories"`
+
+	// TVGIDMatching specifies if tvg-id of M3U channels should be used to detect which M3U channel corresponds a
+	// stream before comparing names.
+	//
+	// Names are compared only if M3U channel or stream has no tvg-id.
+	TVGIDMatching bool `koanf:"tvg_id_matching"`
+
+	// TVGIDCategory represents name of the category which group in a stream is considered as tvg-id of this stream.
+	//
+	// New streams get tvg-id of M3U channel as group of this category. Empty value disables this feature.
+	//
+	// As with any other group, this category and a group for every tvg-id are created in astra by UpdateCategories
+	// method of astra package, use StreamTVGIDMap to avoid it.
+	TVGIDCategory string `koanf:"tvg_id_category"`
+
+	// StreamTVGIDMap represents stream ID to tvg-id mapping.
+	//
+	// It has a priority over TVGIDCategory.
+	//
+	// Key: Stream ID. Value: tvg-id.
+	StreamTVGIDMap map[string]string `koanf:"stream_tvg_id_map"`
+
+	// FuzzyMatching specifies if names with similarity score not less than FuzzyThreshold should be considered equal
+	// to detect which M3U channel corresponds a stream.
+	FuzzyMatching bool `koanf:"fuzzy_matching"`
+
+	// FuzzyThreshold represents minimum similarity score of names (from 0 to 1) to consider them equal
+	FuzzyThreshold float64 `koanf:"fuzzy_threshold"`
+
+	// FuzzyStripRxList represents the list of regular expressions.
+	//
+	// Every match of every expression will be removed from names before fuzzy comparsion, e.g. quality suffixes.
+	FuzzyStripRxList []regexp.Regexp `koanf:"fuzzy_strip_rx_list"`
 }
 
 // SimplifyAliases returns simplified alias list in <c>.
@@ -118,6 +170,79 @@ type M3U struct {
 	//
 	// Key: From. Value: To.
 	ChannGroupMap map[string]string `koanf:"chann_group_map"`
+
+	// Sources represents the list of M3U playlists to get channels from in addition to the one specified in command
+	// line arguments.
+	//
+	// Every source has it's own rules. Rules above apply only to the playlist specified in command line arguments.
+	Sources []M3USource `koanf:"sources"`
+}
+
+// NewSource returns M3U source with <name> and <path> and rules taken from <c>
+func (c M3U) NewSource(name, path string) M3USource {
+	return M3USource{
+		Name:                name,
+		Path:                path,
+		RespTimeout:         c.RespTimeout,
+		ChannNameBlacklist:  c.ChannNameBlacklist,
+		ChannGroupBlacklist: c.ChannGroupBlacklist,
+		ChannURLBlacklist:   c.ChannURLBlacklist,
+		ChannGroupMap:       c.ChannGroupMap,
+	}
+}
+
+// M3USource represents M3U playlist to get channels from with it's own rules
+type M3USource struct {
+	// Name represents name of the source. Every channel of the source is marked with it.
+	Name string `koanf:"name"`
+
+	// Path represents M3U playlist local file path or URL
+	Path string `koanf:"path"`
+
+	// Priority represents priority of the source.
+	//
+	// Channels of sources with higher priority come first among channels with the same name.
+	Priority int `koanf:"priority"`
+
+	// RespTimeout represents M3U playlist URL response timeout.
+	//
+	// If not set, M3U.RespTimeout is used.
+	RespTimeout time.Duration `koanf:"resp_timeout"`
+
+	// ChannNameBlacklist represens the list of regular expressions.
+	//
+	// If any expression match name of a channel, this channel will be removed from M3U input before merging.
+	ChannNameBlacklist []regexp.Regexp `koanf:"chann_name_blacklist"`
+
+	// ChannGroupBlacklist represens the list of regular expressions.
+	//
+	// If any expression match group of a channel, this channel will be removed from M3U input before merging.
+	//
+	// It runs after replacing groups by ChannGroupMap so enter the appropriate values.
+	ChannGroupBlacklist []regexp.Regexp `koanf:"chann_group_blacklist"`
+
+	// ChannURLBlacklist represens the list of regular expressions.
+	//
+	// If any expression match URL of a channel, this channel will be removed from M3U input before merging.
+	ChannURLBlacklist []regexp.Regexp `koanf:"chann_url_blacklist"`
+
+	// ChannGroupMap represents invalid to valid M3U channel group mapping.
+	//
+	// Key: From. Value: To.
+	ChannGroupMap map[string]string `koanf:"chann_group_map"`
+}
+
+// Rules returns M3U settings with rules of the source <s>.
+//
+// If response timeout of <s> is not set, <defRespTimeout> is used.
+func (s M3USource) Rules(defRespTimeout time.Duration) M3U {
+	return M3U{
+		RespTimeout:         lo.Ternary(s.RespTimeout > 0, s.RespTimeout, defRespTimeout),
+		ChannNameBlacklist:  s.ChannNameBlacklist,
+		ChannGroupBlacklist: s.ChannGroupBlacklist,
+		ChannURLBlacklist:   s.ChannURLBlacklist,
+		ChannGroupMap:       s.ChannGroupMap,
+	}
 }
 
 // Streams represents astra streams related settings of the program
@@ -244,11 +369,26 @@ type Streams struct {
 	// InputRespTimeout represents astra stream input response timeout
 	InputRespTimeout time.Duration `koanf:"input_resp_timeout"`
 
+	// CheckHLS specifies if HLS inputs should be checked by following playlists to a segment instead of checking
+	// response status only.
+	//
+	// Not used if UseAnalyzer is true.
+	CheckHLS bool `koanf:"check_hls"`
+
+	// HLSRefreshTimeout represents maximum time to wait for media playlist of HLS input to update
+	HLSRefreshTimeout time.Duration `koanf:"hls_refresh_timeout"`
+
 	// UseAnalyzer specifies if astra analyzer (astra --analyze -p <port>) should be used to check for dead inputs.
 	//
 	// Supports HTTP(S), UDP, RTP, RTSP.
 	UseAnalyzer bool `koanf:"use_analyzer"`
 
+	// UseNativeAnalyzer specifies if built-in MPEG-TS prober should be used instead of astra analyzer if UseAnalyzer
+	// is true.
+	//
+	// Supports HTTP(S), UDP, RTP.
+	UseNativeAnalyzer bool `koanf:"use_native_analyzer"`
+
 	// AnalyzerAddr represents astra analyzer address in format of 'host:port'
 	AnalyzerAddr string `koanf:"analyzer_addr"`
 
@@ -295,6 +435,59 @@ type Streams struct {
 	// Set to negative value to disable this check.
 	AnalyzerPESErrorsThreshold int `koanf:"analyzer_pes_errors_threshold"`
 
+	// AnalyzerDeadIfScrambled specifies if stream inputs should be considered dead if astra analyzer will report them
+	// as scrambled
+	AnalyzerDeadIfScrambled bool `koanf:"analyzer_dead_if_scrambled"`
+
+	// AnalyzerDeadIfNoVideo specifies if stream inputs should be considered dead if astra analyzer will not find video
+	// in them
+	AnalyzerDeadIfNoVideo bool `koanf:"analyzer_dead_if_no_video"`
+
+	// AnalyzerAudioLanguages represents list of ISO 639 language codes such as 'eng'.
+	//
+	// Audio of all of these languages is required: if astra analyzer will not find audio of any single one of them in
+	// stream input, input will be considered dead.
+	AnalyzerAudioLanguages []string `koanf:"analyzer_audio_languages"`
+
+	// AnalyzerAllowedCodecs represents list of codec names such as 'h264', 'hevc', 'mpeg2video', 'aac', 'mp2', 'ac3'.
+	//
+	// If astra analyzer will find audio or video of codec not in this list in stream input, input will be
+	// considered dead.
+	//
+	// If empty, any codec is allowed.
+	AnalyzerAllowedCodecs []string `koanf:"analyzer_allowed_codecs"`
+
+	// InputHealthFile represents path to the file to store check history of inputs of astra streams in between runs
+	// of the program.
+	//
+	// If empty, inputs are considered dead after the first failed check and the settings below are ignored.
+	InputHealthFile string `koanf:"input_health_file"`
+
+	// DeadInputFailures represents amount of consecutive failed checks after which input is considered dead
+	DeadInputFailures int `koanf:"dead_input_failures"`
+
+	// DeadInputFailureRatio represents ratio of failed checks (from 0 to 1) within DeadInputWindow after which input
+	// is considered dead.
+	//
+	// Set to 0 to disable this check.
+	DeadInputFailureRatio float64 `koanf:"dead_input_failure_ratio"`
+
+	// DeadInputWindow represents time window to calculate DeadInputFailureRatio within
+	DeadInputWindow time.Duration `koanf:"dead_input_window"`
+
+	// AliveInputSuccesses represents amount of consecutive successful checks after which dead input is considered
+	// alive again
+	AliveInputSuccesses int `koanf:"alive_input_successes"`
+
+	// InputHealthTTL represents time after which check history of input which is not checked anymore is removed
+	InputHealthTTL time.Duration `koanf:"input_health_ttl"`
+
+	// ReviveDisabledInputs specifies if inputs disabled by DisableDeadInputs should be checked again and enabled if
+	// they are alive.
+	//
+	// Requires InputHealthFile to tell inputs disabled by this program from inputs disabled by other means.
+	ReviveDisabledInputs bool `koanf:"revive_disabled_inputs"`
+
 	// InputUpdateMap represens list of regular expression pairs.
 	//
 	// If any <From> expression match URL of astra stream's input, it will be replaced with URL from according M3U
@@ -350,6 +543,54 @@ type Streams struct {
 	//
 	// Setting will be set if at least one input matches the <By> expression.
 	InputToKeepActiveMap []KeepActiveAddRule `koanf:"input_to_keep_active_map"`
+
+	// NameToOutputsMap represents mapping of stream name regular expression to outputs of stream which should be set.
+	//
+	// Only first matching rule applies per stream in the priority: By inputs -> By name -> By group.
+	NameToOutputsMap []OutputsSetRule `koanf:"name_to_outputs_map"`
+
+	// GroupToOutputsMap represents mapping of stream group regular expression to outputs of stream which should be
+	// set.
+	//
+	// Only first matching rule applies per stream in the priority: By inputs -> By name -> By group.
+	GroupToOutputsMap []OutputsSetRule `koanf:"group_to_outputs_map"`
+
+	// InputToOutputsMap represents mapping of stream input regular expression to outputs of stream which should be
+	// set.
+	//
+	// Only first matching rule applies per stream in the priority: By inputs -> By name -> By group.
+	//
+	// Outputs will be set if at least one input matches the <By> expression.
+	InputToOutputsMap []OutputsSetRule `koanf:"input_to_outputs_map"`
+
+	// OutputMulticastRange represents range of multicast addresses in CIDR notation to allocate addresses for
+	// {multicast} placeholder of outputs from. Every address of the range should be multicast.
+	OutputMulticastRange string `koanf:"output_multicast_range"`
+
+	// OutputMulticastPort represents port of multicast addresses allocated for {multicast} placeholder of outputs
+	OutputMulticastPort int `koanf:"output_multicast_port"`
+
+	// NameToNewStreamTemplateMap represents mapping of M3U channel name regular expression to template of new astra
+	// stream.
+	//
+	// Only first matching template applies per stream in the priority: By name -> By group.
+	NameToNewStreamTemplateMap []NewStreamTemplate `koanf:"name_to_new_stream_template_map"`
+
+	// GroupToNewStreamTemplateMap represents mapping of M3U channel group regular expression to template of new astra
+	// stream.
+	//
+	// Only first matching template applies per stream in the priority: By name -> By group.
+	GroupToNewStreamTemplateMap []NewStreamTemplate `koanf:"group_to_new_stream_template_map"`
+
+	// Rules represents list of generic stream rules.
+	//
+	// Rules run in order at the ApplyRulesStep of Pipeline, every matching rule applies.
+	Rules []Rule `koanf:"rules"`
+
+	// Pipeline represents steps of streams processing to run in order (see PipelineSteps).
+	//
+	// If empty, steps enabled by other settings run in default order.
+	Pipeline []PipelineStep `koanf:"pipeline"`
 }
 
 // UpdateRecord represents astra stream input update rule
@@ -370,6 +611,75 @@ type KeepActiveAddRule struct {
 	KeepActive int           `koanf:"keep_active"`
 }
 
+// OutputsSetRule represents astra stream outputs setting rule.
+//
+// Outputs can contain placeholders: {id}, {name}, {group} and {multicast}.
+type OutputsSetRule struct {
+	By      regexp.Regexp `koanf:"by"`
+	Outputs []string      `koanf:"outputs"`
+	Replace bool          `koanf:"replace"` // Replace all outputs of stream instead of adding missing ones?
+}
+
+// NewStreamTemplate represents template of new astra stream.
+//
+// Stream holds any fields of astra stream config (including unknown to the program) to set on new stream, except ID,
+// name and inputs.
+type NewStreamTemplate struct {
+	By     regexp.Regexp  `koanf:"by"`
+	Stream map[string]any `koanf:"stream"`
+}
+
+// Rule represents generic astra stream rule: if stream matches <Match>, <Actions> are performed on it in order
+type Rule struct {
+	Name    string       `koanf:"name"` // Name of the rule to display in logs
+	Match   RuleMatch    `koanf:"match"`
+	Actions []RuleAction `koanf:"actions"`
+}
+
+// RuleMatch represents condition of stream rule.
+//
+// Stream matches if every set field matches, so empty RuleMatch matches every stream.
+type RuleMatch struct {
+	Name    *regexp.Regexp           `koanf:"name"`
+	Group   *regexp.Regexp           `koanf:"group"` // Matches if any "category: group" pair of stream matches
+	Input   *regexp.Regexp           `koanf:"input"` // Matches if any input of stream matches
+	ID      *regexp.Regexp           `koanf:"id"`
+	M3U     map[string]regexp.Regexp `koanf:"m3u"` // Attributes of M3U channel corresponding to stream
+	Enabled *bool                    `koanf:"enabled"`
+	All     []RuleMatch              `koanf:"all"` // Matches if every condition matches
+	Any     []RuleMatch              `koanf:"any"` // Matches if at least one condition matches
+	Not     *RuleMatch               `koanf:"not"` // Matches if condition does not match
+}
+
+// RuleAction represents action of stream rule.
+//
+// Exactly one field should be set.
+type RuleAction struct {
+	AddHash       string         `koanf:"add_hash"` // Add hash to every input
+	SetKeepActive *int           `koanf:"set_keep_active"`
+	Enable        *bool          `koanf:"enable"` // Enable (true) or disable (false) stream
+	SetType       StreamType     `koanf:"set_type"`
+	AddGroup      string         `koanf:"add_group"`    // "Category: Group" pair to add or replace group of category
+	RemoveInput   *regexp.Regexp `koanf:"remove_input"` // Remove every matching input
+	SetField      map[string]any `koanf:"set_field"`    // Astra stream config fields to set
+}
+
+// Validate returns error if not exactly one field of action is set or value of field is invalid
+func (a RuleAction) Validate() error {
+	set := lo.Filter([]bool{a.AddHash != "", a.SetKeepActive != nil, a.Enable != nil, a.SetType != "",
+		a.AddGroup != "", a.RemoveInput != nil, len(a.SetField) > 0}, func(set bool, _ int) bool { return set })
+	if len(set) != 1 {
+		return errors.Newf("Expecting exactly one action per list item, got %v", len(set))
+	}
+	if a.SetType != "" && a.SetType != SPTS && a.SetType != MPTS {
+		return errors.Newf("Stream type %q is not one of %v, %v", a.SetType, SPTS, MPTS)
+	}
+	if a.AddGroup != "" && len(strings.SplitN(a.AddGroup, ": ", 2)) != 2 {
+		return errors.Newf("Group %q is not in the form of 'Category: Group'", a.AddGroup)
+	}
+	return nil
+}
+
 // StreamType represents astra stream type
 type StreamType string
 
@@ -491,8 +801,46 @@ func Init(log *logger.Logger, cfgFilePath string) (Root, bool, error) {
 		/* 22 */ "streams.analyzer_max_attempts",
 		/* 23 */ "streams.disable_all_but_one_input_by_rx_list",
 		/* 24 */ "streams.remove_disabled_inputs",
+		/* 25 */ "m3u.sources",
+		/* 26 */ "general.tvg_id_matching",
+		/* 27 */ "general.tvg_id_category",
+		/* 28 */ "general.stream_tvg_id_map",
+		/* 29 */ "general.fuzzy_matching",
+		/* 30 */ "general.fuzzy_threshold",
+		/* 31 */ "general.fuzzy_strip_rx_list",
+		/* 32 */ "general.astra_api_max_conns",
+		/* 33 */ "general.astra_api_max_rps",
+		/* 34 */ "general.astra_api_max_retries",
+		/* 35 */ "general.astra_api_retry_delay",
+		/* 36 */ "streams.name_to_outputs_map",
+		/* 37 */ "streams.group_to_outputs_map",
+		/* 38 */ "streams.input_to_outputs_map",
+		/* 39 */ "streams.output_multicast_range",
+		/* 40 */ "streams.output_multicast_port",
+		/* 41 */ "streams.name_to_new_stream_template_map",
+		/* 42 */ "streams.group_to_new_stream_template_map",
+		/* 43 */ "streams.rules",
+		/* 44 */ "streams.pipeline",
+		/* 45 */ "streams.input_health_file",
+		/* 46 */ "streams.dead_input_failures",
+		/* 47 */ "streams.dead_input_failure_ratio",
+		/* 48 */ "streams.dead_input_window",
+		/* 49 */ "streams.alive_input_successes",
+		/* 50 */ "streams.input_health_ttl",
+		/* 51 */ "streams.revive_disabled_inputs",
+		/* 52 */ "streams.check_hls",
+		/* 53 */ "streams.hls_refresh_timeout",
+		/* 54 */ "streams.use_native_analyzer",
+		/* 55 */ "streams.analyzer_dead_if_scrambled",
+		/* 56 */ "streams.analyzer_dead_if_no_video",
+		/* 57 */ "streams.analyzer_audio_languages",
+		/* 58 */ "streams.analyzer_allowed_codecs",
 	}
 	missingFields, _ := lo.Difference(metadata.Unset, knownFields)
+	// Fields of list items are optional
+	missingFields = lo.Reject(missingFields, func(field string, _ int) bool {
+		return strings.Contains(field, "[")
+	})
 	internalFields := []string{
 		"general.SimpleNameAliasList",
 	}
@@ -1030,47 +1378,845 @@ func Init(log *logger.Logger, cfgFilePath string) (Root, bool, error) {
 		}
 		root.Streams.RemoveDisabledInputs = defVal
 	}
-
-	// Validate amount of capture groups
-	for _, rx := range root.Streams.RemoveDuplicatedInputsByRxList {
-		if rx.NumSubexp() < 1 {
-			msg := "Expecting at least one capture group"
-			return root, false, errors.Wrap(BadRegexpError{Regexp: rx, Reason: msg}, "Validate config")
+	// v2.2.0 to v2.3.0
+	knownField = knownFields[25]
+	if lo.Contains(metadata.Unset, knownField) {
+		defVal := defCfg.M3U.Sources
+		log.Infof("Adding missing field to config: %v: %v", knownField, defVal)
+		node := yamlUtil.Node{
+			StartNewline: true,
+			HeadComment: []string{
+				"List of M3U playlists to get channels from in addition to the one specified in command line " +
+					"arguments.",
+				"Every source has it's own rules ('resp_timeout', 'chann_name_blacklist', 'chann_group_blacklist',",
+				"'chann_url_blacklist', 'chann_group_map'), rules above apply only to the command line one.",
+				"Channels of sources with higher 'priority' come first among channels with the same name.",
+			},
+			Data: yamlUtil.Sequence{
+				Key: parse.LastPathItem(knownField, "."),
+				Sets: [][]yamlUtil.Pair{
+					{
+						{Key: "name", Value: "'Provider 1'", Commented: true},
+						{Key: "path", Value: "'http://provider_1.com/playlist.m3u8'", Commented: true},
+						{Key: "priority", Value: "10", Commented: true},
+					},
+					{
+						{Key: "name", Value: "'Provider 2'", Commented: true},
+						{Key: "path", Value: "'/home/user/provider_2.m3u'", Commented: true},
+						{Key: "resp_timeout", Value: "'30s'", Commented: true},
+					},
+				},
+			},
+		}
+		if cfgBytes, err = yamlUtil.Insert(cfgBytes, "m3u.chann_group_map", true, node); err != nil {
+			return root, false, errors.Wrap(err, "Add missing field to config")
 		}
+		root.M3U.Sources = defVal
 	}
-
-	// Write modified config
-	if err = os.WriteFile(cfgFilePath, cfgBytes, 0644); err != nil {
-		return root, false, errors.Wrap(err, "Write modified config")
+	// v2.2.0 to v2.3.0
+	knownField = knownFields[26]
+	if lo.Contains(metadata.Unset, knownField) {
+		defVal := defCfg.General.TVGIDMatching
+		log.Infof("Adding missing field to config: %v: %v", knownField, defVal)
+		node := yamlUtil.Node{
+			StartNewline: true,
+			HeadComment: []string{
+				"Use tvg-id of M3U channels to detect which M3U channel corresponds a stream before comparing names?",
+				"Names are compared only if M3U channel or stream has no tvg-id.",
+			},
+			Data: yamlUtil.Scalar{Key: parse.LastPathItem(knownField, "."), Value: strconv.FormatBool(defVal)},
+		}
+		if cfgBytes, err = yamlUtil.Insert(cfgBytes, "general.merge_categories", false, node); err != nil {
+			return root, false, errors.Wrap(err, "Add missing field to config")
+		}
+		root.General.TVGIDMatching = defVal
 	}
-
-	// Build simple aliases list
-	if root.General.NameAliases {
-		root.General.SimpleNameAliasList = root.General.SimplifyAliases()
+	// v2.2.0 to v2.3.0
+	knownField = knownFields[27]
+	if lo.Contains(metadata.Unset, knownField) {
+		defVal := defCfg.General.TVGIDCategory
+		log.Infof("Adding missing field to config: %v: %v", knownField, defVal)
+		node := yamlUtil.Node{
+			StartNewline: true,
+			HeadComment: []string{
+				"Name of the category which group in a stream is considered as tvg-id of this stream.",
+				"New streams get tvg-id of M3U channel as group of this category. Empty value disables this feature.",
+				"As with any other group, this category and a group for every tvg-id are created in astra, use",
+				"'stream_tvg_id_map' to avoid it.",
+			},
+			Data: yamlUtil.Scalar{Key: parse.LastPathItem(knownField, "."), Value: fmt.Sprintf("'%v'", defVal)},
+		}
+		if cfgBytes, err = yamlUtil.Insert(cfgBytes, "general.tvg_id_matching", false, node); err != nil {
+			return root, false, errors.Wrap(err, "Add missing field to config")
+		}
+		root.General.TVGIDCategory = defVal
 	}
-
-	return root, false, nil
-}
-
-// DefFullTranslitMap returns default full transliteration map.
-//
-// Used in tests.
-func DefFullTranslitMap() map[string]string {
-	return map[string]string{
-		"а": "a", "б": "b", "в": "v", "г": "g", "д": "d", "е": "e", "ё": "yo", "ж": "zh", "з": "z", "и": "i",
-		"й": "j", "к": "k", "л": "l", "м": "m", "н": "n", "о": "o", "п": "p", "р": "r", "с": "s", "т": "t",
-		"у": "u", "ф": "f", "х": "h", "ц": "c", "ч": "ch", "ш": "sh", "щ": "shh", "ъ": "", "ы": "y", "ь": "",
-		"э": "eh", "ю": "yu", "я": "ya",
+	// v2.2.0 to v2.3.0
+	knownField = knownFields[28]
+	if lo.Contains(metadata.Unset, knownField) {
+		defVal := defCfg.General.StreamTVGIDMap
+		log.Infof("Adding missing field to config: %v: %v", knownField, defVal)
+		node := yamlUtil.Node{
+			StartNewline: true,
+			HeadComment: []string{
+				"Stream ID to tvg-id mapping. Has a priority over 'tvg_id_category'.",
+				"Key: Stream ID. Value: tvg-id.",
+			},
+			Data: yamlUtil.Map{
+				Key: parse.LastPathItem(knownField, "."),
+				Map: map[string]yamlUtil.Value{
+					"'a1b2'": {Value: "'discovery.us'", Commented: true},
+				},
+			},
+		}
+		if cfgBytes, err = yamlUtil.Insert(cfgBytes, "general.tvg_id_category", false, node); err != nil {
+			return root, false, errors.Wrap(err, "Add missing field to config")
+		}
+		root.General.StreamTVGIDMap = defVal
 	}
-}
-
-// DefSimilarTranslitMap returns default similar transliteration map.
-//
-// Used in tests.
-func DefSimilarTranslitMap() map[string]string {
-	return map[string]string{
-		"а": "a", "б": "6", "в": "b", "е": "e", "з": "3", "к": "k", "м": "m", "н": "h", "о": "o", "р": "p",
-		"с": "c", "т": "t", "у": "y", "х": "x",
+	// v2.2.0 to v2.3.0
+	knownField = knownFields[29]
+	if lo.Contains(metadata.Unset, knownField) {
+		defVal := defCfg.General.FuzzyMatching
+		log.Infof("Adding missing field to config: %v: %v", knownField, defVal)
+		node := yamlUtil.Node{
+			HeadComment: []string{
+				"Use fuzzy name matching to detect which M3U channel corresponds a stream?",
+				"Names with similarity score not less than 'fuzzy_threshold' will be considered equal.",
+			},
+			Data: yamlUtil.Scalar{Key: parse.LastPathItem(knownField, "."), Value: strconv.FormatBool(defVal)},
+		}
+		if cfgBytes, err = yamlUtil.Insert(cfgBytes, "general.stream_tvg_id_map", true, node); err != nil {
+			return root, false, errors.Wrap(err, "Add missing field to config")
+		}
+		root.General.FuzzyMatching = defVal
+	}
+	// v2.2.0 to v2.3.0
+	knownField = knownFields[30]
+	if lo.Contains(metadata.Unset, knownField) {
+		defVal := defCfg.General.FuzzyThreshold
+		log.Infof("Adding missing field to config: %v: %v", knownField, defVal)
+		node := yamlUtil.Node{
+			StartNewline: true,
+			HeadComment:  []string{"Minimum similarity score of names (from 0 to 1) to consider them equal."},
+			Data: yamlUtil.Scalar{
+				Key:   parse.LastPathItem(knownField, "."),
+				Value: strconv.FormatFloat(defVal, 'f', -1, 64),
+			},
+		}
+		if cfgBytes, err = yamlUtil.Insert(cfgBytes, "general.fuzzy_matching", false, node); err != nil {
+			return root, false, errors.Wrap(err, "Add missing field to config")
+		}
+		root.General.FuzzyThreshold = defVal
+	}
+	// v2.2.0 to v2.3.0
+	knownField = knownFields[31]
+	if lo.Contains(metadata.Unset, knownField) {
+		defVal := defCfg.General.FuzzyStripRxList
+		log.Infof("Adding missing field to config: %v: %v", knownField, defVal)
+		node := yamlUtil.Node{
+			StartNewline: true,
+			HeadComment: []string{
+				"List of regular expressions.",
+				"Every match of every expression will be removed from names before fuzzy comparsion.",
+			},
+			Data: yamlUtil.List{
+				Key: parse.LastPathItem(knownField, "."),
+				Values: lo.Map(defVal, func(rx regexp.Regexp, _ int) yamlUtil.Value {
+					return yamlUtil.Value{Value: fmt.Sprintf("'%v'", rx.String())}
+				}),
+			},
+			EndNewline: true,
+		}
+		if cfgBytes, err = yamlUtil.Insert(cfgBytes, "general.fuzzy_threshold", false, node); err != nil {
+			return root, false, errors.Wrap(err, "Add missing field to config")
+		}
+		root.General.FuzzyStripRxList = defVal
+	}
+	// v2.2.0 to v2.3.0
+	knownField = knownFields[32]
+	if lo.Contains(metadata.Unset, knownField) {
+		defVal := defCfg.General.AstraAPIMaxConns
+		log.Infof("Adding missing field to config: %v: %v", knownField, defVal)
+		node := yamlUtil.Node{
+			StartNewline: true,
+			HeadComment: []string{
+				"Maximum amount of simultaneous requests to astra API setting streams.",
+				"Categories are always set one by one as astra addresses them by index.",
+			},
+			Data: yamlUtil.Scalar{Key: parse.LastPathItem(knownField, "."), Value: strconv.Itoa(defVal)},
+		}
+		if cfgBytes, err = yamlUtil.Insert(cfgBytes, "general.astra_api_resp_timeout", false, node); err != nil {
+			return root, false, errors.Wrap(err, "Add missing field to config")
+		}
+		root.General.AstraAPIMaxConns = defVal
+	}
+	// v2.2.0 to v2.3.0
+	knownField = knownFields[33]
+	if lo.Contains(metadata.Unset, knownField) {
+		defVal := defCfg.General.AstraAPIMaxRPS
+		log.Infof("Adding missing field to config: %v: %v", knownField, defVal)
+		node := yamlUtil.Node{
+			StartNewline: true,
+			HeadComment: []string{
+				"Maximum amount of requests per second to astra API setting streams. Set to 0 to disable the limit.",
+			},
+			Data: yamlUtil.Scalar{
+				Key:   parse.LastPathItem(knownField, "."),
+				Value: strconv.FormatFloat(defVal, 'f', -1, 64),
+			},
+		}
+		if cfgBytes, err = yamlUtil.Insert(cfgBytes, "general.astra_api_max_conns", false, node); err != nil {
+			return root, false, errors.Wrap(err, "Add missing field to config")
+		}
+		root.General.AstraAPIMaxRPS = defVal
+	}
+	// v2.2.0 to v2.3.0
+	knownField = knownFields[34]
+	if lo.Contains(metadata.Unset, knownField) {
+		defVal := defCfg.General.AstraAPIMaxRetries
+		log.Infof("Adding missing field to config: %v: %v", knownField, defVal)
+		node := yamlUtil.Node{
+			StartNewline: true,
+			HeadComment: []string{
+				"Maximum amount of retries of request to astra API setting stream failed with network error such as",
+				"timeout or refused connection.",
+			},
+			Data: yamlUtil.Scalar{Key: parse.LastPathItem(knownField, "."), Value: strconv.Itoa(defVal)},
+		}
+		if cfgBytes, err = yamlUtil.Insert(cfgBytes, "general.astra_api_max_rps", false, node); err != nil {
+			return root, false, errors.Wrap(err, "Add missing field to config")
+		}
+		root.General.AstraAPIMaxRetries = defVal
+	}
+	// v2.2.0 to v2.3.0
+	knownField = knownFields[35]
+	if lo.Contains(metadata.Unset, knownField) {
+		defVal := defCfg.General.AstraAPIRetryDelay
+		log.Infof("Adding missing field to config: %v: %v", knownField, defVal)
+		node := yamlUtil.Node{
+			StartNewline: true,
+			HeadComment: []string{
+				"Delay before the first retry of request to astra API. Doubles on every next retry.",
+			},
+			Data: yamlUtil.Scalar{Key: parse.LastPathItem(knownField, "."), Value: fmt.Sprintf("'%v'", defVal)},
+		}
+		if cfgBytes, err = yamlUtil.Insert(cfgBytes, "general.astra_api_max_retries", false, node); err != nil {
+			return root, false, errors.Wrap(err, "Add missing field to config")
+		}
+		root.General.AstraAPIRetryDelay = defVal
+	}
+	// v2.2.0 to v2.3.0
+	knownField = knownFields[36]
+	if lo.Contains(metadata.Unset, knownField) {
+		defVal := defCfg.Streams.NameToOutputsMap
+		log.Infof("Adding missing field to config: %v: %v", knownField, defVal)
+		node := yamlUtil.Node{
+			StartNewline: true,
+			HeadComment: []string{
+				"Mapping of stream name regular expression to outputs of stream which should be set.",
+				"Outputs can contain placeholders: {id}, {name}, {group} and {multicast}.",
+				"If 'replace' is true, all outputs of stream are replaced, otherwise only missing outputs are added.",
+				"",
+				"Only first matching rule applies per stream in the priority: By inputs -> By name -> By group.",
+			},
+			Data: yamlUtil.Sequence{
+				Key: parse.LastPathItem(knownField, "."),
+				Sets: [][]yamlUtil.Pair{
+					{
+						{Key: "by", Value: "'.*'", Commented: true},
+						{Key: "outputs", Value: "['http://#/play/{id}']", Commented: true},
+						{Key: "replace", Value: "false", Commented: true},
+					},
+				},
+			},
+		}
+		if cfgBytes, err = yamlUtil.Insert(cfgBytes, "streams.input_to_keep_active_map", true, node); err != nil {
+			return root, false, errors.Wrap(err, "Add missing field to config")
+		}
+		root.Streams.NameToOutputsMap = defVal
+	}
+	// v2.2.0 to v2.3.0
+	knownField = knownFields[37]
+	if lo.Contains(metadata.Unset, knownField) {
+		defVal := defCfg.Streams.GroupToOutputsMap
+		log.Infof("Adding missing field to config: %v: %v", knownField, defVal)
+		node := yamlUtil.Node{
+			StartNewline: true,
+			HeadComment: []string{
+				"Mapping of stream group regular expression to outputs of stream which should be set.",
+				"",
+				"Only first matching rule applies per stream in the priority: By inputs -> By name -> By group.",
+			},
+			Data: yamlUtil.Sequence{
+				Key: parse.LastPathItem(knownField, "."),
+				Sets: [][]yamlUtil.Pair{
+					{
+						{Key: "by", Value: "'(?i)All: HD Channels$'", Commented: true},
+						{Key: "outputs", Value: "['http://#/play/{id}', 'udp://eth1@{multicast}']", Commented: true},
+						{Key: "replace", Value: "false", Commented: true},
+					},
+				},
+			},
+		}
+		if cfgBytes, err = yamlUtil.Insert(cfgBytes, "streams.name_to_outputs_map", true, node); err != nil {
+			return root, false, errors.Wrap(err, "Add missing field to config")
+		}
+		root.Streams.GroupToOutputsMap = defVal
+	}
+	// v2.2.0 to v2.3.0
+	knownField = knownFields[38]
+	if lo.Contains(metadata.Unset, knownField) {
+		defVal := defCfg.Streams.InputToOutputsMap
+		log.Infof("Adding missing field to config: %v: %v", knownField, defVal)
+		node := yamlUtil.Node{
+			StartNewline: true,
+			HeadComment: []string{
+				"Mapping of stream input regular expression to outputs of stream which should be set.",
+				"",
+				"Only first matching rule applies per stream in the priority: By inputs -> By name -> By group.",
+				"",
+				"Outputs will be set if at least one input matches the 'by' expression.",
+			},
+			Data: yamlUtil.Sequence{
+				Key: parse.LastPathItem(knownField, "."),
+				Sets: [][]yamlUtil.Pair{
+					{
+						{Key: "by", Value: `'^rts?p:\/\/'`, Commented: true},
+						{Key: "outputs", Value: "['udp://{multicast}']", Commented: true},
+						{Key: "replace", Value: "true", Commented: true},
+					},
+				},
+			},
+		}
+		if cfgBytes, err = yamlUtil.Insert(cfgBytes, "streams.group_to_outputs_map", true, node); err != nil {
+			return root, false, errors.Wrap(err, "Add missing field to config")
+		}
+		root.Streams.InputToOutputsMap = defVal
+	}
+	// v2.2.0 to v2.3.0
+	knownField = knownFields[39]
+	if lo.Contains(metadata.Unset, knownField) {
+		defVal := defCfg.Streams.OutputMulticastRange
+		log.Infof("Adding missing field to config: %v: %v", knownField, defVal)
+		node := yamlUtil.Node{
+			StartNewline: true,
+			HeadComment: []string{
+				"Range of multicast addresses in CIDR notation to allocate addresses for {multicast} placeholder of",
+				"outputs from. Addresses used by outputs of any stream are skipped.",
+			},
+			Data: yamlUtil.Scalar{Key: parse.LastPathItem(knownField, "."), Value: fmt.Sprintf("'%v'", defVal)},
+		}
+		if cfgBytes, err = yamlUtil.Insert(cfgBytes, "streams.input_to_outputs_map", true, node); err != nil {
+			return root, false, errors.Wrap(err, "Add missing field to config")
+		}
+		root.Streams.OutputMulticastRange = defVal
+	}
+	// v2.2.0 to v2.3.0
+	knownField = knownFields[40]
+	if lo.Contains(metadata.Unset, knownField) {
+		defVal := defCfg.Streams.OutputMulticastPort
+		log.Infof("Adding missing field to config: %v: %v", knownField, defVal)
+		node := yamlUtil.Node{
+			StartNewline: true,
+			HeadComment:  []string{"Port of multicast addresses allocated for {multicast} placeholder of outputs."},
+			Data:         yamlUtil.Scalar{Key: parse.LastPathItem(knownField, "."), Value: strconv.Itoa(defVal)},
+		}
+		if cfgBytes, err = yamlUtil.Insert(cfgBytes, "streams.output_multicast_range", false, node); err != nil {
+			return root, false, errors.Wrap(err, "Add missing field to config")
+		}
+		root.Streams.OutputMulticastPort = defVal
+	}
+	// v2.2.0 to v2.3.0
+	knownField = knownFields[41]
+	if lo.Contains(metadata.Unset, knownField) {
+		defVal := defCfg.Streams.NameToNewStreamTemplateMap
+		log.Infof("Adding missing field to config: %v: %v", knownField, defVal)
+		node := yamlUtil.Node{
+			StartNewline: true,
+			HeadComment: []string{
+				"Mapping of M3U channel name regular expression to template of new stream.",
+				"Template can contain any fields of astra stream config except 'id', 'name' and 'input'.",
+				"Groups of template are added to the group of new stream.",
+				"",
+				"Only first matching template applies per stream in the priority: By name -> By group.",
+			},
+			Data: yamlUtil.Sequence{
+				Key: parse.LastPathItem(knownField, "."),
+				Sets: [][]yamlUtil.Pair{
+					{
+						{Key: "by", Value: "'(?i)radio'", Commented: true},
+						{Key: "stream", Value: "{type: 'spts', enable: true, timeout: 30}", Commented: true},
+					},
+				},
+			},
+		}
+		if cfgBytes, err = yamlUtil.Insert(cfgBytes, "streams.output_multicast_port", false, node); err != nil {
+			return root, false, errors.Wrap(err, "Add missing field to config")
+		}
+		root.Streams.NameToNewStreamTemplateMap = defVal
+	}
+	// v2.2.0 to v2.3.0
+	knownField = knownFields[42]
+	if lo.Contains(metadata.Unset, knownField) {
+		defVal := defCfg.Streams.GroupToNewStreamTemplateMap
+		log.Infof("Adding missing field to config: %v: %v", knownField, defVal)
+		node := yamlUtil.Node{
+			StartNewline: true,
+			HeadComment: []string{
+				"Mapping of M3U channel group regular expression to template of new stream.",
+				"",
+				"Only first matching template applies per stream in the priority: By name -> By group.",
+			},
+			Data: yamlUtil.Sequence{
+				Key: parse.LastPathItem(knownField, "."),
+				Sets: [][]yamlUtil.Pair{
+					{
+						{Key: "by", Value: "'(?i)^HD'", Commented: true},
+						{
+							Key:       "stream",
+							Value:     "{output: ['http://#/play/{id}'], backup_type: 'active', groups: {Quality: 'HD'}}",
+							Commented: true,
+						},
+					},
+				},
+			},
+		}
+		cfgBytes, err = yamlUtil.Insert(cfgBytes, "streams.name_to_new_stream_template_map", true, node)
+		if err != nil {
+			return root, false, errors.Wrap(err, "Add missing field to config")
+		}
+		root.Streams.GroupToNewStreamTemplateMap = defVal
+	}
+	// v2.2.0 to v2.3.0
+	knownField = knownFields[43]
+	if lo.Contains(metadata.Unset, knownField) {
+		defVal := defCfg.Streams.Rules
+		log.Infof("Adding missing field to config: %v: %v", knownField, defVal)
+		node := yamlUtil.Node{
+			StartNewline: true,
+			HeadComment: []string{
+				"List of generic stream rules. Rules run in order at the 'apply_rules' step of 'pipeline', every matching",
+				"rule applies. See README for the list of matchers and actions.",
+			},
+			Data: yamlUtil.Sequence{
+				Key: parse.LastPathItem(knownField, "."),
+				Sets: [][]yamlUtil.Pair{
+					{
+						{Key: "name", Value: "'Disable radio'", Commented: true},
+						{Key: "match", Value: "{group: '(?i)radio', not: {id: '^a0'}}", Commented: true},
+						{Key: "actions", Value: "[{enable: false}, {set_keep_active: 0}]", Commented: true},
+					},
+				},
+			},
+		}
+		cfgBytes, err = yamlUtil.Insert(cfgBytes, "streams.group_to_new_stream_template_map", true, node)
+		if err != nil {
+			return root, false, errors.Wrap(err, "Add missing field to config")
+		}
+		root.Streams.Rules = defVal
+	}
+	// v2.2.0 to v2.3.0
+	knownField = knownFields[44]
+	if lo.Contains(metadata.Unset, knownField) {
+		defVal := defCfg.Streams.Pipeline
+		log.Infof("Adding missing field to config: %v: %v", knownField, defVal)
+		node := yamlUtil.Node{
+			StartNewline: true,
+			HeadComment: []string{
+				"Steps of streams processing to run in order. If set, only listed steps run regardless of settings",
+				"enabling them. If empty, steps enabled by other settings run in default order:",
+				"'rename', 'remove_blocked_inputs', 'remove_duplicated_inputs', 'remove_duplicated_inputs_by_rx',",
+				"'remove_disabled_inputs', 'update_inputs', 'remove_inputs_by_update_map', 'add_new_inputs',",
+				"'unite_inputs', 'sort_inputs', 'add_new', 'revive_disabled_inputs', 'remove_dead_inputs',",
+				"'disable_dead_inputs', 'add_hashes', 'disable_all_but_one_input_by_rx', 'set_keep_active',",
+				"'set_outputs', 'apply_rules', 'remove_without_inputs', 'disable_without_inputs'.",
+				"",
+				"Steps 'remove_dead_inputs' and 'disable_dead_inputs', 'remove_without_inputs' and",
+				"'disable_without_inputs' are mutually exclusive.",
+			},
+			Data: yamlUtil.List{
+				Key: parse.LastPathItem(knownField, "."),
+				Values: []yamlUtil.Value{
+					{Value: "'update_inputs'", Commented: true},
+					{Value: "'add_new_inputs'", Commented: true},
+					{Value: "'add_hashes'", Commented: true},
+					{Value: "'sort_inputs'", Commented: true},
+				},
+			},
+		}
+		if cfgBytes, err = yamlUtil.Insert(cfgBytes, "streams.rules", true, node); err != nil {
+			return root, false, errors.Wrap(err, "Add missing field to config")
+		}
+		root.Streams.Pipeline = defVal
+	}
+	// v2.2.0 to v2.3.0
+	knownField = knownFields[45]
+	if lo.Contains(metadata.Unset, knownField) {
+		defVal := defCfg.Streams.InputHealthFile
+		log.Infof("Adding missing field to config: %v: %v", knownField, defVal)
+		node := yamlUtil.Node{
+			StartNewline: true,
+			HeadComment: []string{
+				"Path to the file to store check history of inputs in between runs of the program.",
+				"If empty, inputs are considered dead after the first failed check and the settings below are",
+				"ignored.",
+			},
+			Data: yamlUtil.Scalar{Key: parse.LastPathItem(knownField, "."), Value: fmt.Sprintf("'%v'", defVal)},
+		}
+		if cfgBytes, err = yamlUtil.Insert(cfgBytes, "streams.analyzer_pes_errors_threshold", false, node); err != nil {
+			return root, false, errors.Wrap(err, "Add missing field to config")
+		}
+		root.Streams.InputHealthFile = defVal
+	}
+	// v2.2.0 to v2.3.0
+	knownField = knownFields[46]
+	if lo.Contains(metadata.Unset, knownField) {
+		defVal := defCfg.Streams.DeadInputFailures
+		log.Infof("Adding missing field to config: %v: %v", knownField, defVal)
+		node := yamlUtil.Node{
+			StartNewline: true,
+			HeadComment:  []string{"Amount of consecutive failed checks after which input is considered dead."},
+			Data:         yamlUtil.Scalar{Key: parse.LastPathItem(knownField, "."), Value: strconv.Itoa(defVal)},
+		}
+		if cfgBytes, err = yamlUtil.Insert(cfgBytes, "streams.input_health_file", false, node); err != nil {
+			return root, false, errors.Wrap(err, "Add missing field to config")
+		}
+		root.Streams.DeadInputFailures = defVal
+	}
+	// v2.2.0 to v2.3.0
+	knownField = knownFields[47]
+	if lo.Contains(metadata.Unset, knownField) {
+		defVal := defCfg.Streams.DeadInputFailureRatio
+		log.Infof("Adding missing field to config: %v: %v", knownField, defVal)
+		node := yamlUtil.Node{
+			StartNewline: true,
+			HeadComment: []string{
+				"Ratio of failed checks (from 0 to 1) within 'dead_input_window' after which input is considered dead.",
+				"Set to 0 to disable this check.",
+			},
+			Data: yamlUtil.Scalar{
+				Key:   parse.LastPathItem(knownField, "."),
+				Value: strconv.FormatFloat(defVal, 'f', -1, 64),
+			},
+		}
+		if cfgBytes, err = yamlUtil.Insert(cfgBytes, "streams.dead_input_failures", false, node); err != nil {
+			return root, false, errors.Wrap(err, "Add missing field to config")
+		}
+		root.Streams.DeadInputFailureRatio = defVal
+	}
+	// v2.2.0 to v2.3.0
+	knownField = knownFields[48]
+	if lo.Contains(metadata.Unset, knownField) {
+		defVal := defCfg.Streams.DeadInputWindow
+		log.Infof("Adding missing field to config: %v: %v", knownField, defVal)
+		node := yamlUtil.Node{
+			StartNewline: true,
+			HeadComment:  []string{"Time window to calculate 'dead_input_failure_ratio' within."},
+			Data: yamlUtil.Scalar{
+				Key:   parse.LastPathItem(knownField, "."),
+				Value: fmt.Sprintf("'%vh'", defVal.Hours()),
+			},
+		}
+		if cfgBytes, err = yamlUtil.Insert(cfgBytes, "streams.dead_input_failure_ratio", false, node); err != nil {
+			return root, false, errors.Wrap(err, "Add missing field to config")
+		}
+		root.Streams.DeadInputWindow = defVal
+	}
+	// v2.2.0 to v2.3.0
+	knownField = knownFields[49]
+	if lo.Contains(metadata.Unset, knownField) {
+		defVal := defCfg.Streams.AliveInputSuccesses
+		log.Infof("Adding missing field to config: %v: %v", knownField, defVal)
+		node := yamlUtil.Node{
+			StartNewline: true,
+			HeadComment: []string{
+				"Amount of consecutive successful checks after which dead input is considered alive again.",
+			},
+			Data: yamlUtil.Scalar{Key: parse.LastPathItem(knownField, "."), Value: strconv.Itoa(defVal)},
+		}
+		if cfgBytes, err = yamlUtil.Insert(cfgBytes, "streams.dead_input_window", false, node); err != nil {
+			return root, false, errors.Wrap(err, "Add missing field to config")
+		}
+		root.Streams.AliveInputSuccesses = defVal
+	}
+	// v2.2.0 to v2.3.0
+	knownField = knownFields[50]
+	if lo.Contains(metadata.Unset, knownField) {
+		defVal := defCfg.Streams.InputHealthTTL
+		log.Infof("Adding missing field to config: %v: %v", knownField, defVal)
+		node := yamlUtil.Node{
+			StartNewline: true,
+			HeadComment:  []string{"Time after which check history of input which is not checked anymore is removed."},
+			Data: yamlUtil.Scalar{
+				Key:   parse.LastPathItem(knownField, "."),
+				Value: fmt.Sprintf("'%vh'", defVal.Hours()),
+			},
+		}
+		if cfgBytes, err = yamlUtil.Insert(cfgBytes, "streams.alive_input_successes", false, node); err != nil {
+			return root, false, errors.Wrap(err, "Add missing field to config")
+		}
+		root.Streams.InputHealthTTL = defVal
+	}
+	// v2.2.0 to v2.3.0
+	knownField = knownFields[51]
+	if lo.Contains(metadata.Unset, knownField) {
+		defVal := defCfg.Streams.ReviveDisabledInputs
+		log.Infof("Adding missing field to config: %v: %v", knownField, defVal)
+		node := yamlUtil.Node{
+			StartNewline: true,
+			HeadComment: []string{
+				"Check inputs disabled by 'disable_dead_inputs' again and enable ones which are alive?",
+				"Requires 'input_health_file' to tell inputs disabled by this program from inputs disabled",
+				"by other means.",
+			},
+			Data: yamlUtil.Scalar{Key: parse.LastPathItem(knownField, "."), Value: strconv.FormatBool(defVal)},
+		}
+		if cfgBytes, err = yamlUtil.Insert(cfgBytes, "streams.input_health_ttl", false, node); err != nil {
+			return root, false, errors.Wrap(err, "Add missing field to config")
+		}
+		root.Streams.ReviveDisabledInputs = defVal
+	}
+	// v2.2.0 to v2.3.0
+	knownField = knownFields[52]
+	if lo.Contains(metadata.Unset, knownField) {
+		defVal := defCfg.Streams.CheckHLS
+		log.Infof("Adding missing field to config: %v: %v", knownField, defVal)
+		node := yamlUtil.Node{
+			StartNewline: true,
+			HeadComment: []string{
+				"Check HLS inputs by following playlists to a segment instead of checking response status only?",
+				"Input is considered alive if it's media playlist updates and it's last segment is MPEG-TS or fMP4.",
+				"Not used if 'use_analyzer' is enabled.",
+			},
+			Data: yamlUtil.Scalar{Key: parse.LastPathItem(knownField, "."), Value: strconv.FormatBool(defVal)},
+		}
+		if cfgBytes, err = yamlUtil.Insert(cfgBytes, "streams.input_resp_timeout", false, node); err != nil {
+			return root, false, errors.Wrap(err, "Add missing field to config")
+		}
+		root.Streams.CheckHLS = defVal
+	}
+	// v2.2.0 to v2.3.0
+	knownField = knownFields[53]
+	if lo.Contains(metadata.Unset, knownField) {
+		defVal := defCfg.Streams.HLSRefreshTimeout
+		log.Infof("Adding missing field to config: %v: %v", knownField, defVal)
+		node := yamlUtil.Node{
+			StartNewline: true,
+			HeadComment:  []string{"Maximum time to wait for media playlist of HLS input to update."},
+			Data:         yamlUtil.Scalar{Key: parse.LastPathItem(knownField, "."), Value: fmt.Sprintf("'%v'", defVal)},
+		}
+		if cfgBytes, err = yamlUtil.Insert(cfgBytes, "streams.check_hls", false, node); err != nil {
+			return root, false, errors.Wrap(err, "Add missing field to config")
+		}
+		root.Streams.HLSRefreshTimeout = defVal
+	}
+	// v2.2.0 to v2.3.0
+	knownField = knownFields[54]
+	if lo.Contains(metadata.Unset, knownField) {
+		defVal := defCfg.Streams.UseNativeAnalyzer
+		log.Infof("Adding missing field to config: %v: %v", knownField, defVal)
+		node := yamlUtil.Node{
+			StartNewline: true,
+			HeadComment: []string{
+				"Use built-in MPEG-TS prober instead of astra analyzer if 'use_analyzer' is enabled?",
+				"It does not require running astra analyzer, 'analyzer_addr' is not used then.",
+				"",
+				"Supports HTTP(S), UDP, RTP.",
+			},
+			Data: yamlUtil.Scalar{Key: parse.LastPathItem(knownField, "."), Value: strconv.FormatBool(defVal)},
+		}
+		if cfgBytes, err = yamlUtil.Insert(cfgBytes, "streams.use_analyzer", false, node); err != nil {
+			return root, false, errors.Wrap(err, "Add missing field to config")
+		}
+		root.Streams.UseNativeAnalyzer = defVal
+	}
+	// v2.2.0 to v2.3.0
+	knownField = knownFields[55]
+	if lo.Contains(metadata.Unset, knownField) {
+		defVal := defCfg.Streams.AnalyzerDeadIfScrambled
+		log.Infof("Adding missing field to config: %v: %v", knownField, defVal)
+		node := yamlUtil.Node{
+			StartNewline: true,
+			HeadComment:  []string{"Consider stream inputs reported as scrambled by astra analyzer dead?"},
+			Data:         yamlUtil.Scalar{Key: parse.LastPathItem(knownField, "."), Value: strconv.FormatBool(defVal)},
+		}
+		if cfgBytes, err = yamlUtil.Insert(cfgBytes, "streams.analyzer_pes_errors_threshold", false, node); err != nil {
+			return root, false, errors.Wrap(err, "Add missing field to config")
+		}
+		root.Streams.AnalyzerDeadIfScrambled = defVal
+	}
+	// v2.2.0 to v2.3.0
+	knownField = knownFields[56]
+	if lo.Contains(metadata.Unset, knownField) {
+		defVal := defCfg.Streams.AnalyzerDeadIfNoVideo
+		log.Infof("Adding missing field to config: %v: %v", knownField, defVal)
+		node := yamlUtil.Node{
+			StartNewline: true,
+			HeadComment:  []string{"Consider stream inputs without video found by astra analyzer dead?"},
+			Data:         yamlUtil.Scalar{Key: parse.LastPathItem(knownField, "."), Value: strconv.FormatBool(defVal)},
+		}
+		if cfgBytes, err = yamlUtil.Insert(cfgBytes, "streams.analyzer_dead_if_scrambled", false, node); err != nil {
+			return root, false, errors.Wrap(err, "Add missing field to config")
+		}
+		root.Streams.AnalyzerDeadIfNoVideo = defVal
+	}
+	// v2.2.0 to v2.3.0
+	knownField = knownFields[57]
+	if lo.Contains(metadata.Unset, knownField) {
+		defVal := defCfg.Streams.AnalyzerAudioLanguages
+		log.Infof("Adding missing field to config: %v: %v", knownField, defVal)
+		node := yamlUtil.Node{
+			StartNewline: true,
+			HeadComment: []string{
+				"List of ISO 639 language codes.",
+				"Audio of all of these languages is required: if astra analyzer will not find audio of any single one",
+				"of them in stream input, input will be considered dead.",
+			},
+			Data: yamlUtil.List{
+				Key:    parse.LastPathItem(knownField, "."),
+				Values: []yamlUtil.Value{{Value: "'eng'", Commented: true}},
+			},
+		}
+		if cfgBytes, err = yamlUtil.Insert(cfgBytes, "streams.analyzer_dead_if_no_video", false, node); err != nil {
+			return root, false, errors.Wrap(err, "Add missing field to config")
+		}
+		root.Streams.AnalyzerAudioLanguages = defVal
+	}
+	// v2.2.0 to v2.3.0
+	knownField = knownFields[58]
+	if lo.Contains(metadata.Unset, knownField) {
+		defVal := defCfg.Streams.AnalyzerAllowedCodecs
+		log.Infof("Adding missing field to config: %v: %v", knownField, defVal)
+		node := yamlUtil.Node{
+			HeadComment: []string{
+				"List of allowed codecs such as 'h264', 'hevc', 'mpeg2video', 'aac', 'mp2', 'ac3', 'eac3'.",
+				"If astra analyzer will find audio or video of codec not in this list in stream input, input will be",
+				"considered dead.",
+				"If empty, any codec is allowed.",
+			},
+			Data: yamlUtil.List{
+				Key: parse.LastPathItem(knownField, "."),
+				Values: []yamlUtil.Value{
+					{Value: "'h264'", Commented: true},
+					{Value: "'aac'", Commented: true},
+				},
+			},
+			EndNewline: true,
+		}
+		if cfgBytes, err = yamlUtil.Insert(cfgBytes, "streams.analyzer_audio_languages", true, node); err != nil {
+			return root, false, errors.Wrap(err, "Add missing field to config")
+		}
+		root.Streams.AnalyzerAllowedCodecs = defVal
+	}
+
+	// Validate amount of capture groups
+	for _, rx := range root.Streams.RemoveDuplicatedInputsByRxList {
+		if rx.NumSubexp() < 1 {
+			msg := "Expecting at least one capture group"
+			return root, false, errors.Wrap(BadRegexpError{Regexp: rx, Reason: msg}, "Validate config")
+		}
+	}
+
+	// Validate fuzzy matching settings
+	if threshold := root.General.FuzzyThreshold; threshold < 0 || threshold > 1 {
+		err := errors.Newf("Fuzzy threshold %v is not in range from 0 to 1", threshold)
+		return root, false, errors.Wrap(err, "Validate config")
+	}
+
+	// Validate M3U sources
+	for idx, src := range root.M3U.Sources {
+		if src.Name == "" || src.Path == "" {
+			err := errors.Newf("M3U source #%v should have name and path", idx+1)
+			return root, false, errors.Wrap(err, "Validate config")
+		}
+		if lo.CountBy(root.M3U.Sources, func(s M3USource) bool { return s.Name == src.Name }) > 1 {
+			err := errors.Newf("M3U source name %q is not unique", src.Name)
+			return root, false, errors.Wrap(err, "Validate config")
+		}
+	}
+
+	// Validate health of inputs settings
+	if root.Streams.DeadInputFailures < 1 || root.Streams.AliveInputSuccesses < 1 {
+		err := errors.New("Dead input failures and alive input successes should be at least 1")
+		return root, false, errors.Wrap(err, "Validate config")
+	}
+	if ratio := root.Streams.DeadInputFailureRatio; ratio < 0 || ratio > 1 {
+		err := errors.Newf("Dead input failure ratio %v is not in range from 0 to 1", ratio)
+		return root, false, errors.Wrap(err, "Validate config")
+	}
+
+	// Validate pipeline
+	if err := validatePipeline(root.Streams.Pipeline); err != nil {
+		return root, false, errors.Wrap(err, "Validate config")
+	}
+	for _, pair := range root.Streams.ignoredSteps() {
+		log.WarnFi("Mutually exclusive settings are both enabled, only the first one is used", "first", pair[0],
+			"ignored", pair[1])
+	}
+	if lo.Contains(root.Streams.Steps(), ReviveDisabledInputsStep) && root.Streams.InputHealthFile == "" {
+		err := errors.New("Reviving disabled inputs requires input health file to be set")
+		return root, false, errors.Wrap(err, "Validate config")
+	}
+
+	// Validate actions of stream rules
+	for idx, rule := range root.Streams.Rules {
+		for _, action := range rule.Actions {
+			if err := action.Validate(); err != nil {
+				err := errors.Wrapf(err, "Stream rule #%v %q", idx+1, rule.Name)
+				return root, false, errors.Wrap(err, "Validate config")
+			}
+		}
+	}
+
+	// Validate multicast range of outputs
+	prefix, err := netip.ParsePrefix(root.Streams.OutputMulticastRange)
+	if err != nil || !network.IsMulticastPrefix(prefix) {
+		err := errors.Newf("Output multicast range %q is not a multicast range in CIDR notation",
+			root.Streams.OutputMulticastRange)
+		return root, false, errors.Wrap(err, "Validate config")
+	}
+
+	// Write modified config
+	if err = os.WriteFile(cfgFilePath, cfgBytes, 0644); err != nil {
+		return root, false, errors.Wrap(err, "Write modified config")
+	}
+
+	// Build simple aliases list
+	if root.General.NameAliases {
+		root.General.SimpleNameAliasList = root.General.SimplifyAliases()
+	}
+
+	return root, false, nil
+}
+
+// DefFullTranslitMap returns default full transliteration map.
+//
+// Used in tests.
+func DefFullTranslitMap() map[string]string {
+	return map[string]string{
+		"а": "a", "б": "b", "в": "v", "г": "g", "д": "d", "е": "e", "ё": "yo", "ж": "zh", "з": "z", "и": "i",
+		"й": "j", "к": "k", "л": "l", "м": "m", "н": "n", "о": "o", "п": "p", "р": "r", "с": "s", "т": "t",
+		"у": "u", "ф": "f", "х": "h", "ц": "c", "ч": "ch", "ш": "sh", "щ": "shh", "ъ": "", "ы": "y", "ь": "",
+		"э": "eh", "ю": "yu", "я": "ya",
+	}
+}
+
+// DefSimilarTranslitMap returns default similar transliteration map.
+//
+// Used in tests.
+func DefSimilarTranslitMap() map[string]string {
+	return map[string]string{
+		"а": "a", "б": "6", "в": "b", "е": "e", "з": "3", "к": "k", "м": "m", "н": "h", "о": "o", "р": "p",
+		"с": "c", "т": "t", "у": "y", "х": "x",
+	}
+}
+
+// DefFuzzyStripRxList returns default list of expressions to remove from names before fuzzy comparsion
+func DefFuzzyStripRxList() []regexp.Regexp {
+	return []regexp.Regexp{
+		*regexp.MustCompile(`(?i)[ _-]*\b(hd|fhd|uhd|sd|4k|8k|orig)\b`),
+		*regexp.MustCompile(`[ _-]*\(?\+\d+\)?$`),
 	}
 }
 
@@ -1086,7 +2232,17 @@ func NewDefCfg() Root {
 			NameAliasList:       [][]string(nil),
 			SimpleNameAliasList: [][]string(nil),
 			AstraAPIRespTimeout: time.Second * 10,
+			AstraAPIMaxConns:    4,
+			AstraAPIMaxRPS:      20,
+			AstraAPIMaxRetries:  3,
+			AstraAPIRetryDelay:  time.Second,
 			MergeCategories:     false,
+			TVGIDMatching:       true,
+			TVGIDCategory:       "",
+			StreamTVGIDMap:      map[string]string(nil),
+			FuzzyMatching:       false,
+			FuzzyThreshold:      0.92,
+			FuzzyStripRxList:    DefFuzzyStripRxList(),
 		},
 		M3U: M3U{
 			RespTimeout:         time.Second * 10,
@@ -1094,6 +2250,7 @@ func NewDefCfg() Root {
 			ChannGroupBlacklist: []regexp.Regexp(nil),
 			ChannURLBlacklist:   []regexp.Regexp(nil),
 			ChannGroupMap:       map[string]string(nil),
+			Sources:             []M3USource(nil),
 		},
 		Streams: Streams{
 			AddedPrefix:                       "_ADDED: ",
@@ -1125,7 +2282,10 @@ func NewDefCfg() Root {
 			DeadInputsCheckBlacklist:          []regexp.Regexp(nil),
 			InputMaxConns:                     1,
 			InputRespTimeout:                  time.Second * 10,
+			CheckHLS:                          false,
+			HLSRefreshTimeout:                 time.Second * 30,
 			UseAnalyzer:                       false,
+			UseNativeAnalyzer:                 false,
 			AnalyzerAddr:                      "127.0.0.1:8001",
 			AnalyzerWatchTime:                 time.Second * 20,
 			AnalyzerMaxAttempts:               3,
@@ -1135,6 +2295,17 @@ func NewDefCfg() Root {
 			AnalyzerCCErrorsThreshold:         -1,
 			AnalyzerPCRErrorsThreshold:        -1,
 			AnalyzerPESErrorsThreshold:        -1,
+			AnalyzerDeadIfScrambled:           false,
+			AnalyzerDeadIfNoVideo:             false,
+			AnalyzerAudioLanguages:            []string(nil),
+			AnalyzerAllowedCodecs:             []string(nil),
+			InputHealthFile:                   "",
+			DeadInputFailures:                 1,
+			DeadInputFailureRatio:             0,
+			DeadInputWindow:                   time.Hour * 24,
+			AliveInputSuccesses:               1,
+			InputHealthTTL:                    time.Hour * 24 * 30,
+			ReviveDisabledInputs:              false,
 			InputUpdateMap:                    []UpdateRecord(nil),
 			UpdateInputs:                      false,
 			KeepInputHash:                     true,
@@ -1145,6 +2316,15 @@ func NewDefCfg() Root {
 			NameToKeepActiveMap:               []KeepActiveAddRule(nil),
 			GroupToKeepActiveMap:              []KeepActiveAddRule(nil),
 			InputToKeepActiveMap:              []KeepActiveAddRule(nil),
+			NameToOutputsMap:                  []OutputsSetRule(nil),
+			GroupToOutputsMap:                 []OutputsSetRule(nil),
+			InputToOutputsMap:                 []OutputsSetRule(nil),
+			OutputMulticastRange:              "239.255.0.0/16",
+			OutputMulticastPort:               1234,
+			NameToNewStreamTemplateMap:        []NewStreamTemplate(nil),
+			GroupToNewStreamTemplateMap:       []NewStreamTemplate(nil),
+			Rules:                             []Rule(nil),
+			Pipeline:                          []PipelineStep(nil),
 		},
 	}
 }
@@ -7,10 +7,12 @@ import (
 	"os"
 	"path/filepath"
 	"regexp"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/cockroachdb/errors"
+	"github.com/samber/lo"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -142,6 +144,284 @@ func TestInitValidateCaptureGroups(t *testing.T) {
 	assert.Exactly(t, expectedErr, errors.UnwrapAll(err), "should return bad regexp error")
 }
 
+func TestInitFuzzyThreshold(t *testing.T) {
+	log := logger.New(logger.DebugLevel)
+
+	path := filepath.Join(t.TempDir(), "m3u_merge_astra_init_test.yaml")
+
+	for _, threshold := range []string{"-0.1", "1.5"} {
+		cfgStr := strings.Replace(string(defCfgBytes), "  fuzzy_threshold: 0.92\n",
+			"  fuzzy_threshold: "+threshold+"\n", 1)
+		err := os.WriteFile(path, []byte(cfgStr), 0644)
+		assert.NoError(t, err, "should write config bytes")
+
+		_, _, err = Init(log, path)
+		assert.ErrorContains(t, err, "Validate config: Fuzzy threshold "+threshold+" is not in range from 0 to 1",
+			"should reject threshold "+threshold)
+	}
+}
+
+func TestInitSources(t *testing.T) {
+	log := logger.New(logger.DebugLevel)
+
+	path := filepath.Join(t.TempDir(), "m3u_merge_astra_init_test.yaml")
+
+	// Test reading config with M3U sources
+	err := file.Copy("init_sources_test.yaml", path)
+	assert.NoError(t, err, "should copy and overwrite previous test file")
+
+	actual, isNewCfg, err := Init(log, path)
+
+	expected := []M3USource{
+		{
+			Name:                "Provider 1",
+			Path:                "http://provider_1.com/playlist.m3u8",
+			Priority:            10,
+			ChannNameBlacklist:  []regexp.Regexp{*regexp.MustCompile(`Nonsense TV`)},
+			ChannGroupBlacklist: []regexp.Regexp(nil),
+			ChannURLBlacklist:   []regexp.Regexp(nil),
+			ChannGroupMap:       map[string]string{"For kids": "Kids"},
+		},
+		{
+			Name:                "Provider 2",
+			Path:                "/home/user/provider_2.m3u",
+			RespTimeout:         time.Second * 30,
+			ChannNameBlacklist:  []regexp.Regexp(nil),
+			ChannGroupBlacklist: []regexp.Regexp(nil),
+			ChannURLBlacklist:   []regexp.Regexp(nil),
+			ChannGroupMap:       map[string]string(nil),
+		},
+	}
+
+	assert.Exactly(t, expected, actual.M3U.Sources, "actual config should contain these sources")
+	assert.False(t, isNewCfg, "should return false")
+	assert.NoError(t, err, "should not return error")
+
+	// Test reading config with duplicated source names
+	cfgBytes, err := os.ReadFile("init_sources_test.yaml")
+	assert.NoError(t, err, "should read config bytes")
+	cfgBytes = []byte(strings.Replace(string(cfgBytes), "'Provider 2'", "'Provider 1'", 1))
+	err = os.WriteFile(path, cfgBytes, 0644)
+	assert.NoError(t, err, "should write config bytes")
+
+	_, _, err = Init(log, path)
+	assert.ErrorContains(t, err, `M3U source name "Provider 1" is not unique`, "should return error")
+
+	// Test reading config with source without path
+	cfgBytes, err = os.ReadFile("init_sources_test.yaml")
+	assert.NoError(t, err, "should read config bytes")
+	cfgBytes = []byte(strings.Replace(string(cfgBytes), "'/home/user/provider_2.m3u'", "''", 1))
+	err = os.WriteFile(path, cfgBytes, 0644)
+	assert.NoError(t, err, "should write config bytes")
+
+	_, _, err = Init(log, path)
+	assert.ErrorContains(t, err, "M3U source #2 should have name and path", "should return error")
+}
+
+func TestInitOutputs(t *testing.T) {
+	log := logger.New(logger.DebugLevel)
+
+	path := filepath.Join(t.TempDir(), "m3u_merge_astra_init_test.yaml")
+
+	// Test reading config with output rules
+	cfgStr := strings.Replace(string(defCfgBytes), "    # - by: '.*'\n"+
+		"    #   outputs: ['http://#/play/{id}']\n"+
+		"    #   replace: false\n", "    - by: '.*'\n"+
+		"      outputs: ['http://#/play/{id}', 'udp://{multicast}']\n"+
+		"      replace: true\n", 1)
+	err := os.WriteFile(path, []byte(cfgStr), 0644)
+	assert.NoError(t, err, "should write config bytes")
+
+	actual, _, err := Init(log, path)
+	assert.NoError(t, err, "should not return error")
+	expected := []OutputsSetRule{
+		{By: *regexp.MustCompile(`.*`), Outputs: []string{"http://#/play/{id}", "udp://{multicast}"}, Replace: true},
+	}
+	assert.Exactly(t, expected, actual.Streams.NameToOutputsMap, "actual config should contain these rules")
+
+	// Test reading config with invalid multicast range
+	for _, rng := range []string{"'10.0.0.0/8'", "'239.255.0.0'", "''", "'239.0.0.0/1'", "'224.0.0.0/3'"} {
+		cfgStr := strings.Replace(string(defCfgBytes), "'239.255.0.0/16'", rng, 1)
+		err = os.WriteFile(path, []byte(cfgStr), 0644)
+		assert.NoError(t, err, "should write config bytes")
+
+		_, _, err = Init(log, path)
+		assert.ErrorContains(t, err, "is not a multicast range in CIDR notation", "should return error for "+rng)
+	}
+}
+
+func TestInitNewStreamTemplates(t *testing.T) {
+	log := logger.New(logger.DebugLevel)
+
+	path := filepath.Join(t.TempDir(), "m3u_merge_astra_init_test.yaml")
+
+	cfgStr := strings.Replace(string(defCfgBytes), "    # - by: '(?i)^HD'\n"+
+		"    #   stream:\n"+
+		"    #     output: ['http://#/play/{id}']\n"+
+		"    #     backup_type: 'active'\n"+
+		"    #     groups:\n"+
+		"    #       Quality: 'HD'\n", "    - by: '(?i)^HD'\n"+
+		"      stream:\n"+
+		"        output: ['http://#/play/{id}']\n"+
+		"        backup_type: 'active'\n"+
+		"        timeout: 30\n"+
+		"        groups:\n"+
+		"          Quality: 'HD'\n", 1)
+	err := os.WriteFile(path, []byte(cfgStr), 0644)
+	assert.NoError(t, err, "should write config bytes")
+
+	actual, _, err := Init(log, path)
+	assert.NoError(t, err, "should not return error")
+	expected := []NewStreamTemplate{
+		{
+			By: *regexp.MustCompile(`(?i)^HD`),
+			Stream: map[string]any{
+				"output":      []any{"http://#/play/{id}"},
+				"backup_type": "active",
+				"timeout":     30,
+				"groups":      map[string]any{"Quality": "HD"},
+			},
+		},
+	}
+	assert.Exactly(t, expected, actual.Streams.GroupToNewStreamTemplateMap, "actual config should contain templates")
+}
+
+func TestInitRules(t *testing.T) {
+	log := logger.New(logger.DebugLevel)
+
+	path := filepath.Join(t.TempDir(), "m3u_merge_astra_init_test.yaml")
+
+	rules := "  rules:\n" +
+		"    - name: 'Disable radio'\n" +
+		"      match:\n" +
+		"        group: '(?i)radio'\n" +
+		"        m3u:\n" +
+		"          tvg-id: '\\.fm$'\n" +
+		"        not:\n" +
+		"          id: '^a0'\n" +
+		"      actions:\n" +
+		"        - enable: false\n" +
+		"        - set_keep_active: 0\n" +
+		"        - set_field: {timeout: 30}\n"
+	cfgStr := strings.Replace(string(defCfgBytes), "  rules:\n", rules, 1)
+	err := os.WriteFile(path, []byte(cfgStr), 0644)
+	assert.NoError(t, err, "should write config bytes")
+
+	actual, _, err := Init(log, path)
+	assert.NoError(t, err, "should not return error")
+	expected := []Rule{
+		{
+			Name: "Disable radio",
+			Match: RuleMatch{
+				Group: regexp.MustCompile(`(?i)radio`),
+				M3U:   map[string]regexp.Regexp{"tvg-id": *regexp.MustCompile(`\.fm$`)},
+				Not:   &RuleMatch{ID: regexp.MustCompile(`^a0`)},
+			},
+			Actions: []RuleAction{
+				{Enable: lo.ToPtr(false)},
+				{SetKeepActive: lo.ToPtr(0)},
+				{SetField: map[string]any{"timeout": 30}},
+			},
+		},
+	}
+	assert.Exactly(t, expected, actual.Streams.Rules, "actual config should contain these rules")
+
+	// Test reading config with invalid actions
+	for action, msg := range map[string]string{
+		"{}":                            "Expecting exactly one action per list item, got 0",
+		"{enable: true, add_hash: 'a'}": "Expecting exactly one action per list item, got 2",
+		"{set_type: 'dvb'}":             `Stream type "dvb" is not one of spts, mpts`,
+		"{add_group: 'Group'}":          `Group "Group" is not in the form of 'Category: Group'`,
+	} {
+		cfgStr := strings.Replace(string(defCfgBytes), "  rules:\n", "  rules:\n    - name: 'Bad'\n"+
+			"      actions: ["+action+"]\n", 1)
+		err = os.WriteFile(path, []byte(cfgStr), 0644)
+		assert.NoError(t, err, "should write config bytes")
+
+		_, _, err = Init(log, path)
+		assert.ErrorContains(t, err, `Validate config: Stream rule #1 "Bad": `+msg, "should return error for "+action)
+	}
+}
+
+func TestM3USourceRules(t *testing.T) {
+	src := M3USource{
+		Name:                "Provider 1",
+		Path:                "http://provider_1.com/playlist.m3u8",
+		Priority:            10,
+		ChannNameBlacklist:  []regexp.Regexp{*regexp.MustCompile(`Nonsense TV`)},
+		ChannGroupBlacklist: []regexp.Regexp{*regexp.MustCompile(`18\+`)},
+		ChannURLBlacklist:   []regexp.Regexp{*regexp.MustCompile(`filter_me`)},
+		ChannGroupMap:       map[string]string{"For kids": "Kids"},
+	}
+
+	expected := M3U{
+		RespTimeout:         time.Second * 10,
+		ChannNameBlacklist:  src.ChannNameBlacklist,
+		ChannGroupBlacklist: src.ChannGroupBlacklist,
+		ChannURLBlacklist:   src.ChannURLBlacklist,
+		ChannGroupMap:       src.ChannGroupMap,
+	}
+	assert.Exactly(t, expected, src.Rules(time.Second*10), "should use default response timeout if not set")
+
+	src.RespTimeout = time.Second * 30
+	expected.RespTimeout = time.Second * 30
+	assert.Exactly(t, expected, src.Rules(time.Second*10), "should use response timeout of the source")
+
+	// Test building source from M3U settings
+	expectedSrc := src
+	expectedSrc.Priority = 0
+	assert.Exactly(t, expectedSrc, expected.NewSource("Provider 1", "http://provider_1.com/playlist.m3u8"))
+}
+
+func TestInitInputHealth(t *testing.T) {
+	log := logger.New(logger.DebugLevel)
+
+	path := filepath.Join(t.TempDir(), "m3u_merge_astra_init_test.yaml")
+
+	cfgStr := strings.Replace(string(defCfgBytes), "  dead_input_failures: 1\n", "  dead_input_failures: 3\n", 1)
+	cfgStr = strings.Replace(cfgStr, "  dead_input_failure_ratio: 0\n", "  dead_input_failure_ratio: 0.5\n", 1)
+	cfgStr = strings.Replace(cfgStr, "  dead_input_window: '24h'\n", "  dead_input_window: '6h'\n", 1)
+	err := os.WriteFile(path, []byte(cfgStr), 0644)
+	assert.NoError(t, err, "should write config bytes")
+
+	actual, _, err := Init(log, path)
+	assert.NoError(t, err, "should not return error")
+	assert.Exactly(t, 3, actual.Streams.DeadInputFailures, "should read amount of failures")
+	assert.Exactly(t, 0.5, actual.Streams.DeadInputFailureRatio, "should read failure ratio")
+	assert.Exactly(t, time.Hour*6, actual.Streams.DeadInputWindow, "should read window")
+
+	cfgStr = strings.Replace(string(defCfgBytes), "  dead_input_failures: 1\n", "  dead_input_failures: 0\n", 1)
+	err = os.WriteFile(path, []byte(cfgStr), 0644)
+	assert.NoError(t, err, "should write config bytes")
+
+	_, _, err = Init(log, path)
+	assert.ErrorContains(t, err, "Validate config: Dead input failures and alive input successes should be at least 1")
+
+	cfgStr = strings.Replace(string(defCfgBytes), "  dead_input_failure_ratio: 0\n",
+		"  dead_input_failure_ratio: 1.5\n", 1)
+	err = os.WriteFile(path, []byte(cfgStr), 0644)
+	assert.NoError(t, err, "should write config bytes")
+
+	_, _, err = Init(log, path)
+	assert.ErrorContains(t, err, "Validate config: Dead input failure ratio 1.5 is not in range from 0 to 1")
+
+	cfgStr = strings.Replace(string(defCfgBytes), "  revive_disabled_inputs: false\n",
+		"  revive_disabled_inputs: true\n", 1)
+	err = os.WriteFile(path, []byte(cfgStr), 0644)
+	assert.NoError(t, err, "should write config bytes")
+
+	_, _, err = Init(log, path)
+	assert.ErrorContains(t, err, "Validate config: Reviving disabled inputs requires input health file to be set")
+
+	cfgStr = strings.Replace(cfgStr, "  input_health_file: ''\n", "  input_health_file: 'health.json'\n", 1)
+	err = os.WriteFile(path, []byte(cfgStr), 0644)
+	assert.NoError(t, err, "should write config bytes")
+
+	actual, _, err = Init(log, path)
+	assert.NoError(t, err, "should not return error")
+	assert.True(t, actual.Streams.ReviveDisabledInputs, "should enable reviving disabled inputs")
+}
+
 func TestInitSimplifyAliases(t *testing.T) {
 	log := logger.New(logger.DebugLevel)
 
@@ -170,11 +450,21 @@ func newTestConfig() Root {
 			FullTranslitMap:     map[string]string{"ş": "ш", "\\n": ""},
 			SimilarTranslit:     false,
 			SimilarTranslitMap:  map[string]string(nil),
-			NameAliases:         true,             // New field in v1.3.0
-			NameAliasList:       [][]string(nil),  // New field in v1.3.0
-			SimpleNameAliasList: [][]string(nil),  // Field for internal use
-			AstraAPIRespTimeout: time.Second * 10, // New field in v2.0.0
-			MergeCategories:     false,            // New field in v2.0.0
+			NameAliases:         true,                   // New field in v1.3.0
+			NameAliasList:       [][]string(nil),        // New field in v1.3.0
+			SimpleNameAliasList: [][]string(nil),        // Field for internal use
+			AstraAPIRespTimeout: time.Second * 10,       // New field in v2.0.0
+			AstraAPIMaxConns:    4,                      // New field in v2.3.0
+			AstraAPIMaxRPS:      20,                     // New field in v2.3.0
+			AstraAPIMaxRetries:  3,                      // New field in v2.3.0
+			AstraAPIRetryDelay:  time.Second,            // New field in v2.3.0
+			MergeCategories:     false,                  // New field in v2.0.0
+			TVGIDMatching:       true,                   // New field in v2.3.0
+			TVGIDCategory:       "",                     // New field in v2.3.0
+			StreamTVGIDMap:      map[string]string(nil), // New field in v2.3.0
+			FuzzyMatching:       false,                  // New field in v2.3.0
+			FuzzyThreshold:      0.92,                   // New field in v2.3.0
+			FuzzyStripRxList:    DefFuzzyStripRxList(),  // New field in v2.3.0
 		},
 		M3U: M3U{
 			RespTimeout:         time.Second * 10,
@@ -185,6 +475,7 @@ func newTestConfig() Root {
 				*regexp.MustCompile(`192\.168\.88\.14\/play`),
 			},
 			ChannGroupMap: map[string]string{"": "General", "-": "General", "For kids": "Kids"},
+			Sources:       []M3USource(nil), // New field in v2.3.0
 		},
 		Streams: Streams{
 			AddedPrefix:             "",
@@ -225,7 +516,10 @@ func newTestConfig() Root {
 			},
 			InputMaxConns:                     10,
 			InputRespTimeout:                  time.Minute,
+			CheckHLS:                          false,            // New field in v2.3.0
+			HLSRefreshTimeout:                 time.Second * 30, // New field in v2.3.0
 			UseAnalyzer:                       false,            // New field in v1.5.0
+			UseNativeAnalyzer:                 false,            // New field in v2.3.0
 			AnalyzerAddr:                      "127.0.0.1:8001", // New field in v1.5.0
 			AnalyzerWatchTime:                 time.Second * 20, // New field in v1.5.0
 			AnalyzerMaxAttempts:               3,                // New field in v2.0.0
@@ -235,6 +529,17 @@ func newTestConfig() Root {
 			AnalyzerCCErrorsThreshold:         -1,               // New field in v1.5.0
 			AnalyzerPCRErrorsThreshold:        -1,               // New field in v1.5.0
 			AnalyzerPESErrorsThreshold:        -1,               // New field in v1.5.0
+			AnalyzerDeadIfScrambled:           false,            // New field in v2.3.0
+			AnalyzerDeadIfNoVideo:             false,            // New field in v2.3.0
+			AnalyzerAudioLanguages:            []string(nil),    // New field in v2.3.0
+			AnalyzerAllowedCodecs:             []string(nil),    // New field in v2.3.0
+			InputHealthFile:                   "",               // New field in v2.3.0
+			DeadInputFailures:                 1,                // New field in v2.3.0
+			DeadInputFailureRatio:             0,                // New field in v2.3.0
+			DeadInputWindow:                   time.Hour * 24,   // New field in v2.3.0
+			AliveInputSuccesses:               1,                // New field in v2.3.0
+			InputHealthTTL:                    time.Hour * 720,  // New field in v2.3.0
+			ReviveDisabledInputs:              false,            // New field in v2.3.0
 			InputUpdateMap: []UpdateRecord{
 				{From: *regexp.MustCompile(`127\.0\.0\.1`), To: *regexp.MustCompile(`127\.0\.0\.1`)},
 				{From: *regexp.MustCompile(`some_url\.com`), To: *regexp.MustCompile(`some_url\.com`)},
@@ -254,9 +559,18 @@ func newTestConfig() Root {
 				{By: *regexp.MustCompile(`:8080`), Hash: "ua=VLC/3.0.9 LibVLC/3.0.9"},
 				{By: *regexp.MustCompile(`^rts?p:\/\/`), Hash: "no_reload"},
 			},
-			NameToKeepActiveMap:  []KeepActiveAddRule(nil), // New field in v1.4.0
-			GroupToKeepActiveMap: []KeepActiveAddRule(nil), // New field in v1.4.0
-			InputToKeepActiveMap: []KeepActiveAddRule(nil), // New field in v1.4.0
+			NameToKeepActiveMap:         []KeepActiveAddRule(nil), // New field in v1.4.0
+			GroupToKeepActiveMap:        []KeepActiveAddRule(nil), // New field in v1.4.0
+			InputToKeepActiveMap:        []KeepActiveAddRule(nil), // New field in v1.4.0
+			NameToOutputsMap:            []OutputsSetRule(nil),    // New field in v2.3.0
+			GroupToOutputsMap:           []OutputsSetRule(nil),    // New field in v2.3.0
+			InputToOutputsMap:           []OutputsSetRule(nil),    // New field in v2.3.0
+			OutputMulticastRange:        "239.255.0.0/16",         // New field in v2.3.0
+			OutputMulticastPort:         1234,                     // New field in v2.3.0
+			NameToNewStreamTemplateMap:  []NewStreamTemplate(nil), // New field in v2.3.0
+			GroupToNewStreamTemplateMap: []NewStreamTemplate(nil), // New field in v2.3.0
+			Rules:                       []Rule(nil),              // New field in v2.3.0
+			Pipeline:                    []PipelineStep(nil),      // New field in v2.3.0
 		},
 	}
 }
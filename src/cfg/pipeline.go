@@ -0,0 +1,140 @@
+package cfg
+
+import (
+	"m3u_merge_astra/util/slice"
+
+	"github.com/cockroachdb/errors"
+	"github.com/samber/lo"
+)
+
+// PipelineStep represents step of astra streams processing
+type PipelineStep string
+
+const (
+	RenameStep                     PipelineStep = "rename"
+	RemoveBlockedInputsStep        PipelineStep = "remove_blocked_inputs"
+	RemoveDuplicatedInputsStep     PipelineStep = "remove_duplicated_inputs"
+	RemoveDuplicatedInputsByRxStep PipelineStep = "remove_duplicated_inputs_by_rx"
+	RemoveDisabledInputsStep       PipelineStep = "remove_disabled_inputs"
+	UpdateInputsStep               PipelineStep = "update_inputs"
+	RemoveInputsByUpdateMapStep    PipelineStep = "remove_inputs_by_update_map"
+	AddNewInputsStep               PipelineStep = "add_new_inputs"
+	UniteInputsStep                PipelineStep = "unite_inputs"
+	SortInputsStep                 PipelineStep = "sort_inputs"
+	AddNewStep                     PipelineStep = "add_new"
+	ReviveDisabledInputsStep       PipelineStep = "revive_disabled_inputs"
+	RemoveDeadInputsStep           PipelineStep = "remove_dead_inputs"
+	DisableDeadInputsStep          PipelineStep = "disable_dead_inputs"
+	AddHashesStep                  PipelineStep = "add_hashes"
+	DisableAllButOneInputByRxStep  PipelineStep = "disable_all_but_one_input_by_rx"
+	SetKeepActiveStep              PipelineStep = "set_keep_active"
+	SetOutputsStep                 PipelineStep = "set_outputs"
+	ApplyRulesStep                 PipelineStep = "apply_rules"
+	RemoveWithoutInputsStep        PipelineStep = "remove_without_inputs"
+	DisableWithoutInputsStep       PipelineStep = "disable_without_inputs"
+)
+
+// PipelineSteps represents all known steps of astra streams processing in default order
+var PipelineSteps = []PipelineStep{
+	RenameStep,
+	RemoveBlockedInputsStep,
+	RemoveDuplicatedInputsStep,
+	RemoveDuplicatedInputsByRxStep,
+	RemoveDisabledInputsStep,
+	UpdateInputsStep,
+	RemoveInputsByUpdateMapStep,
+	AddNewInputsStep,
+	UniteInputsStep,
+	SortInputsStep,
+	AddNewStep,
+	ReviveDisabledInputsStep,
+	RemoveDeadInputsStep,
+	DisableDeadInputsStep,
+	AddHashesStep,
+	DisableAllButOneInputByRxStep,
+	SetKeepActiveStep,
+	SetOutputsStep,
+	ApplyRulesStep,
+	RemoveWithoutInputsStep,
+	DisableWithoutInputsStep,
+}
+
+// exclusiveSteps represents pairs of steps which can not be in the same pipeline
+var exclusiveSteps = [][2]PipelineStep{
+	{RemoveDeadInputsStep, DisableDeadInputsStep},
+	{RemoveWithoutInputsStep, DisableWithoutInputsStep},
+}
+
+// Steps returns steps of astra streams processing to run in order.
+//
+// Returns <Pipeline> if it's not empty, otherwise returns steps enabled by other settings in default order.
+func (s Streams) Steps() []PipelineStep {
+	if len(s.Pipeline) > 0 {
+		return s.Pipeline
+	}
+
+	enabled := map[PipelineStep]bool{
+		RenameStep:                     s.Rename,
+		RemoveBlockedInputsStep:        len(s.InputBlacklist) > 0,
+		RemoveDuplicatedInputsStep:     s.RemoveDuplicatedInputs,
+		RemoveDuplicatedInputsByRxStep: len(s.RemoveDuplicatedInputsByRxList) > 0,
+		RemoveDisabledInputsStep:       s.RemoveDisabledInputs,
+		UpdateInputsStep:               s.UpdateInputs,
+		RemoveInputsByUpdateMapStep:    s.RemoveInputsByUpdateMap,
+		AddNewInputsStep:               s.AddNewInputs,
+		UniteInputsStep:                s.UniteInputs,
+		SortInputsStep:                 s.SortInputs,
+		AddNewStep:                     s.AddNew,
+		ReviveDisabledInputsStep:       s.ReviveDisabledInputs,
+		RemoveDeadInputsStep:           s.RemoveDeadInputs,
+		DisableDeadInputsStep:          s.DisableDeadInputs && !s.RemoveDeadInputs,
+		DisableAllButOneInputByRxStep:  len(s.DisableAllButOneInputByRxList) > 0,
+		ApplyRulesStep:                 len(s.Rules) > 0,
+		RemoveWithoutInputsStep:        s.RemoveWithoutInputs,
+		DisableWithoutInputsStep:       s.DisableWithoutInputs && !s.RemoveWithoutInputs,
+	}
+	enabled[AddHashesStep] = !slice.IsAllEmpty(s.NameToInputHashMap, s.GroupToInputHashMap, s.InputToInputHashMap)
+	enabled[SetKeepActiveStep] = !slice.IsAllEmpty(s.NameToKeepActiveMap, s.GroupToKeepActiveMap,
+		s.InputToKeepActiveMap)
+	enabled[SetOutputsStep] = !slice.IsAllEmpty(s.NameToOutputsMap, s.GroupToOutputsMap, s.InputToOutputsMap)
+	return lo.Filter(PipelineSteps, func(step PipelineStep, _ int) bool {
+		return enabled[step]
+	})
+}
+
+// ignoredSteps returns pairs of mutually exclusive steps both enabled by settings other than <Pipeline> if it's empty.
+//
+// The first step of every pair has priority, the second one is not run (see Steps).
+func (s Streams) ignoredSteps() [][2]PipelineStep {
+	if len(s.Pipeline) > 0 {
+		return nil
+	}
+	enabled := map[PipelineStep]bool{
+		RemoveDeadInputsStep:     s.RemoveDeadInputs,
+		DisableDeadInputsStep:    s.DisableDeadInputs,
+		RemoveWithoutInputsStep:  s.RemoveWithoutInputs,
+		DisableWithoutInputsStep: s.DisableWithoutInputs,
+	}
+	return lo.Filter(exclusiveSteps, func(pair [2]PipelineStep, _ int) bool {
+		return enabled[pair[0]] && enabled[pair[1]]
+	})
+}
+
+// validatePipeline returns error if <pipeline> has unknown or duplicated steps or both steps of mutually exclusive
+// pair
+func validatePipeline(pipeline []PipelineStep) error {
+	for _, step := range pipeline {
+		if !lo.Contains(PipelineSteps, step) {
+			return errors.Newf("Unknown pipeline step %q", step)
+		}
+	}
+	if dupl := lo.FindDuplicates(pipeline); len(dupl) > 0 {
+		return errors.Newf("Duplicated pipeline step %q", dupl[0])
+	}
+	for _, pair := range exclusiveSteps {
+		if lo.Every(pipeline, pair[:]) {
+			return errors.Newf("Pipeline steps %q and %q are mutually exclusive", pair[0], pair[1])
+		}
+	}
+	return nil
+}
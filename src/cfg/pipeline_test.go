@@ -0,0 +1,96 @@
+package cfg
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+
+	"m3u_merge_astra/util/logger"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/zenizh/go-capturer"
+)
+
+func TestSteps(t *testing.T) {
+	s := Streams{}
+	s.Rename = true
+	s.ReviveDisabledInputs = true
+	s.RemoveDeadInputs = true
+	s.DisableDeadInputs = true
+	s.DisableWithoutInputs = true
+	s.NameToOutputsMap = []OutputsSetRule{{By: *regexp.MustCompile(`.*`)}}
+	expected := []PipelineStep{RenameStep, ReviveDisabledInputsStep, RemoveDeadInputsStep, SetOutputsStep,
+		DisableWithoutInputsStep}
+	assert.Exactly(t, expected, s.Steps(), "should return enabled steps in default order, remove has priority")
+
+	s.Pipeline = []PipelineStep{AddHashesStep, SortInputsStep}
+	assert.Exactly(t, s.Pipeline, s.Steps(), "should return pipeline regardless of other settings")
+}
+
+func TestIgnoredSteps(t *testing.T) {
+	s := Streams{}
+	s.RemoveDeadInputs = true
+	s.DisableDeadInputs = true
+	s.DisableWithoutInputs = true
+	expected := [][2]PipelineStep{{RemoveDeadInputsStep, DisableDeadInputsStep}}
+	assert.Exactly(t, expected, s.ignoredSteps(), "should return pairs of mutually exclusive steps both enabled")
+
+	s.RemoveWithoutInputs = true
+	assert.Len(t, s.ignoredSteps(), 2, "should return every pair")
+
+	s.Pipeline = []PipelineStep{RemoveDeadInputsStep}
+	assert.Empty(t, s.ignoredSteps(), "should not return steps if pipeline is set")
+}
+
+func TestValidatePipeline(t *testing.T) {
+	assert.NoError(t, validatePipeline(nil), "should not return error for empty pipeline")
+	assert.NoError(t, validatePipeline(PipelineSteps[:12]), "should not return error for valid pipeline")
+
+	err := validatePipeline([]PipelineStep{RenameStep, "unknown"})
+	assert.ErrorContains(t, err, `Unknown pipeline step "unknown"`)
+
+	err = validatePipeline([]PipelineStep{SortInputsStep, AddHashesStep, SortInputsStep})
+	assert.ErrorContains(t, err, `Duplicated pipeline step "sort_inputs"`)
+
+	err = validatePipeline([]PipelineStep{DisableWithoutInputsStep, RenameStep, RemoveWithoutInputsStep})
+	assert.ErrorContains(t, err, `Pipeline steps "remove_without_inputs" and "disable_without_inputs" are mutually `+
+		`exclusive`)
+
+	assert.Error(t, validatePipeline(PipelineSteps), "should return error for all steps")
+}
+
+func TestInitPipeline(t *testing.T) {
+	log := logger.New(logger.DebugLevel)
+
+	path := filepath.Join(t.TempDir(), "m3u_merge_astra_init_test.yaml")
+
+	cfgStr := strings.Replace(string(defCfgBytes), "  pipeline:\n", "  pipeline: ['add_hashes', 'sort_inputs']\n", 1)
+	err := os.WriteFile(path, []byte(cfgStr), 0644)
+	assert.NoError(t, err, "should write config bytes")
+
+	actual, _, err := Init(log, path)
+	assert.NoError(t, err, "should not return error")
+	assert.Exactly(t, []PipelineStep{AddHashesStep, SortInputsStep}, actual.Streams.Pipeline,
+		"actual config should contain this pipeline")
+
+	cfgStr = strings.Replace(string(defCfgBytes), "  pipeline:\n", "  pipeline: ['add_hashes', 'sort']\n", 1)
+	err = os.WriteFile(path, []byte(cfgStr), 0644)
+	assert.NoError(t, err, "should write config bytes")
+
+	_, _, err = Init(log, path)
+	assert.ErrorContains(t, err, `Validate config: Unknown pipeline step "sort"`)
+
+	cfgStr = strings.Replace(string(defCfgBytes), "  remove_dead_inputs: false\n", "  remove_dead_inputs: true\n", 1)
+	cfgStr = strings.Replace(cfgStr, "  disable_dead_inputs: false\n", "  disable_dead_inputs: true\n", 1)
+	err = os.WriteFile(path, []byte(cfgStr), 0644)
+	assert.NoError(t, err, "should write config bytes")
+
+	out := capturer.CaptureStderr(func() {
+		_, _, err = Init(logger.New(logger.DebugLevel), path)
+	})
+	assert.NoError(t, err, "should not return error")
+	assert.Contains(t, out, `Mutually exclusive settings are both enabled, only the first one is used: `+
+		`first "remove_dead_inputs", ignored "disable_dead_inputs"`)
+}
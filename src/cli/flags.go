@@ -1,22 +1,94 @@
 package cli
 
 import (
+	"time"
+
 	"github.com/cockroachdb/errors"
 	goFlags "github.com/jessevdk/go-flags"
 	pLog "github.com/phuslu/log"
 )
 
+// Command names
+const (
+	RestoreCmd = "restore"
+	ServeCmd   = "serve"
+	ReportCmd  = "report"
+	ExportCmd  = "export"
+	PlanCmd    = "plan"
+	ApplyCmd   = "apply"
+)
+
 // Flags represents command line flags
 type Flags struct {
-	Version        bool       `short:"v" long:"version"        description:"Print the program version"`
-	Noninteractive bool       `short:"n" long:"noninteractive" description:"Do not ask user for input (confirmations etc.)"`
-	LogLevel       pLog.Level `short:"l" long:"logLevel"       description:"Logging level. Can be from 1 (most verbose) to 7 (least verbose)"`
-	LogFile        string     `short:"f" long:"logFile"        description:"Log file. If set, writes structured log to a file at the specified path"`
-	ProgramCfgPath string     `short:"c" long:"programCfgPath" description:"Program config file path to read from or initialize a default"`
-	M3UPath        string     `short:"m" long:"m3uPath"        description:"M3U file path to get channels from. Can be a local file or URL"`
-	AstraAddr      string     `short:"a" long:"astraAddr"      description:"Astra address in format of scheme://host:port"`
-	AstraUser      string     `short:"u" long:"astraUser"      description:"Astra user"`
-	AstraPwd       string     `short:"p" long:"astraPwd"       description:"Astra password"`
+	Version         bool       `short:"v" long:"version"         description:"Print the program version"`
+	Noninteractive  bool       `short:"n" long:"noninteractive"  description:"Do not ask user for input (confirmations etc.)"`
+	LogLevel        pLog.Level `short:"l" long:"logLevel"        description:"Logging level. Can be from 1 (most verbose) to 7 (least verbose)"`
+	LogFile         string     `short:"f" long:"logFile"         description:"Log file. If set, writes structured log to a file at the specified path"`
+	ProgramCfgPath  string     `short:"c" long:"programCfgPath"  description:"Program config file path to read from or initialize a default"`
+	M3UPath         string     `short:"m" long:"m3uPath"         description:"M3U file path to get channels from. Can be a local file or URL. See also m3u.sources setting"`
+	AstraAddr       string     `short:"a" long:"astraAddr"       description:"Astra address in format of scheme://host:port"`
+	AstraUser       string     `short:"u" long:"astraUser"       description:"Astra user"`
+	AstraPwd        string     `short:"p" long:"astraPwd"        description:"Astra password"`
+	DryRun          bool       `short:"d" long:"dryRun"          description:"Print planned changes as human-readable and JSON reports, exit with code 2 without sending them to astra"`
+	BackupDir       string     `short:"b" long:"backupDir"       description:"Directory to save snapshots of astra config to before sending changes. Set to empty string to disable"`
+	BackupKeep      int        `short:"k" long:"backupKeep"      description:"Amount of the newest snapshots to keep in backupDir, older ones are removed. Set to 0 to keep all"`
+	AstraCfgFile    string     `short:"i" long:"astraCfgFile"    description:"Astra config file (JSON) to use instead of astra API. Changes are written to astraCfgOutFile"`
+	AstraCfgOutFile string     `short:"o" long:"astraCfgOutFile" description:"Astra config file (JSON) to write changes to if astraCfgFile is set. Defaults to astraCfgFile"`
+	Atomic          bool       `short:"t" long:"atomic"          description:"Stop on the first change failed to apply and revert already applied ones"`
+	Verify          bool       `short:"V" long:"verify"          description:"Refetch astra config after sending changes and report changes astra does not hold"`
+	VerifyRetries   int        `short:"r" long:"verifyRetries"   description:"How many times to send changes astra does not hold again if verify is set"`
+
+	Restore RestoreArgs `command:"restore" description:"Send astra config from snapshot file back to astra, reverting any changes made after"`
+	Serve   ServeArgs   `command:"serve"   description:"Run merge on schedule until SIGINT or SIGTERM signal is received"`
+	Report  ReportArgs  `command:"report"  description:"Print streams and M3U channels which do not match each other and exit"`
+	Export  ExportArgs  `command:"export"  description:"Write astra streams to M3U playlist and exit"`
+	Plan    PlanArgs    `command:"plan"    description:"Write changes to plan file to send them later with the apply command and exit"`
+	Apply   ApplyArgs   `command:"apply"   description:"Send changes from plan file to astra if astra config did not change since the plan was written"`
+
+	Command string // Name of the command specified or empty string if not specified
+}
+
+// RestoreArgs represents arguments of the restore command
+type RestoreArgs struct {
+	Args struct {
+		SnapshotPath string `positional-arg-name:"snapshotPath" description:"Path to snapshot file of astra config"`
+	} `positional-args:"yes" required:"yes"`
+}
+
+// ServeArgs represents arguments of the serve command
+type ServeArgs struct {
+	Interval time.Duration `long:"interval" description:"Interval between runs, for example 30m or 6h. Ignored if cron is set"`
+	Cron     string        `long:"cron"     description:"Cron expression to schedule runs with, for example '0 */6 * * *'"`
+}
+
+// ReportArgs represents arguments of the report command
+type ReportArgs struct {
+	Threshold float64 `long:"threshold" description:"Minimum name similarity score (from 0 to 1) of unmatched stream and M3U channel to report them as a near miss"`
+}
+
+// ExportArgs represents arguments of the export command
+type ExportArgs struct {
+	URLTemplate     string `long:"urlTemplate"     description:"URL of astra HTTP output of a stream. {id} and {name} are replaced with ID and name of the stream. Set to empty string to export only inputs"`
+	AllGroups       bool   `long:"allGroups"       description:"Put all groups of a stream into group-title separated by ';' instead of the first one"`
+	Inputs          bool   `long:"inputs"          description:"Export every input of a stream as a separate entry"`
+	ExcludeDisabled bool   `long:"excludeDisabled" description:"Do not export disabled streams"`
+	Args            struct {
+		OutPath string `positional-arg-name:"outPath" description:"Path to M3U playlist to write. Use - to write to stdout"`
+	} `positional-args:"yes" required:"yes"`
+}
+
+// PlanArgs represents arguments of the plan command
+type PlanArgs struct {
+	Args struct {
+		PlanPath string `positional-arg-name:"planPath" description:"Path to plan file to write"`
+	} `positional-args:"yes" required:"yes"`
+}
+
+// ApplyArgs represents arguments of the apply command
+type ApplyArgs struct {
+	Args struct {
+		PlanPath string `positional-arg-name:"planPath" description:"Path to plan file to read"`
+	} `positional-args:"yes" required:"yes"`
 }
 
 // Parse returns a structure initialized with command line arguments and error if parsing failed
@@ -26,9 +98,18 @@ func Parse() (Flags, error) {
 		LogLevel:       pLog.InfoLevel,
 		ProgramCfgPath: "m3u_merge_astra.yaml",
 		AstraAddr:      "http://127.0.0.1:8000",
+		BackupDir:      "astra_backup",
+		BackupKeep:     10,
+		Serve:          ServeArgs{Interval: time.Hour},
+		Report:         ReportArgs{Threshold: 0.8},
+		Export:         ExportArgs{URLTemplate: "http://127.0.0.1:8000/play/{id}"},
 	}
 	parser := goFlags.NewParser(&flags, goFlags.Options(goFlags.Default))
+	parser.SubcommandsOptional = true
 	_, err := parser.Parse()
+	if parser.Active != nil {
+		flags.Command = parser.Active.Name
+	}
 	return flags, errors.Wrap(err, "Parse CLI arguments")
 }
 
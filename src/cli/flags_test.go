@@ -3,6 +3,7 @@ package cli
 import (
 	"os"
 	"testing"
+	"time"
 
 	goFlags "github.com/jessevdk/go-flags"
 	pLog "github.com/phuslu/log"
@@ -15,6 +16,10 @@ func TestParse(t *testing.T) {
 	assert.NoError(t, err, "should not return error")
 	assert.False(t, flags.Noninteractive, "noninteracive flag should be false if not specified")
 	assert.Empty(t, flags.LogFile, "logFile flag should be empty if not specified")
+	assert.False(t, flags.DryRun, "dryRun flag should be false if not specified")
+	assert.Exactly(t, "astra_backup", flags.BackupDir, "backupDir flag should have default value if not specified")
+	assert.Empty(t, flags.Command, "command should be empty if not specified")
+	assert.Exactly(t, time.Hour, flags.Serve.Interval, "interval flag should have default value if not specified")
 
 	os.Args = []string{"", "--help"}
 	_, err = Parse()
@@ -41,7 +46,7 @@ func TestParse(t *testing.T) {
 	assert.Exactly(t, pLog.Level(999), flags.LogLevel, "flag should have this value")
 
 	os.Args = []string{"", "--noninteractive", "--logFile=/log", "--programCfgPath=/cfg/path", "--m3uPath=/m3u/path",
-		"--astraAddr=http://127.0.0.1:8005", "--astraUser=admin", "--astraPwd=admin"}
+		"--astraAddr=http://127.0.0.1:8005", "--astraUser=admin", "--astraPwd=admin", "--dryRun", "--backupDir=/backup"}
 	flags, err = Parse()
 	assert.NoError(t, err, "should not return error")
 	assert.True(t, flags.Noninteractive, "flag should have this value")
@@ -52,6 +57,27 @@ func TestParse(t *testing.T) {
 	assert.Exactly(t, "http://127.0.0.1:8005", flags.AstraAddr, "flag should have this value")
 	assert.Exactly(t, "admin", flags.AstraUser, "flag should have this value")
 	assert.Exactly(t, "admin", flags.AstraPwd, "flag should have this value")
+	assert.True(t, flags.DryRun, "flag should have this value")
+	assert.Exactly(t, "/backup", flags.BackupDir, "flag should have this value")
+
+	os.Args = []string{"", "restore"}
+	_, err = Parse()
+	assert.True(t, IsErrOfType(err, goFlags.ErrRequired), "should return required error without snapshot path")
+
+	os.Args = []string{"", "-u", "admin", "restore", "/backup/snapshot.json", "-p", "admin"}
+	flags, err = Parse()
+	assert.NoError(t, err, "should not return error")
+	assert.Exactly(t, RestoreCmd, flags.Command, "should have this command")
+	assert.Exactly(t, "/backup/snapshot.json", flags.Restore.Args.SnapshotPath, "argument should have this value")
+	assert.Exactly(t, "admin", flags.AstraUser, "flag should have this value")
+	assert.Exactly(t, "admin", flags.AstraPwd, "flag should have this value")
+
+	os.Args = []string{"", "serve", "--interval=30m", "--cron=0 */6 * * *"}
+	flags, err = Parse()
+	assert.NoError(t, err, "should not return error")
+	assert.Exactly(t, ServeCmd, flags.Command, "should have this command")
+	assert.Exactly(t, time.Minute*30, flags.Serve.Interval, "flag should have this value")
+	assert.Exactly(t, "0 */6 * * *", flags.Serve.Cron, "flag should have this value")
 }
 
 func TestIsErrOfType(t *testing.T) {
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"os"
+
+	"m3u_merge_astra/astra"
+	"m3u_merge_astra/cfg"
+	"m3u_merge_astra/cli"
+	"m3u_merge_astra/m3u"
+	"m3u_merge_astra/merge"
+	"m3u_merge_astra/util/logger"
+
+	"github.com/cockroachdb/errors"
+)
+
+// export writes astra streams to M3U playlist at path specified in <flags>
+func export(log *logger.Logger, flags cli.Flags, cfg cfg.Root) {
+	// Fetch astra config
+	log.Info("Fetching astra config")
+	apiHandler := newAPIHandler(log, flags, cfg)
+	astraCfg, err := apiHandler.FetchCfg()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	streams := astra.NewRepo(log, cfg).Sort(astraCfg.Streams)
+	channels := merge.NewRepo(log, cfg).Export(streams, merge.ExportOptions{
+		URLTemplate:     flags.Export.URLTemplate,
+		AllGroups:       flags.Export.AllGroups,
+		Inputs:          flags.Export.Inputs,
+		ExcludeDisabled: flags.Export.ExcludeDisabled,
+	})
+
+	out := os.Stdout
+	if path := flags.Export.Args.OutPath; path != "-" {
+		if out, err = os.Create(path); err != nil {
+			log.Fatal(errors.Wrap(err, "Create M3U playlist"))
+		}
+		defer out.Close()
+	}
+	if err := m3u.NewRepo(log, cfg).Write(out, channels); err != nil {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,103 @@
+package m3u
+
+import (
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Attributes represents attributes of #EXTINF directive
+type Attributes map[string]string
+
+// Get returns value of attribute with <key> (case insensitive) or empty string if not found
+func (a Attributes) Get(key string) string {
+	return a[strings.ToLower(key)]
+}
+
+// extInf represents parsed #EXTINF directive
+type extInf struct {
+	Duration   float64
+	Attributes Attributes
+	Name       string
+	Complete   bool // False if directive ends inside of quoted attribute value and can continue on the next line
+}
+
+// parseExtInf parses #EXTINF directive <line> in format of:
+//
+// #EXTINF:<duration> <key>="<value>" <key>='<value>' <key>=<value>,<name>
+//
+// Attribute keys are converted to lowercase, if key is repeated, the last value wins. Commas inside of quoted values
+// are not considered as a name separator. Duration is 0 if invalid.
+//
+// If quoted attribute value is not closed until the end of line, the attribute is skipped and name is taken after the
+// first comma.
+func parseExtInf(line string) (out extInf) {
+	rest := []rune(strings.TrimPrefix(line, "#EXTINF:"))
+	pos := 0
+
+	skipSpaces := func() {
+		for pos < len(rest) && unicode.IsSpace(rest[pos]) {
+			pos++
+		}
+	}
+	readUntil := func(stop func(r rune) bool) string {
+		start := pos
+		for pos < len(rest) && !stop(rest[pos]) {
+			pos++
+		}
+		return string(rest[start:pos])
+	}
+	isSeparator := func(r rune) bool {
+		return r == ',' || unicode.IsSpace(r)
+	}
+
+	// Duration
+	skipSpaces()
+	out.Duration, _ = strconv.ParseFloat(readUntil(isSeparator), 64)
+
+	// Attributes
+	out.Complete = true
+	for {
+		skipSpaces()
+		if pos >= len(rest) {
+			return // No name
+		}
+		if rest[pos] == ',' {
+			break
+		}
+		key := readUntil(func(r rune) bool { return r == '=' || isSeparator(r) })
+		if pos >= len(rest) || rest[pos] != '=' {
+			continue // Attribute without value, ignore
+		}
+		pos++ // Skip '='
+
+		var value string
+		if pos < len(rest) && (rest[pos] == '"' || rest[pos] == '\'') {
+			quote := rest[pos]
+			pos++
+			value = readUntil(func(r rune) bool { return r == quote })
+			if pos >= len(rest) {
+				out.Complete = false
+				if _, name, found := strings.Cut(string(rest), ","); found {
+					out.Name = strings.TrimSpace(name)
+				}
+				return
+			}
+			pos++ // Skip closing quote
+		} else {
+			value = readUntil(isSeparator)
+		}
+
+		if key != "" {
+			if out.Attributes == nil {
+				out.Attributes = Attributes{}
+			}
+			out.Attributes[strings.ToLower(key)] = strings.TrimSpace(value)
+		}
+	}
+
+	// Name
+	out.Name = strings.TrimSpace(string(rest[pos+1:]))
+
+	return
+}
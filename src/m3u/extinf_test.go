@@ -0,0 +1,35 @@
+package m3u
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAttributesGet(t *testing.T) {
+	attrs := Attributes{"tvg-id": "id"}
+	assert.Exactly(t, "id", attrs.Get("TVG-ID"), "should find attribute case insensitive")
+	assert.Exactly(t, "", attrs.Get("tvg-name"), "should return empty string for missing attribute")
+	assert.Exactly(t, "", Attributes(nil).Get("tvg-id"), "should return empty string for nil attributes")
+}
+
+func TestParseExtInf(t *testing.T) {
+	expected := extInf{Duration: -1, Name: "Name", Complete: true}
+	assert.Exactly(t, expected, parseExtInf("#EXTINF:-1,Name"), "should parse directive without attributes")
+
+	expected = extInf{
+		Duration:   10.5,
+		Attributes: Attributes{"a": "1", "b": "2, 3", "c": "4", "d": "it's"},
+		Name:       "Name, with comma",
+		Complete:   true,
+	}
+	actual := parseExtInf(`#EXTINF: 10.5 A="0" a="1" b='2, 3' c=4  flag d="it's" , Name, with comma `)
+	assert.Exactly(t, expected, actual, "should parse attributes in all formats, the last repeated key wins")
+
+	expected = extInf{Attributes: Attributes{"a": "1"}, Name: "", Complete: true}
+	assert.Exactly(t, expected, parseExtInf(`#EXTINF:invalid a=1`), "should parse directive without name")
+
+	expected = extInf{Duration: 0, Attributes: Attributes{"a": "1"}, Name: "Name", Complete: false}
+	assert.Exactly(t, expected, parseExtInf(`#EXTINF:0 a="1" b="2,Name`),
+		"should report unclosed quote, take name after the first comma")
+}
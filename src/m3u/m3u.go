@@ -3,7 +3,7 @@ package m3u
 import (
 	"bufio"
 	"io"
-	"regexp"
+	"strconv"
 	"strings"
 
 	"m3u_merge_astra/cfg"
@@ -15,9 +15,21 @@ import (
 
 // Channel represents M3U channel object
 type Channel struct {
-	Name  string
-	Group string
-	URL   string
+	Name   string
+	Group  string
+	URL    string
+	Source string // Name of the M3U source the channel came from
+
+	Duration    float64
+	TVGID       string
+	TVGName     string
+	TVGLogo     string
+	TVGChNo     string
+	Catchup     string
+	CatchupDays int
+	Attributes  Attributes // All attributes of #EXTINF directive including known ones
+	VLCOpts     []string   // Values of #EXTVLCOPT directives
+	KodiProps   []string   // Values of #KODIPROP directives
 }
 
 // GetName used to satisfy util/slice.Named interface
@@ -25,55 +37,93 @@ func (ch Channel) GetName() string {
 	return ch.Name
 }
 
-// replaceGroup returns channel with group taken from <cfg>, running <callback> with new group on change
+// GetTVGID used to satisfy util/slice/find.Identified interface
+func (ch Channel) GetTVGID(_ cfg.General) string {
+	return ch.TVGID
+}
+
+// replaceGroup returns channel with group (and group-title attribute if set) taken from <cfg>, running <callback> with
+// new group on change
 func (ch Channel) replaceGroup(cfg cfg.M3U, callback func(string)) Channel {
 	newGroup := cfg.ChannGroupMap[ch.Group]
 	if ch.Group != newGroup && newGroup != "" {
 		callback(newGroup)
 		ch.Group = newGroup
+		if _, found := ch.Attributes["group-title"]; found {
+			ch.Attributes = lo.Assign(ch.Attributes, Attributes{"group-title": newGroup})
+		}
 	}
 	return ch
 }
 
-// Parse parses <rawChannels> into []Channel
+// Parse parses <rawChannels> into []Channel.
+//
+// #EXTINF directive with quoted attribute value not closed until the end of line continues on the next lines if it
+// gets closed there. Otherwise, name of the channel is taken after the first comma.
+//
+// #EXTVLCOPT and #KODIPROP directives apply to the next channel.
 func (r repo) Parse(rawChannels io.ReadCloser) (out []Channel) {
 	r.log.Info("Parsing M3U channels")
 
-	nameRx := regexp.MustCompile(`^#EXTINF:.*?,(.*)`)
-	groupTitleRx := regexp.MustCompile(`^#EXTINF:.*group-title="(.*?)"`)
-	extGrpRx := regexp.MustCompile(`^#EXTGRP:(.*)`)
-
-	name := ""
-	groupTitle := ""
-	lastExtGrp := ""
-
+	lines := []string{}
 	scanner := bufio.NewScanner(rawChannels)
 	for scanner.Scan() {
-		line := strings.TrimSpace(scanner.Text())
-
-		if line == "" {
-			continue
+		if line := strings.TrimSpace(scanner.Text()); line != "" {
+			lines = append(lines, line)
 		}
-		if matchList := nameRx.FindStringSubmatch(line); len(matchList) > 1 {
-			name = strings.TrimSpace(matchList[1])
-		}
-		if matchList := groupTitleRx.FindStringSubmatch(line); len(matchList) > 1 {
-			groupTitle = strings.TrimSpace(matchList[1])
-		}
-		if matchList := extGrpRx.FindStringSubmatch(line); len(matchList) > 1 {
-			lastExtGrp = strings.TrimSpace(matchList[1])
-			continue
-		}
-		if !strings.HasPrefix(line, "#") && name != "" {
+	}
+
+	var inf extInf
+	lastExtGrp := ""
+	var vlcOpts, kodiProps []string
+
+	for idx := 0; idx < len(lines); idx++ {
+		line := lines[idx]
+
+		switch {
+		case strings.HasPrefix(line, "#EXTINF:"):
+			inf = parseExtInf(line)
+			// Look for the closing quote on the next lines until the next directive or the end of file
+			for last := idx + 1; !inf.Complete && last < len(lines) && !strings.HasPrefix(lines[last], "#"); last++ {
+				joined := parseExtInf(strings.Join(lines[idx:last+1], " "))
+				if joined.Complete {
+					inf = joined
+					idx = last
+				}
+			}
+			if !inf.Complete {
+				r.log.WarnFi("Unclosed quote in #EXTINF directive, taking name after the first comma", "line", line)
+			}
+		case strings.HasPrefix(line, "#EXTGRP:"):
+			lastExtGrp = strings.TrimSpace(strings.TrimPrefix(line, "#EXTGRP:"))
+		case strings.HasPrefix(line, "#EXTVLCOPT:"):
+			vlcOpts = append(vlcOpts, strings.TrimSpace(strings.TrimPrefix(line, "#EXTVLCOPT:")))
+		case strings.HasPrefix(line, "#KODIPROP:"):
+			kodiProps = append(kodiProps, strings.TrimSpace(strings.TrimPrefix(line, "#KODIPROP:")))
+		case strings.HasPrefix(line, "#"):
+			// Unknown directive or comment
+		case inf.Name != "":
+			groupTitle := inf.Attributes.Get("group-title")
+			catchupDays, _ := strconv.Atoi(inf.Attributes.Get("catchup-days"))
 			ch := Channel{
-				Name: name,
+				Name: inf.Name,
 				// group-title have a priority over #EXTGRP
-				Group: lo.Ternary(groupTitle != "", groupTitle, lastExtGrp),
-				URL:   line,
+				Group:       lo.Ternary(groupTitle != "", groupTitle, lastExtGrp),
+				URL:         line,
+				Duration:    inf.Duration,
+				TVGID:       inf.Attributes.Get("tvg-id"),
+				TVGName:     inf.Attributes.Get("tvg-name"),
+				TVGLogo:     inf.Attributes.Get("tvg-logo"),
+				TVGChNo:     inf.Attributes.Get("tvg-chno"),
+				Catchup:     inf.Attributes.Get("catchup"),
+				CatchupDays: catchupDays,
+				Attributes:  inf.Attributes,
+				VLCOpts:     vlcOpts,
+				KodiProps:   kodiProps,
 			}
 			out = append(out, ch)
-			name = ""
-			groupTitle = ""
+			inf = extInf{}
+			vlcOpts, kodiProps = nil, nil
 			// #EXTGRP applies to every subsequent channel until overriden. Not clearing lastExtGrp.
 		}
 	}
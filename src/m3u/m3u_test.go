@@ -61,20 +61,50 @@ func TestParse(t *testing.T) {
 
 	assert.Len(t, cl, 5, "should parse this amount of channels")
 
-	expected := Channel{Name: ", Channel 1", Group: "Group 1", URL: "http://channel/url/1"}
+	expected := Channel{Name: ", Channel 1", Group: "Group 1", URL: "http://channel/url/1", Duration: -1,
+		TVGLogo: "http://tvg/logo/1", Attributes: Attributes{"tvg-logo": "http://tvg/logo/1", "group-title": "Group 1"}}
 	assert.Exactly(t, expected, cl[0], "should have this channel")
 
 	expected = Channel{Name: ",:It,\"s, - a difficult name |", Group: "Ext Group", URL: "ftp://channel/url/2"}
 	assert.Exactly(t, expected, cl[1], "should have this channel with a group from #EXTGRP")
 
-	expected = Channel{Name: "Channel 3", Group: "Group 3", URL: "/path/to/file/3"}
+	expected = Channel{Name: "Channel 3", Group: "Group 3", URL: "/path/to/file/3", Duration: 1,
+		Attributes: Attributes{"group-title": "Group 3"}}
 	assert.Exactly(t, expected, cl[2], "should have this channel, prioritize group-title over #EXTGRP")
 
-	expected = Channel{Name: "Channel 4", Group: "Ext Group", URL: "file:///channel/url/4"}
+	expected = Channel{Name: "Channel 4", Group: "Ext Group", URL: "file:///channel/url/4", Duration: 2}
 	assert.Exactly(t, expected, cl[3], "should have this channel with a group from previous #EXTGRP")
 
-	expected = Channel{Name: "Channel 5", Group: "#EXTGRP: Ext Group 2", URL: "file:///C:/channel/url/5"}
+	expected = Channel{Name: "Channel 5", Group: "#EXTGRP: Ext Group 2", URL: "file:///C:/channel/url/5", Duration: 3}
 	assert.Exactly(t, expected, cl[4], "should have this channel, overwrite previous #EXTGRP")
+
+	// Test attributes and extra directives
+	playlist, err = openuri.Open("test_attributes.m3u8")
+	assert.NoError(t, err, "Should read playlist")
+
+	cl = r.Parse(playlist)
+
+	assert.Len(t, cl, 4, "should parse this amount of channels")
+
+	expected = Channel{Name: "Channel 1", URL: "http://channel/url/1", Duration: -1, TVGID: "ch1.uk",
+		TVGName: "Channel, One", TVGLogo: "http://tvg/logo/1", TVGChNo: "1", Catchup: "shift", CatchupDays: 7,
+		Attributes: Attributes{"tvg-id": "ch1.uk", "tvg-name": "Channel, One", "tvg-logo": "http://tvg/logo/1",
+			"tvg-chno": "1", "catchup": "shift", "catchup-days": "7", "x-custom": "a, b"},
+		VLCOpts:   []string{"http-user-agent=Agent", "http-referrer=http://referrer"},
+		KodiProps: []string{"inputstream=inputstream.adaptive"},
+	}
+	assert.Exactly(t, expected, cl[0], "should have this channel with all attributes and extra directives")
+
+	expected = Channel{Name: "Channel 2", Group: "Multi line group", URL: "http://channel/url/2", Duration: -1,
+		TVGID: "ch2.tv", Attributes: Attributes{"tvg-id": "ch2.tv", "group-title": "Multi line group"}}
+	assert.Exactly(t, expected, cl[1], "should have this channel with attribute value continued on the next line")
+
+	expected = Channel{Name: "Channel 3", URL: "http://channel/url/3", Duration: -1}
+	assert.Exactly(t, expected, cl[2], "should have this channel with name after the first comma as quote is unclosed")
+
+	expected = Channel{Name: "Channel 4", URL: "http://channel/url/4", Duration: 5.5,
+		Attributes: Attributes{"catchup-days": "invalid"}}
+	assert.Exactly(t, expected, cl[3], "should have this channel, ignore invalid catchup days")
 }
 
 func TestSort(t *testing.T) {
@@ -102,7 +132,8 @@ func TestReplaceGroups(t *testing.T) {
 	}
 
 	cl1 := []Channel{
-		{Group: "Other"}, {Group: "From Group 2"}, {Group: "From Group 1"},
+		{Group: "Other"}, {Group: "From Group 2"},
+		{Group: "From Group 1", Attributes: Attributes{"group-title": "From Group 1", "tvg-id": "ch1.tv"}},
 	}
 	cl1Original := copier.TestDeep(t, cl1)
 
@@ -118,8 +149,8 @@ func TestReplaceGroups(t *testing.T) {
 	expected = Channel{Group: "To Group 2"}
 	assert.Exactly(t, expected, cl2[1], "should replace known group")
 
-	expected = Channel{Group: "To Group 1"}
-	assert.Exactly(t, expected, cl2[2], "should replace known group")
+	expected = Channel{Group: "To Group 1", Attributes: Attributes{"group-title": "To Group 1", "tvg-id": "ch1.tv"}}
+	assert.Exactly(t, expected, cl2[2], "should replace known group and group-title attribute")
 
 	// Test log output
 	out := capturer.CaptureStderr(func() {
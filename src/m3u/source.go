@@ -0,0 +1,73 @@
+package m3u
+
+import (
+	"sort"
+
+	"m3u_merge_astra/cfg"
+	"m3u_merge_astra/util/copier"
+	"m3u_merge_astra/util/network"
+	"m3u_merge_astra/util/slice"
+
+	"github.com/cockroachdb/errors"
+	"github.com/samber/lo"
+	"github.com/utahta/go-openuri"
+)
+
+// FetchAll returns channels from all <sources> merged into one list sorted by name.
+//
+// Channels of sources with higher priority come first among channels with the same name.
+//
+// Returns error if any of <sources> can't be fetched.
+func (r repo) FetchAll(sources []cfg.M3USource) (out []Channel, err error) {
+	sources = copier.MustDeep(sources)
+	sort.SliceStable(sources, func(i, j int) bool {
+		return sources[i].Priority > sources[j].Priority
+	})
+
+	for _, src := range sources {
+		channels, err := r.Fetch(src)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, channels...)
+	}
+
+	if len(sources) > 1 {
+		out = r.Sort(out)
+	}
+
+	return
+}
+
+// Fetch returns channels from <src> sorted by name, with groups replaced and without blocked ones according to rules
+// of <src>.
+//
+// Every channel has Source field set to name of <src>.
+func (r repo) Fetch(src cfg.M3USource) ([]Channel, error) {
+	r.log.InfoFi("Fetching M3U channels", "source", src.Name, "path", src.Path)
+
+	srcRepo := NewRepo(r.log, r.cfg)
+	srcRepo.cfg.M3U = src.Rules(r.cfg.M3U.RespTimeout)
+
+	httpClient := network.NewHttpClient(srcRepo.cfg.M3U.RespTimeout)
+	resp, err := openuri.Open(src.Path, openuri.WithHTTPClient(httpClient))
+	if err != nil {
+		return nil, errors.Wrapf(err, "Fetch M3U channels of source %q", src.Name)
+	}
+	defer resp.Close()
+
+	channels := srcRepo.Parse(resp)
+	channels = srcRepo.Sort(channels)
+	if len(srcRepo.cfg.M3U.ChannGroupMap) > 0 {
+		channels = srcRepo.ReplaceGroups(channels)
+	}
+	if !slice.IsAllEmpty(srcRepo.cfg.M3U.ChannNameBlacklist, srcRepo.cfg.M3U.ChannGroupBlacklist,
+		srcRepo.cfg.M3U.ChannURLBlacklist) {
+		channels = srcRepo.RemoveBlocked(channels)
+	}
+
+	return lo.Map(channels, func(ch Channel, _ int) Channel {
+		ch.Source = src.Name
+		return ch
+	}), nil
+}
@@ -0,0 +1,70 @@
+package m3u
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+
+	"m3u_merge_astra/cfg"
+	"m3u_merge_astra/util/copier"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/zenizh/go-capturer"
+)
+
+func TestFetchAll(t *testing.T) {
+	r := newDefRepo()
+
+	dir := t.TempDir()
+	path1 := filepath.Join(dir, "1.m3u")
+	err := os.WriteFile(path1, []byte("#EXTM3U\n"+
+		"#EXTINF:-1 group-title=\"For kids\",Channel B\nhttp://provider_1/b\n"+
+		"#EXTINF:-1 group-title=\"Group 1\",Channel A\nhttp://provider_1/a\n"+
+		"#EXTINF:-1 group-title=\"Group 1\",Nonsense TV\nhttp://provider_1/nonsense\n"), 0644)
+	assert.NoError(t, err, "should write M3U file")
+	path2 := filepath.Join(dir, "2.m3u")
+	err = os.WriteFile(path2, []byte("#EXTM3U\n"+
+		"#EXTINF:-1 group-title=\"Group 2\",Channel B\nhttp://provider_2/b\n"+
+		"#EXTINF:-1 group-title=\"Group 2\",Nonsense TV\nhttp://provider_2/nonsense\n"), 0644)
+	assert.NoError(t, err, "should write M3U file")
+
+	sources := []cfg.M3USource{
+		{
+			Name:               "Provider 1",
+			Path:               path1,
+			ChannNameBlacklist: []regexp.Regexp{*regexp.MustCompile(`Nonsense TV`)},
+			ChannGroupMap:      map[string]string{"For kids": "Kids"},
+		},
+		{Name: "Provider 2", Path: path2, Priority: 10},
+	}
+	sourcesOriginal := copier.TestDeep(t, sources)
+
+	actual, err := r.FetchAll(sources)
+	assert.NoError(t, err, "should not return error")
+	assert.Exactly(t, sourcesOriginal, sources, "should not modify the source")
+
+	expected := []Channel{
+		{Name: "Channel A", Group: "Group 1", URL: "http://provider_1/a", Source: "Provider 1", Duration: -1,
+			Attributes: Attributes{"group-title": "Group 1"}},
+		{Name: "Channel B", Group: "Group 2", URL: "http://provider_2/b", Source: "Provider 2", Duration: -1,
+			Attributes: Attributes{"group-title": "Group 2"}},
+		{Name: "Channel B", Group: "Kids", URL: "http://provider_1/b", Source: "Provider 1", Duration: -1,
+			Attributes: Attributes{"group-title": "Kids"}},
+		{Name: "Nonsense TV", Group: "Group 2", URL: "http://provider_2/nonsense", Source: "Provider 2", Duration: -1,
+			Attributes: Attributes{"group-title": "Group 2"}},
+	}
+	assert.Exactly(t, expected, actual, "should merge channels of all sources processed by their own rules")
+
+	// Test missing source
+	sources = append(sources, cfg.M3USource{Name: "Provider 3", Path: filepath.Join(dir, "3.m3u")})
+	_, err = r.FetchAll(sources)
+	assert.ErrorContains(t, err, `Fetch M3U channels of source "Provider 3"`, "should return error")
+
+	// Test log output
+	out := capturer.CaptureStderr(func() {
+		r := newDefRepo()
+		_, _ = r.Fetch(cfg.M3USource{Name: "Provider 1", Path: path1})
+	})
+	assert.Contains(t, out, `Fetching M3U channels: source "Provider 1", path "`+path1+`"`)
+}
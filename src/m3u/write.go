@@ -0,0 +1,76 @@
+package m3u
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/cockroachdb/errors"
+	"github.com/samber/lo"
+)
+
+// knownAttrKeys represents keys of attributes stored in dedicated fields of Channel in the order they are written
+var knownAttrKeys = []string{"tvg-id", "tvg-name", "tvg-logo", "tvg-chno", "catchup", "catchup-days", "group-title"}
+
+// Write writes <channels> to <w> as M3U playlist
+func (r repo) Write(w io.Writer, channels []Channel) error {
+	r.log.InfoFi("Writing M3U channels", "amount", len(channels))
+
+	bw := bufio.NewWriter(w)
+	lines := []string{"#EXTM3U"}
+	for _, ch := range channels {
+		lines = append(lines, ch.extInf())
+		for _, opt := range ch.VLCOpts {
+			lines = append(lines, "#EXTVLCOPT:"+opt)
+		}
+		for _, prop := range ch.KodiProps {
+			lines = append(lines, "#KODIPROP:"+prop)
+		}
+		lines = append(lines, ch.URL)
+	}
+	for _, line := range lines {
+		if _, err := bw.WriteString(line + "\n"); err != nil {
+			return errors.Wrap(err, "Write M3U channels")
+		}
+	}
+
+	return errors.Wrap(bw.Flush(), "Write M3U channels")
+}
+
+// extInf returns #EXTINF directive of the channel.
+//
+// Values of dedicated fields have a priority over the same attributes in Attributes field. Empty attributes are
+// omitted.
+func (ch Channel) extInf() string {
+	known := map[string]string{
+		"tvg-id":       ch.TVGID,
+		"tvg-name":     ch.TVGName,
+		"tvg-logo":     ch.TVGLogo,
+		"tvg-chno":     ch.TVGChNo,
+		"catchup":      ch.Catchup,
+		"catchup-days": lo.Ternary(ch.CatchupDays != 0, strconv.Itoa(ch.CatchupDays), ""),
+		"group-title":  ch.Group,
+	}
+	extra := lo.OmitByKeys(ch.Attributes, knownAttrKeys)
+	extraKeys := lo.Keys(extra)
+	sort.Strings(extraKeys)
+
+	var sb strings.Builder
+	sb.WriteString("#EXTINF:" + strconv.FormatFloat(ch.Duration, 'f', -1, 64))
+	for _, key := range slices.Concat(knownAttrKeys, extraKeys) {
+		value := lo.Ternary(lo.Contains(knownAttrKeys, key), known[key], extra[key])
+		if value == "" {
+			continue
+		}
+		// Use single quotes if value contains double quotes, parseExtInf supports both
+		quote := lo.Ternary(strings.Contains(value, `"`), "'", `"`)
+		sb.WriteString(fmt.Sprintf(" %v=%v%v%v", key, quote, value, quote))
+	}
+	sb.WriteString("," + ch.Name)
+
+	return sb.String()
+}
@@ -0,0 +1,55 @@
+package m3u
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"m3u_merge_astra/util/copier"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/zenizh/go-capturer"
+)
+
+func TestWrite(t *testing.T) {
+	r := newDefRepo()
+
+	cl := []Channel{
+		{Name: "Channel 1", Group: "Group 1", URL: "http://channel/url/1", Duration: -1, TVGID: "ch1.tv",
+			TVGName: `Channel "1"`, TVGLogo: "http://tvg/logo/1", TVGChNo: "1", Catchup: "shift", CatchupDays: 7,
+			Attributes: Attributes{"tvg-id": "outdated.tv", "x-custom": "a, b", "a-custom": "c"},
+			VLCOpts:    []string{"http-user-agent=Agent"}, KodiProps: []string{"inputstream=inputstream.adaptive"}},
+		{Name: "Channel, 2", URL: "http://channel/url/2"},
+	}
+	clOriginal := copier.TestDeep(t, cl)
+
+	var buf bytes.Buffer
+	err := r.Write(&buf, cl)
+	assert.NoError(t, err, "should not return error")
+	assert.Exactly(t, clOriginal, cl, "should not modify the source channels")
+
+	expected := "#EXTM3U\n" +
+		`#EXTINF:-1 tvg-id="ch1.tv" tvg-name='Channel "1"' tvg-logo="http://tvg/logo/1" tvg-chno="1" ` +
+		`catchup="shift" catchup-days="7" group-title="Group 1" a-custom="c" x-custom="a, b",Channel 1` + "\n" +
+		"#EXTVLCOPT:http-user-agent=Agent\n" +
+		"#KODIPROP:inputstream=inputstream.adaptive\n" +
+		"http://channel/url/1\n" +
+		"#EXTINF:0,Channel, 2\n" +
+		"http://channel/url/2\n"
+	assert.Exactly(t, expected, buf.String(), "should write this playlist")
+
+	// Test parsing written playlist
+	parsed := r.Parse(io.NopCloser(&buf))
+	assert.Len(t, parsed, 2, "should parse this amount of channels")
+	assert.Exactly(t, cl[0].Name, parsed[0].Name, "should parse the same name")
+	assert.Exactly(t, cl[0].TVGName, parsed[0].TVGName, "should parse the same attribute")
+	assert.Exactly(t, cl[0].VLCOpts, parsed[0].VLCOpts, "should parse the same options")
+	assert.Exactly(t, cl[1].Name, parsed[1].Name, "should parse the same name")
+
+	// Test log output
+	out := capturer.CaptureStderr(func() {
+		r := newDefRepo()
+		_ = r.Write(io.Discard, cl)
+	})
+	assert.Contains(t, out, `Writing M3U channels: amount "2"`)
+}
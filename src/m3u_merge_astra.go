@@ -5,7 +5,6 @@ import (
 	"os"
 
 	"m3u_merge_astra/astra"
-	"m3u_merge_astra/astra/analyzer"
 	"m3u_merge_astra/astra/api"
 	"m3u_merge_astra/cfg"
 	"m3u_merge_astra/cli"
@@ -15,11 +14,11 @@ import (
 	"m3u_merge_astra/util/input"
 	"m3u_merge_astra/util/logger"
 	"m3u_merge_astra/util/network"
-	"m3u_merge_astra/util/slice"
 
 	"github.com/adampresley/sigint"
+	"github.com/cockroachdb/errors"
 	goFlags "github.com/jessevdk/go-flags"
-	"github.com/utahta/go-openuri"
+	"github.com/samber/lo"
 )
 
 func main() {
@@ -50,121 +49,152 @@ func main() {
 		log.Error(err)
 	}
 
-	// Register SIGINT and SIGTERM event handler
-	sigint.Listen(func() {
-		log.Info("SIGINT or SIGTERM signal received, shutting down")
-		os.Exit(0)
-	})
+	// Register SIGINT and SIGTERM event handler. Serve mode handles them on it's own to shut down gracefully.
+	if flags.Command != cli.ServeCmd {
+		sigint.Listen(func() {
+			log.Info("SIGINT or SIGTERM signal received, shutting down")
+			os.Exit(0)
+		})
+	}
 
 	log.InfoFi("Running with", "program config path", flags.ProgramCfgPath, "M3U path", flags.M3UPath, "astra address",
-		flags.AstraAddr)
+		flags.AstraAddr, "astra config file", flags.AstraCfgFile)
 
 	// Read program config
 	cfg, isNewCfg, err := cfg.Init(log, flags.ProgramCfgPath)
 	if err != nil {
 		log.Fatal(err)
 	}
+	if err := astra.ValidateTemplates(cfg.Streams); err != nil {
+		log.Fatal(errors.Wrap(err, "Validate config"))
+	}
 	if isNewCfg {
 		log.Infof("New config is written to %v, please verify it and start this program again", flags.ProgramCfgPath)
 		os.Exit(0)
 	}
 
+	// Commands which do not send anything to astra have no outcome
+	switch flags.Command {
+	case cli.ServeCmd:
+		serve(log, flags, cfg)
+		return
+	case cli.ReportCmd:
+		report(log, flags, cfg)
+		return
+	case cli.ExportCmd:
+		export(log, flags, cfg)
+		return
+	case cli.PlanCmd:
+		plan(log, flags, cfg)
+		return
+	}
+
+	var outcome api.Outcome
+	switch flags.Command {
+	case cli.RestoreCmd:
+		outcome = restore(log, flags, cfg)
+	case cli.ApplyCmd:
+		outcome = apply(log, flags, cfg)
+	default:
+		if outcome, err = run(log, flags, cfg); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	log.InfoFi("Done", "outcome", outcome)
+	if code := exitCodes[outcome]; code != 0 {
+		logFile.Close()
+		os.Exit(code)
+	}
+}
+
+// exitCodes represents exit codes of the program for outcomes of applying changes to astra. Fatal errors exit with 255.
+var exitCodes = map[api.Outcome]int{api.Applied: 0, api.NothingChanged: 2, api.PartiallyFailed: 3, api.Aborted: 4}
+
+// run fetches astra config and M3U channels, merges them according to <cfg>, rebases changes on top of astra config
+// refetched just before sending, sends them to astra and returns outcome of sending.
+//
+// Changes conflicting with edits made in astra meanwhile are skipped and result in PartiallyFailed outcome.
+//
+// Returns error if astra config or M3U channels can't be fetched or snapshot of astra config can't be saved.
+func run(log *logger.Logger, flags cli.Flags, cfg cfg.Root) (api.Outcome, error) {
 	// Fetch astra config
 	log.Info("Fetching astra config")
-	apiHttpClient := network.NewHttpClient(cfg.General.AstraAPIRespTimeout)
-	apiHandler := api.NewHandler(log, apiHttpClient, flags.AstraAddr, flags.AstraUser, flags.AstraPwd)
+	apiHandler := newAPIHandler(log, flags, cfg)
 	astraCfg, err := apiHandler.FetchCfg()
 	if err != nil {
-		log.Fatal(err)
+		return api.NothingChanged, err
 	}
 
-	// Fetch M3U channels
-	log.Info("Fetching M3U channels")
-	m3uHttpClient := network.NewHttpClient(cfg.M3U.RespTimeout)
-	m3uResp, err := openuri.Open(flags.M3UPath, openuri.WithHTTPClient(m3uHttpClient))
+	// Fetch M3U channels and merge astra config with them
+	modifiedCats, modifiedStreams, err := mergeCfg(log, flags, cfg, astraCfg)
 	if err != nil {
-		log.Fatal(err)
+		return api.NothingChanged, err
 	}
-	defer m3uResp.Close()
+	astraRepo := astra.NewRepo(log, cfg)
 
-	// Parse and preprocess M3U channels
-	m3uRepo := m3u.NewRepo(log, cfg)
+	// Search for changes
+	changedCatMap := astraRepo.ChangedCategories(astraCfg.Categories, modifiedCats)
+	changedStreams := astraRepo.ChangedStreams(astraCfg.Streams, modifiedStreams)
+
+	// Print planned changes and exit without sending them if dry run is requested
+	if flags.DryRun {
+		log.Info("Dry run, changes are not sent to astra")
+		return api.NothingChanged, printDiff(astraRepo.Diff(astraCfg.Streams, modifiedStreams, astraCfg.Categories,
+			modifiedCats))
+	}
+
+	// Sending changes to astra
+	if !flags.Noninteractive && !input.AskYesNo(log, os.Stdin, "Send changes to astra (Y/N)? ") {
+		return api.NothingChanged, nil
+	}
+
+	// Astra config could be edited while merging, so rebase changes on top of the current config
+	log.Info("Fetching current astra config")
+	currentCfg, err := apiHandler.FetchCfg()
+	if err != nil {
+		return api.NothingChanged, err
+	}
+	changedCatMap, catConflicts := astraRepo.RebaseCategories(astraCfg.Categories, currentCfg.Categories,
+		changedCatMap)
+	changedStreams, streamConflicts := astraRepo.RebaseStreams(astraCfg.Streams, currentCfg.Streams, changedStreams)
+
+	outcome, err := send(log, flags, cfg, apiHandler, currentCfg, changedCatMap, changedStreams)
+	if err != nil {
+		return outcome, err
+	}
 
-	m3uChannels := m3uRepo.Parse(m3uResp)
-	m3uChannels = m3uRepo.Sort(m3uChannels)
-	if len(cfg.M3U.ChannGroupMap) > 0 {
-		m3uChannels = m3uRepo.ReplaceGroups(m3uChannels)
+	if len(catConflicts) > 0 || len(streamConflicts) > 0 {
+		log.WarnFi("Some changes are skipped due to concurrent edits in astra", "categories", len(catConflicts),
+			"streams", len(streamConflicts))
+		if outcome == api.Applied || outcome == api.NothingChanged {
+			return api.PartiallyFailed, nil
+		}
 	}
-	if !slice.IsAllEmpty(cfg.M3U.ChannNameBlacklist, cfg.M3U.ChannGroupBlacklist, cfg.M3U.ChannURLBlacklist) {
-		m3uChannels = m3uRepo.RemoveBlocked(m3uChannels)
+
+	return outcome, nil
+}
+
+// mergeCfg fetches M3U channels and returns categories and streams of <astraCfg> merged with them according to <cfg>.
+//
+// Returns error if M3U channels can't be fetched.
+func mergeCfg(log *logger.Logger, flags cli.Flags, cfg cfg.Root, astraCfg astra.Cfg) ([]astra.Category,
+	[]astra.Stream, error) {
+	// Fetch and preprocess M3U channels
+	m3uChannels, err := fetchChannels(log, flags, cfg)
+	if err != nil {
+		return nil, nil, err
 	}
 
 	// Update astra streams with data from M3U channels and run extra operations such as sorting or disabling streams
-	// without inputs
+	// without inputs in order of the pipeline
 	astraRepo := astra.NewRepo(log, cfg)
 	mergeRepo := merge.NewRepo(log, cfg)
 
 	modifiedStreams := copier.MustDeep(astraCfg.Streams)
 	modifiedStreams = astraRepo.RemoveNamePrefixes(modifiedStreams)
 	modifiedStreams = astraRepo.Sort(modifiedStreams)
-	if cfg.Streams.Rename {
-		modifiedStreams = mergeRepo.RenameStreams(modifiedStreams, m3uChannels)
-	}
-	if len(cfg.Streams.InputBlacklist) > 0 {
-		modifiedStreams = astraRepo.RemoveBlockedInputs(modifiedStreams)
-	}
-	if cfg.Streams.RemoveDuplicatedInputs {
-		modifiedStreams = astraRepo.RemoveDuplicatedInputs(modifiedStreams)
-	}
-	if len(cfg.Streams.RemoveDuplicatedInputsByRxList) > 0 {
-		modifiedStreams = astraRepo.RemoveDuplicatedInputsByRx(modifiedStreams)
-	}
-	if cfg.Streams.RemoveDisabledInputs {
-		modifiedStreams = astraRepo.RemoveDisabledInputs(modifiedStreams)
-	}
-	if cfg.Streams.UpdateInputs {
-		modifiedStreams = mergeRepo.UpdateInputs(modifiedStreams, m3uChannels)
-	}
-	if cfg.Streams.RemoveInputsByUpdateMap {
-		modifiedStreams = mergeRepo.RemoveInputsByUpdateMap(modifiedStreams, m3uChannels)
-	}
-	if cfg.Streams.AddNewInputs {
-		modifiedStreams = mergeRepo.AddNewInputs(modifiedStreams, m3uChannels)
-	}
-	if cfg.Streams.UniteInputs {
-		modifiedStreams = astraRepo.UniteInputs(modifiedStreams)
-	}
-	if cfg.Streams.SortInputs {
-		modifiedStreams = astraRepo.SortInputs(modifiedStreams)
-	}
-	if cfg.Streams.AddNew {
-		modifiedStreams = mergeRepo.AddNewStreams(modifiedStreams, m3uChannels)
-	}
-	if cfg.Streams.RemoveDeadInputs {
-		httpClient := network.NewHttpClient(cfg.Streams.InputRespTimeout)
-		analyzer := analyzer.New(log, cfg.Streams.AnalyzerAddr, cfg.Streams.InputRespTimeout)
-		modifiedStreams = astraRepo.RemoveDeadInputs(httpClient, analyzer, modifiedStreams)
-	} else if cfg.Streams.DisableDeadInputs {
-		httpClient := network.NewHttpClient(cfg.Streams.InputRespTimeout)
-		analyzer := analyzer.New(log, cfg.Streams.AnalyzerAddr, cfg.Streams.InputRespTimeout)
-		modifiedStreams = astraRepo.DisableDeadInputs(httpClient, analyzer, modifiedStreams)
-	}
-	if !slice.IsAllEmpty(cfg.Streams.NameToInputHashMap, cfg.Streams.GroupToInputHashMap,
-		cfg.Streams.InputToInputHashMap) {
-		modifiedStreams = astraRepo.AddHashes(modifiedStreams)
-	}
-	if len(cfg.Streams.DisableAllButOneInputByRxList) > 0 {
-		modifiedStreams = astraRepo.DisableAllButOneInputByRx(modifiedStreams)
-	}
-	if !slice.IsAllEmpty(cfg.Streams.NameToKeepActiveMap, cfg.Streams.GroupToKeepActiveMap,
-		cfg.Streams.InputToKeepActiveMap) {
-		modifiedStreams = astraRepo.SetKeepActive(modifiedStreams)
-	}
-	if cfg.Streams.RemoveWithoutInputs {
-		modifiedStreams = astraRepo.RemoveWithoutInputs(modifiedStreams)
-	} else if cfg.Streams.DisableWithoutInputs {
-		modifiedStreams = astraRepo.DisableWithoutInputs(modifiedStreams)
-	}
+	modifiedStreams = mergeRepo.RunPipeline(modifiedStreams, m3uChannels)
 	modifiedStreams = astraRepo.AddNamePrefixes(modifiedStreams)
 
 	// Update astra categories
@@ -174,19 +204,64 @@ func main() {
 	}
 	modifiedCats = astraRepo.UpdateCategories(modifiedCats, modifiedStreams)
 
-	// Search for changes
-	changedCatMap := astraRepo.ChangedCategories(astraCfg.Categories, modifiedCats)
-	changedStreams := astraRepo.ChangedStreams(astraCfg.Streams, modifiedStreams)
+	return modifiedCats, modifiedStreams, nil
+}
 
-	// Sending changes to astra
-	sendChangesAllowed := true
-	if !flags.Noninteractive {
-		sendChangesAllowed = input.AskYesNo(log, os.Stdin, "Send changes to astra (Y/N)? ")
+// send saves snapshot of <current> astra config to the backup directory specified in <flags>, sends <idxCategoryMap>
+// and <streams> to astra using <apiHandler> and verifies them if requested in <flags>. Returns outcome of sending.
+//
+// Returns error if snapshot of astra config can't be saved or astra config can't be fetched for verification.
+func send(log *logger.Logger, flags cli.Flags, cfg cfg.Root, apiHandler api.Handler, current astra.Cfg,
+	idxCategoryMap []lo.Entry[int, astra.Category], streams []astra.Stream) (api.Outcome, error) {
+	astraRepo := astra.NewRepo(log, cfg)
+	if flags.BackupDir != "" && (len(idxCategoryMap) > 0 || len(streams) > 0) {
+		if _, err := astraRepo.SaveSnapshot(flags.BackupDir, flags.BackupKeep, current); err != nil {
+			return api.NothingChanged, err
+		}
+	}
+	summary := api.Apply(log, apiHandler, astraRepo, current, idxCategoryMap, streams, flags.Atomic)
+	if flags.Verify && (summary.Outcome == api.Applied || summary.Outcome == api.PartiallyFailed) {
+		drift, err := api.Verify(log, apiHandler, astraRepo, summary, flags.VerifyRetries)
+		if err != nil {
+			return summary.Outcome, err
+		}
+		if len(drift) > 0 {
+			summary.Outcome = api.PartiallyFailed
+		}
+	}
+	return summary.Outcome, nil
+}
+
+// newAPIHandler returns handler of astra config file if it is specified in <flags> or handler of astra API otherwise
+func newAPIHandler(log *logger.Logger, flags cli.Flags, cfg cfg.Root) api.Handler {
+	if flags.AstraCfgFile != "" {
+		return api.NewFileHandler(log, flags.AstraCfgFile, flags.AstraCfgOutFile)
+	}
+	apiHttpClient := network.NewHttpClient(cfg.General.AstraAPIRespTimeout)
+	return api.NewHandler(log, cfg.General, apiHttpClient, flags.AstraAddr, flags.AstraUser, flags.AstraPwd)
+}
+
+// fetchChannels returns preprocessed M3U channels of all sources from <cfg> and the one specified in <flags>.
+//
+// Returns error if no sources specified or any of them can't be fetched.
+func fetchChannels(log *logger.Logger, flags cli.Flags, cfg cfg.Root) ([]m3u.Channel, error) {
+	sources := copier.MustDeep(cfg.M3U.Sources)
+	if flags.M3UPath != "" {
+		sources = append(sources, cfg.M3U.NewSource("Command line", flags.M3UPath))
 	}
-	if sendChangesAllowed {
-		apiHandler.SetCategories(changedCatMap)
-		apiHandler.SetStreams(changedStreams)
+	if len(sources) == 0 {
+		return nil, errors.New("No M3U sources specified, set M3U path in command line arguments or in program config")
 	}
+	return m3u.NewRepo(log, cfg).FetchAll(sources)
+}
 
-	log.Info("Done")
+// printDiff prints human-readable and JSON reports of <diff> to stdout
+func printDiff(diff astra.Diff) error {
+	diffJSON, err := diff.JSON()
+	if err != nil {
+		return err
+	}
+	fmt.Print(diff.String())
+	fmt.Println(string(diffJSON))
+	return nil
 }
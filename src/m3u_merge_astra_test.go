@@ -36,7 +36,7 @@ func TestMain(t *testing.T) {
 	// Read initial astra config
 	log := logger.New(logger.DebugLevel)
 	apiHttpClient := network.NewHttpClient(time.Second * 10)
-	apiHandler := api.NewHandler(log, apiHttpClient, "http://127.0.0.1:8000", "admin", "admin")
+	apiHandler := api.NewHandler(log, cfg.NewDefCfg().General, apiHttpClient, "http://127.0.0.1:8000", "admin", "admin")
 	astraCfg, err := apiHandler.FetchCfg()
 	assert.NoError(t, err, "should fetch astra config")
 
@@ -76,7 +76,7 @@ func TestMain(t *testing.T) {
 			Inputs:  []string{"http://url/2"},
 			Groups:  map[string]string{"Category 1": "Group 2"},
 		},
-	})
+	}, false)
 
 	// Run program
 	os.Args = []string{"", "-n", "-l", "2", "-c", programCfgPath, "-m", m3uPath, "-u", "admin", "-p", "admin"}
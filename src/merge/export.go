@@ -0,0 +1,81 @@
+package merge
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+
+	"m3u_merge_astra/astra"
+	"m3u_merge_astra/m3u"
+
+	"github.com/samber/lo"
+)
+
+// ExportOptions represents settings of exporting astra streams to M3U channels
+type ExportOptions struct {
+	// URLTemplate represents URL of astra HTTP output of a stream. Placeholders {id} and {name} are replaced with ID
+	// and URL encoded name of the stream. If empty, channels with this URL are not exported.
+	URLTemplate string
+
+	// AllGroups specifies if group-title should contain all groups of the stream separated by ';' instead of the first
+	// one.
+	AllGroups bool
+
+	// Inputs specifies if every input of the stream should be exported as a separate channel
+	Inputs bool
+
+	// ExcludeDisabled specifies if disabled streams should be omitted
+	ExcludeDisabled bool
+}
+
+// Export returns M3U channels generated from <streams> according to <opts>.
+//
+// Every stream becomes a channel with URL built from opts.URLTemplate, optionally followed by channels for every input
+// of the stream. Group of cfg.General.TVGIDCategory is exported as tvg-id instead of group-title.
+func (r repo) Export(streams []astra.Stream, opts ExportOptions) (out []m3u.Channel) {
+	r.log.Info("Exporting streams to M3U channels")
+
+	for _, s := range streams {
+		if opts.ExcludeDisabled && !s.Enabled {
+			r.log.DebugFi("Skipping disabled stream", "ID", s.ID, "name", s.Name)
+			continue
+		}
+		ch := m3u.Channel{
+			Name:     s.Name,
+			Group:    r.exportGroup(s, opts.AllGroups),
+			Duration: -1,
+			TVGID:    s.GetTVGID(r.cfg.General),
+		}
+		if opts.URLTemplate != "" {
+			ch.URL = strings.NewReplacer("{id}", s.ID, "{name}", url.PathEscape(s.Name)).Replace(opts.URLTemplate)
+			out = append(out, ch)
+		}
+		if opts.Inputs {
+			for _, input := range s.Inputs {
+				ch.URL = input
+				out = append(out, ch)
+			}
+		}
+	}
+
+	r.log.InfoFi("Exported streams to M3U channels", "streams", len(streams), "channels", len(out))
+
+	return
+}
+
+// exportGroup returns group-title of M3U channel for stream <s> without group of cfg.General.TVGIDCategory.
+//
+// If <all> is true, returns every "category: group" pair sorted alphabetically and separated by ';', otherwise
+// returns the first one.
+func (r repo) exportGroup(s astra.Stream, all bool) string {
+	s.Groups = lo.OmitByKeys(s.Groups, []string{r.cfg.General.TVGIDCategory})
+	if !all {
+		return s.FirstGroup()
+	}
+	pairs := lo.MapToSlice(s.Groups, func(cat, group string) string {
+		return fmt.Sprintf("%v: %v", cat, group)
+	})
+	sort.Strings(pairs)
+	return strings.Join(pairs, ";")
+}
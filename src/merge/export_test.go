@@ -0,0 +1,61 @@
+package merge
+
+import (
+	"testing"
+
+	"m3u_merge_astra/astra"
+	"m3u_merge_astra/m3u"
+	"m3u_merge_astra/util/copier"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/zenizh/go-capturer"
+)
+
+func TestExport(t *testing.T) {
+	r := newDefRepo()
+	r.cfg.General.TVGIDCategory = "TVG ID"
+
+	sl := []astra.Stream{
+		{ID: "0", Name: "Stream 0", Enabled: true, Inputs: []string{"http://input/0"},
+			Groups: map[string]string{"Cat B": "Grp B", "Cat A": "Grp A", "TVG ID": "id.tv"}},
+		{ID: "1", Name: "Stream 1/HD", Enabled: false, Inputs: []string{"http://input/1a", "http://input/1b"}},
+	}
+	slOriginal := copier.TestDeep(t, sl)
+
+	opts := ExportOptions{URLTemplate: "http://astra:8000/play/{id}?name={name}"}
+	actual := r.Export(sl, opts)
+	assert.Exactly(t, slOriginal, sl, "should not modify the source streams")
+
+	expected := []m3u.Channel{
+		{Name: "Stream 0", Group: "Cat A: Grp A", URL: "http://astra:8000/play/0?name=Stream%200", Duration: -1,
+			TVGID: "id.tv"},
+		{Name: "Stream 1/HD", URL: "http://astra:8000/play/1?name=Stream%201%2FHD", Duration: -1},
+	}
+	assert.Exactly(t, expected, actual, "should export every stream with URL built from template")
+
+	opts = ExportOptions{AllGroups: true, Inputs: true, ExcludeDisabled: true}
+	actual = r.Export(sl, opts)
+
+	expected = []m3u.Channel{
+		{Name: "Stream 0", Group: "Cat A: Grp A;Cat B: Grp B", URL: "http://input/0", Duration: -1, TVGID: "id.tv"},
+	}
+	assert.Exactly(t, expected, actual, "should export only inputs of enabled streams with all groups")
+
+	opts = ExportOptions{URLTemplate: "http://astra/{id}", Inputs: true}
+	actual = r.Export(sl[1:], opts)
+
+	expected = []m3u.Channel{
+		{Name: "Stream 1/HD", URL: "http://astra/1", Duration: -1},
+		{Name: "Stream 1/HD", URL: "http://input/1a", Duration: -1},
+		{Name: "Stream 1/HD", URL: "http://input/1b", Duration: -1},
+	}
+	assert.Exactly(t, expected, actual, "should export output URL followed by inputs")
+
+	// Test log output
+	out := capturer.CaptureStderr(func() {
+		r := newDefRepo()
+		_ = r.Export(sl, ExportOptions{URLTemplate: "http://astra/{id}", ExcludeDisabled: true})
+	})
+	assert.Contains(t, out, `Skipping disabled stream: ID "1", name "Stream 1/HD"`)
+	assert.Contains(t, out, `Exported streams to M3U channels: streams "2", channels "1"`)
+}
@@ -9,13 +9,13 @@ import (
 	"github.com/samber/lo"
 )
 
-// RenameStreams returns shallow copy of <streams> with names taken from <channels> if their standardized names are
-// equal.
+// RenameStreams returns shallow copy of <streams> with names taken from <channels> if their tvg-id or standardized
+// names are equal (see util/slice/find.Matching).
 func (r repo) RenameStreams(streams []astra.Stream, channels []m3u.Channel) (out []astra.Stream) {
 	r.log.Info("Renaming streams")
 
 	for _, s := range streams {
-		ch, _, chFound := find.Named(r.cfg.General, channels, s.Name)
+		ch, _, chFound := find.Matching(r.cfg.General, channels, s.GetTVGID(r.cfg.General), s.Name)
 		if chFound && s.Name != ch.Name {
 			r.log.InfoFi("Renaming stream", "ID", s.ID, "old name", s.Name, "new name", ch.Name,
 				"group", s.FirstGroup())
@@ -35,7 +35,7 @@ func (r repo) UpdateInputs(streams []astra.Stream, channels []m3u.Channel) (out
 	r.log.Info("Updating inputs of streams")
 
 	for _, s := range streams {
-		find.EverySimilar(r.cfg.General, channels, s.Name, 0, func(ch m3u.Channel, _ int) {
+		find.EveryMatching(r.cfg.General, channels, s.GetTVGID(r.cfg.General), s.Name, 0, func(ch m3u.Channel, _ int) {
 			if !s.HasInput(r.log, ch.URL, true) {
 				var updated bool
 				s, updated = s.UpdateInput(r, ch.URL, func(oldURL string) {
@@ -63,7 +63,7 @@ func (r repo) RemoveInputsByUpdateMap(streams []astra.Stream, channels []m3u.Cha
 	m3uRepo := m3u.NewRepo(r.log, r.cfg)
 
 	for _, s := range streams {
-		similarChannels := find.GetSimilar(r.cfg.General, channels, s.Name)
+		similarChannels := find.GetMatching(r.cfg.General, channels, s.GetTVGID(r.cfg.General), s.Name)
 		for _, knownInp := range s.KnownInputs(r.cfg.Streams) {
 			if !m3uRepo.HasURL(similarChannels, knownInp, false) {
 				s = s.RemoveInputsCb(knownInp, func() {
@@ -85,7 +85,7 @@ func (r repo) AddNewInputs(streams []astra.Stream, channels []m3u.Channel) (out
 	r.log.Info("Adding new inputs to streams")
 
 	for _, s := range streams {
-		find.EverySimilar(r.cfg.General, channels, s.Name, 0, func(ch m3u.Channel, _ int) {
+		find.EveryMatching(r.cfg.General, channels, s.GetTVGID(r.cfg.General), s.Name, 0, func(ch m3u.Channel, _ int) {
 			if !s.HasInput(r.log, ch.URL, r.cfg.Streams.HashCheckOnAddNewInputs) {
 				r.log.InfoFi("Adding new input to stream", "ID", s.ID, "name", s.Name, "group", s.FirstGroup(),
 					"URL", ch.URL, "note", s.InputsUpdateNote(r.cfg.Streams))
@@ -103,7 +103,11 @@ func (r repo) AddNewInputs(streams []astra.Stream, channels []m3u.Channel) (out
 	return
 }
 
-// AddNewStreams returns <streams> with new streams generated from <channels> if no such found in <streams>
+// AddNewStreams returns <streams> with new streams generated from <channels> if no such found in <streams>.
+//
+// If cfg.General.TVGIDCategory is set, new streams get tvg-id of the channel as group of this category.
+//
+// New streams are created from templates of cfg.Streams.*ToNewStreamTemplateMap if any match the channel.
 func (r repo) AddNewStreams(streams []astra.Stream, channels []m3u.Channel) []astra.Stream {
 	r.log.Info("Adding new streams")
 
@@ -113,9 +117,15 @@ func (r repo) AddNewStreams(streams []astra.Stream, channels []m3u.Channel) []as
 		if !r.cfg.Streams.AddNewWithKnownInputs && astraRepo.HasInput(streams, ch.URL, false) {
 			continue
 		}
-		if !find.HasAnySimilar(r.cfg.General, streams, ch.Name) {
+		if !find.HasMatching(r.cfg.General, streams, ch.TVGID, ch.Name) {
 			id := generateUID(streams)
-			stream := astra.NewStream(r.cfg.Streams, id, ch.Name, ch.Group, []string{ch.URL})
+			stream, err := astra.NewStream(r.cfg.Streams, id, ch.Name, ch.Group, []string{ch.URL})
+			if err != nil {
+				r.log.Error(err)
+			}
+			if r.cfg.General.TVGIDMatching && r.cfg.General.TVGIDCategory != "" && ch.TVGID != "" {
+				stream = stream.SetTVGID(r.cfg.General, ch.TVGID)
+			}
 			r.log.InfoFi("Adding new stream", "ID", id, "name", ch.Name, "group", stream.FirstGroup(), "input", ch.URL)
 			streams = append(streams, stream)
 		}
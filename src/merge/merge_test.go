@@ -45,6 +45,25 @@ func TestRenameStreams(t *testing.T) {
 	expected = astra.Stream{Name: "Other name A"}
 	assert.Exactly(t, expected, sl2[2], "should not rename stream if no channel counterpart name found")
 
+	// Test tvg-id matching
+	r.cfg.General.TVGIDCategory = "TVG ID"
+	sl1 = []astra.Stream{
+		{Name: "Sport", Groups: map[string]string{"TVG ID": "sport.uk"}},
+		{ID: "1", Name: "News"},
+	}
+	r.cfg.General.StreamTVGIDMap = map[string]string{"1": "news.uk"}
+	cl1 = []m3u.Channel{
+		{Name: "News", TVGID: "news.us"}, {Name: "Sport", TVGID: "sport.us"}, {Name: "Sport FHD", TVGID: "sport.uk"},
+	}
+
+	sl2 = r.RenameStreams(sl1, cl1)
+
+	expected = astra.Stream{Name: "Sport FHD", Groups: map[string]string{"TVG ID": "sport.uk"}}
+	assert.Exactly(t, expected, sl2[0], "should rename stream to it's channel counterpart with the same tvg-id")
+
+	expected = astra.Stream{ID: "1", Name: "News"}
+	assert.Exactly(t, expected, sl2[1], "should not rename stream if channel with the same name has other tvg-id")
+
 	// Test log output
 	out := capturer.CaptureStderr(func() {
 		r := newDefRepo()
@@ -477,6 +496,31 @@ func TestAddNewStreams(t *testing.T) {
 	assert.Exactly(t, sl1, sl2, "should not change as AddNewStreamsWithKnownInputs = false and hash difference should"+
 		"be ignored")
 
+	// Test tvg-id matching
+	r.cfg.General.TVGIDCategory = "TVG ID"
+	r.cfg.Streams.AddNewWithKnownInputs = true
+	r.cfg.Streams.AddGroupsToNew = false
+	sl1 = []astra.Stream{{Name: "Sport", Groups: map[string]string{"TVG ID": "sport.uk"}}}
+	cl1 = []m3u.Channel{
+		{Name: "Sport FHD", URL: "http://sport/uk", TVGID: "sport.uk"},
+		{Name: "Sport", URL: "http://sport/us", TVGID: "sport.us"},
+	}
+	sl2 = r.AddNewStreams(sl1, cl1)
+
+	assert.Len(t, sl2, 2, "should add new stream")
+	expected = astra.Stream{
+		DisabledInputs: make([]string, 0),
+		Enabled:        r.cfg.Streams.MakeNewEnabled,
+		Groups:         map[string]string{"TVG ID": "sport.us"},
+		HTTPKeepActive: strconv.Itoa(r.cfg.Streams.NewKeepActive),
+		ID:             sl2[1].ID,
+		Inputs:         []string{"http://sport/us"},
+		Name:           "Sport",
+		Type:           string(r.cfg.Streams.NewType),
+		MarkAdded:      true,
+	}
+	assert.Exactly(t, expected, sl2[1], "should add stream for channel with the same name but other tvg-id")
+
 	// Test log output
 	out := capturer.CaptureStderr(func() {
 		r := newDefRepo()
@@ -490,6 +534,29 @@ func TestAddNewStreams(t *testing.T) {
 	})
 	assert.Contains(t, out, fmt.Sprintf(`Adding new stream: ID "%v", name "Name 1", group "All: Grp", `+
 		`input "http://url/1"`, sl2[0].ID))
+
+	// Test templates
+	out = capturer.CaptureStderr(func() {
+		r := newDefRepo()
+		r.cfg.Streams.GroupToNewStreamTemplateMap = []cfg.NewStreamTemplate{
+			{By: *regexp.MustCompile(`^Radio$`), Stream: map[string]any{"timeout": 30, "enable": false}},
+			{By: *regexp.MustCompile(`^Bad$`), Stream: map[string]any{"timeout": "abc"}},
+		}
+
+		sl1 := []astra.Stream{}
+
+		cl1 := []m3u.Channel{
+			{Name: "Name 1", Group: "Radio", URL: "http://url/1"},
+			{Name: "Name 2", Group: "Bad", URL: "http://url/2"},
+		}
+
+		sl2 = r.AddNewStreams(sl1, cl1)
+	})
+	assert.Len(t, sl2, 2, "should add new streams")
+	assert.Exactly(t, lo.ToPtr(astra.Number(30)), sl2[0].Timeout, "should apply template matching the group")
+	assert.False(t, sl2[0].Enabled, "should apply template matching the group")
+	assert.Nil(t, sl2[1].Timeout, "should add stream without invalid template")
+	assert.Contains(t, out, `Apply template "^Bad$" to new stream`, "should log error of invalid template")
 }
 
 func TestGenerateUID(t *testing.T) {
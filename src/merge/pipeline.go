@@ -0,0 +1,80 @@
+package merge
+
+import (
+	"m3u_merge_astra/astra"
+	"m3u_merge_astra/astra/analyzer"
+	"m3u_merge_astra/cfg"
+	"m3u_merge_astra/m3u"
+	"m3u_merge_astra/util/network"
+)
+
+// RunPipeline returns <streams> processed by steps of cfg.Streams.Steps in order, using <channels> as the source of
+// new data
+func (r repo) RunPipeline(streams []astra.Stream, channels []m3u.Channel) []astra.Stream {
+	astraRepo := astra.NewRepo(r.log, r.cfg)
+
+	for _, step := range r.cfg.Streams.Steps() {
+		r.log.Debugf("Running pipeline step %v", step)
+		switch step {
+		case cfg.RenameStep:
+			streams = r.RenameStreams(streams, channels)
+		case cfg.RemoveBlockedInputsStep:
+			streams = astraRepo.RemoveBlockedInputs(streams)
+		case cfg.RemoveDuplicatedInputsStep:
+			streams = astraRepo.RemoveDuplicatedInputs(streams)
+		case cfg.RemoveDuplicatedInputsByRxStep:
+			streams = astraRepo.RemoveDuplicatedInputsByRx(streams)
+		case cfg.RemoveDisabledInputsStep:
+			streams = astraRepo.RemoveDisabledInputs(streams)
+		case cfg.UpdateInputsStep:
+			streams = r.UpdateInputs(streams, channels)
+		case cfg.RemoveInputsByUpdateMapStep:
+			streams = r.RemoveInputsByUpdateMap(streams, channels)
+		case cfg.AddNewInputsStep:
+			streams = r.AddNewInputs(streams, channels)
+		case cfg.UniteInputsStep:
+			streams = astraRepo.UniteInputs(streams)
+		case cfg.SortInputsStep:
+			streams = astraRepo.SortInputs(streams)
+		case cfg.AddNewStep:
+			streams = r.AddNewStreams(streams, channels)
+		case cfg.ReviveDisabledInputsStep:
+			httpClient := network.NewHttpClient(r.cfg.Streams.InputRespTimeout)
+			analyzer := r.newAnalyzer()
+			streams = astraRepo.ReviveDisabledInputs(httpClient, analyzer, streams)
+		case cfg.RemoveDeadInputsStep:
+			httpClient := network.NewHttpClient(r.cfg.Streams.InputRespTimeout)
+			analyzer := r.newAnalyzer()
+			streams = astraRepo.RemoveDeadInputs(httpClient, analyzer, streams)
+		case cfg.DisableDeadInputsStep:
+			httpClient := network.NewHttpClient(r.cfg.Streams.InputRespTimeout)
+			analyzer := r.newAnalyzer()
+			streams = astraRepo.DisableDeadInputs(httpClient, analyzer, streams)
+		case cfg.AddHashesStep:
+			streams = astraRepo.AddHashes(streams)
+		case cfg.DisableAllButOneInputByRxStep:
+			streams = astraRepo.DisableAllButOneInputByRx(streams)
+		case cfg.SetKeepActiveStep:
+			streams = astraRepo.SetKeepActive(streams)
+		case cfg.SetOutputsStep:
+			streams = astraRepo.SetOutputs(streams)
+		case cfg.ApplyRulesStep:
+			streams = r.ApplyRules(streams, channels)
+		case cfg.RemoveWithoutInputsStep:
+			streams = astraRepo.RemoveWithoutInputs(streams)
+		case cfg.DisableWithoutInputsStep:
+			streams = astraRepo.DisableWithoutInputs(streams)
+		}
+	}
+
+	return streams
+}
+
+// newAnalyzer returns built-in MPEG-TS prober if cfg.Streams.UseNativeAnalyzer is true or astra analyzer client
+// otherwise
+func (r repo) newAnalyzer() analyzer.Analyzer {
+	if r.cfg.Streams.UseNativeAnalyzer {
+		return analyzer.NewProbe(r.log, r.cfg.Streams.InputRespTimeout)
+	}
+	return analyzer.New(r.log, r.cfg.Streams.AnalyzerAddr, r.cfg.Streams.InputRespTimeout)
+}
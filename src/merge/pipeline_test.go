@@ -0,0 +1,39 @@
+package merge
+
+import (
+	"regexp"
+	"testing"
+
+	"m3u_merge_astra/astra"
+	"m3u_merge_astra/cfg"
+	"m3u_merge_astra/util/copier"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRunPipeline(t *testing.T) {
+	r := newDefRepo()
+	r.cfg.Streams.Rules = []cfg.Rule{
+		{Name: "Remove inputs", Actions: []cfg.RuleAction{{RemoveInput: regexp.MustCompile(`^http://dead/`)}}},
+	}
+
+	sl1 := []astra.Stream{
+		{ID: "0000", Name: "Name", Enabled: true, Inputs: []string{"http://dead/1"}},
+	}
+	sl1Original := copier.TestDeep(t, sl1)
+
+	r.cfg.Streams.Pipeline = []cfg.PipelineStep{cfg.ApplyRulesStep, cfg.RemoveWithoutInputsStep}
+	sl2 := r.RunPipeline(sl1, nil)
+	assert.Exactly(t, sl1Original, sl1, "should not modify the source")
+	assert.True(t, sl2[0].Remove, "should remove stream as rules removed all inputs before")
+	assert.Empty(t, sl2[0].Inputs, "should remove inputs by rules")
+
+	r.cfg.Streams.Pipeline = []cfg.PipelineStep{cfg.RemoveWithoutInputsStep, cfg.ApplyRulesStep}
+	sl2 = r.RunPipeline(sl1, nil)
+	assert.False(t, sl2[0].Remove, "should not remove stream as it had inputs before rules run")
+	assert.Empty(t, sl2[0].Inputs, "should remove inputs by rules")
+
+	r.cfg.Streams.Pipeline = []cfg.PipelineStep{cfg.RemoveWithoutInputsStep}
+	sl2 = r.RunPipeline(sl1, nil)
+	assert.Exactly(t, sl1, sl2, "should not run steps missing in the pipeline")
+}
@@ -0,0 +1,125 @@
+package merge
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"m3u_merge_astra/astra"
+	"m3u_merge_astra/m3u"
+	"m3u_merge_astra/util/compare"
+	"m3u_merge_astra/util/slice/find"
+
+	"github.com/samber/lo"
+)
+
+// Report represents streams and M3U channels which do not match each other
+type Report struct {
+	UnmatchedChannels []m3u.Channel
+	UnmatchedStreams  []astra.Stream
+	NearMisses        []NearMiss // Sorted by score from best to worst
+}
+
+// NearMiss represents unmatched stream and unmatched M3U channel with slightly different names
+type NearMiss struct {
+	Stream  astra.Stream
+	Channel m3u.Channel
+	Score   float64
+}
+
+// Report returns report of <streams> and <channels> which do not match each other.
+//
+// Unmatched stream and unmatched channel are considered a near miss if their name similarity score (see
+// util/compare.FuzzyScore) is not less than <threshold>.
+func (r repo) Report(streams []astra.Stream, channels []m3u.Channel, threshold float64) (out Report) {
+	r.log.Info("Building report of unmatched streams and M3U channels")
+
+	out.UnmatchedChannels = lo.Filter(channels, func(ch m3u.Channel, _ int) bool {
+		return !find.HasMatching(r.cfg.General, streams, ch.TVGID, ch.Name)
+	})
+	out.UnmatchedStreams = lo.Filter(streams, func(s astra.Stream, _ int) bool {
+		return !find.HasMatching(r.cfg.General, channels, s.GetTVGID(r.cfg.General), s.Name)
+	})
+
+	for _, s := range out.UnmatchedStreams {
+		for _, ch := range out.UnmatchedChannels {
+			score := compare.FuzzyScore(r.cfg.General, s.Name, ch.Name)
+			if score < threshold {
+				continue
+			}
+			known := lo.ContainsBy(out.NearMisses, func(nm NearMiss) bool {
+				return nm.Stream.ID == s.ID && nm.Channel.Name == ch.Name
+			})
+			if !known {
+				out.NearMisses = append(out.NearMisses, NearMiss{Stream: s, Channel: ch, Score: score})
+			}
+		}
+	}
+	sort.SliceStable(out.NearMisses, func(i, j int) bool {
+		return out.NearMisses[i].Score > out.NearMisses[j].Score
+	})
+
+	r.log.InfoFi("Built report", "unmatched channels", len(out.UnmatchedChannels),
+		"unmatched streams", len(out.UnmatchedStreams), "near misses", len(out.NearMisses))
+
+	return
+}
+
+// AliasList returns sets of names ready to be added to general.name_alias_list to make near misses in <rep> match.
+//
+// Every set starts with the stream name followed by names of M3U channels similar to it.
+func (rep Report) AliasList() (out [][]string) {
+	for _, nm := range rep.NearMisses {
+		_, idx, found := lo.FindIndexOf(out, func(set []string) bool {
+			return set[0] == nm.Stream.Name
+		})
+		if !found {
+			out = append(out, []string{nm.Stream.Name})
+			idx = len(out) - 1
+		}
+		if !lo.Contains(out[idx], nm.Channel.Name) {
+			out[idx] = append(out[idx], nm.Channel.Name)
+		}
+	}
+	return
+}
+
+// String returns YAML representation of <rep>.
+//
+// The name_alias_list section is ready to be pasted into the program config.
+func (rep Report) String() string {
+	var sb strings.Builder
+
+	sb.WriteString("# M3U channels which do not match any stream:\n")
+	sb.WriteString("unmatched_channels:\n")
+	for _, ch := range rep.UnmatchedChannels {
+		sb.WriteString(fmt.Sprintf("  - name: %v\n", quote(ch.Name)))
+		sb.WriteString(fmt.Sprintf("    group: %v\n", quote(ch.Group)))
+		if ch.Source != "" {
+			sb.WriteString(fmt.Sprintf("    source: %v\n", quote(ch.Source)))
+		}
+	}
+
+	sb.WriteString("\n# Streams which do not match any M3U channel:\n")
+	sb.WriteString("unmatched_streams:\n")
+	for _, s := range rep.UnmatchedStreams {
+		sb.WriteString(fmt.Sprintf("  - id: %v\n", quote(s.ID)))
+		sb.WriteString(fmt.Sprintf("    name: %v\n", quote(s.Name)))
+		sb.WriteString(fmt.Sprintf("    group: %v\n", quote(s.FirstGroup())))
+	}
+
+	sb.WriteString("\n# Near misses (stream name first), add to 'general.name_alias_list' to make them match:\n")
+	sb.WriteString("name_alias_list:\n")
+	for _, set := range rep.AliasList() {
+		for idx, name := range set {
+			sb.WriteString(fmt.Sprintf("  %v %v\n", lo.Ternary(idx == 0, "- -", "  -"), quote(name)))
+		}
+	}
+
+	return sb.String()
+}
+
+// quote returns <inp> as single quoted YAML string
+func quote(inp string) string {
+	return "'" + strings.ReplaceAll(inp, "'", "''") + "'"
+}
@@ -0,0 +1,103 @@
+package merge
+
+import (
+	"testing"
+
+	"m3u_merge_astra/astra"
+	"m3u_merge_astra/m3u"
+	"m3u_merge_astra/util/copier"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/zenizh/go-capturer"
+)
+
+func TestReport(t *testing.T) {
+	r := newDefRepo()
+
+	sl := []astra.Stream{
+		{ID: "0", Name: "Known name"},
+		{ID: "1", Name: "Discovery Channel", Groups: map[string]string{"Cat": "Grp"}},
+		{ID: "2", Name: "Eurosport"},
+	}
+	slOriginal := copier.TestDeep(t, sl)
+	cl := []m3u.Channel{
+		{Name: "Known_Name"},
+		{Name: "Discovery Chanel", Group: "Docs", Source: "Provider 1"},
+		{Name: "Discovery Channel Ru", Group: "Docs", Source: "Provider 1"},
+		{Name: "Discovery Chanel", Group: "Docs", Source: "Provider 2"},
+		{Name: "Nonsense TV", Group: "Other"},
+	}
+	clOriginal := copier.TestDeep(t, cl)
+
+	actual := r.Report(sl, cl, 0.8)
+
+	assert.Exactly(t, slOriginal, sl, "should not modify the source streams")
+	assert.Exactly(t, clOriginal, cl, "should not modify the source channels")
+
+	assert.Exactly(t, cl[1:], actual.UnmatchedChannels, "should report channels which do not match any stream")
+	assert.Exactly(t, sl[1:], actual.UnmatchedStreams, "should report streams which do not match any channel")
+
+	assert.Len(t, actual.NearMisses, 2, "should report this amount of near misses")
+	assert.Exactly(t, sl[1], actual.NearMisses[0].Stream, "should report this stream as a near miss")
+	assert.Exactly(t, cl[1], actual.NearMisses[0].Channel, "should report the most similar channel first")
+	assert.Exactly(t, sl[1], actual.NearMisses[1].Stream, "should report this stream as a near miss")
+	assert.Exactly(t, cl[2], actual.NearMisses[1].Channel, "should report less similar channel after")
+	assert.Greater(t, actual.NearMisses[0].Score, actual.NearMisses[1].Score, "should sort near misses by score")
+
+	actual = r.Report(sl, cl, 0.99)
+	assert.Empty(t, actual.NearMisses, "should not report near misses with score below threshold")
+
+	// Test log output
+	out := capturer.CaptureStderr(func() {
+		r := newDefRepo()
+		_ = r.Report(sl, cl, 0.8)
+	})
+	assert.Contains(t, out, `Built report: unmatched channels "4", unmatched streams "2", near misses "2"`)
+}
+
+func TestReportAliasList(t *testing.T) {
+	rep := Report{
+		NearMisses: []NearMiss{
+			{Stream: astra.Stream{Name: "Stream 1"}, Channel: m3u.Channel{Name: "Channel 1"}},
+			{Stream: astra.Stream{Name: "Stream 2"}, Channel: m3u.Channel{Name: "Channel 2"}},
+			{Stream: astra.Stream{Name: "Stream 1"}, Channel: m3u.Channel{Name: "Channel 1b"}},
+			{Stream: astra.Stream{Name: "Stream 1"}, Channel: m3u.Channel{Name: "Channel 1"}},
+		},
+	}
+
+	expected := [][]string{{"Stream 1", "Channel 1", "Channel 1b"}, {"Stream 2", "Channel 2"}}
+	assert.Exactly(t, expected, rep.AliasList(), "should group channel names by stream name")
+}
+
+func TestReportString(t *testing.T) {
+	rep := Report{
+		UnmatchedChannels: []m3u.Channel{
+			{Name: "It's TV", Group: "Group"},
+			{Name: "Channel", Group: "Group", Source: "Provider"},
+		},
+		UnmatchedStreams: []astra.Stream{{ID: "0", Name: "Stream", Groups: map[string]string{"Cat": "Grp"}}},
+		NearMisses: []NearMiss{
+			{Stream: astra.Stream{Name: "Stream"}, Channel: m3u.Channel{Name: "Streem"}},
+		},
+	}
+
+	expected := "# M3U channels which do not match any stream:\n" +
+		"unmatched_channels:\n" +
+		"  - name: 'It''s TV'\n" +
+		"    group: 'Group'\n" +
+		"  - name: 'Channel'\n" +
+		"    group: 'Group'\n" +
+		"    source: 'Provider'\n" +
+		"\n" +
+		"# Streams which do not match any M3U channel:\n" +
+		"unmatched_streams:\n" +
+		"  - id: '0'\n" +
+		"    name: 'Stream'\n" +
+		"    group: 'Cat: Grp'\n" +
+		"\n" +
+		"# Near misses (stream name first), add to 'general.name_alias_list' to make them match:\n" +
+		"name_alias_list:\n" +
+		"  - - 'Stream'\n" +
+		"    - 'Streem'\n"
+	assert.Exactly(t, expected, rep.String(), "should return this YAML representation")
+}
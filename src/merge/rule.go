@@ -0,0 +1,170 @@
+package merge
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"m3u_merge_astra/astra"
+	"m3u_merge_astra/cfg"
+	"m3u_merge_astra/m3u"
+	"m3u_merge_astra/util/copier"
+	"m3u_merge_astra/util/slice"
+	"m3u_merge_astra/util/slice/find"
+	urlUtil "m3u_merge_astra/util/url"
+
+	json "github.com/SCP002/jsonexraw"
+	"github.com/cockroachdb/errors"
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	"github.com/samber/lo"
+)
+
+// ApplyRules returns shallow copy of <streams> with cfg.Streams.Rules applied in order.
+//
+// M3U attributes of a stream are taken from the channel of <channels> corresponding to the stream (see
+// util/slice/find.Matching). Removed streams are skipped.
+func (r repo) ApplyRules(streams []astra.Stream, channels []m3u.Channel) (out []astra.Stream) {
+	r.log.Info("Applying rules to streams")
+
+	for _, s := range streams {
+		if s.Remove {
+			out = append(out, s)
+			continue
+		}
+		ch, _, chFound := find.Matching(r.cfg.General, channels, s.GetTVGID(r.cfg.General), s.Name)
+		for _, rule := range r.cfg.Streams.Rules {
+			if !matchRule(rule.Match, s, ch, chFound) {
+				continue
+			}
+			var applied []string
+			for _, action := range rule.Actions {
+				var changed bool
+				var err error
+				s, changed, err = applyAction(action, s)
+				if err != nil {
+					r.log.Error(errors.Wrapf(err, "Apply rule %q to stream %q (%v)", rule.Name, s.Name, s.ID))
+				}
+				if changed {
+					applied = append(applied, describeAction(action))
+				}
+			}
+			if len(applied) > 0 {
+				r.log.InfoFi("Applying rule to stream", "rule", rule.Name, "ID", s.ID, "name", s.Name,
+					"group", s.FirstGroup(), "actions", strings.Join(applied, "; "))
+			} else {
+				r.log.DebugFi("Rule matches stream but changes nothing", "rule", rule.Name, "ID", s.ID, "name", s.Name)
+			}
+		}
+		out = append(out, s)
+	}
+
+	return
+}
+
+// matchRule returns true if stream <s> with corresponding M3U channel <ch> matches condition <m>.
+//
+// If <chFound> is false, conditions on M3U attributes never match.
+func matchRule(m cfg.RuleMatch, s astra.Stream, ch m3u.Channel, chFound bool) bool {
+	if m.Name != nil && !m.Name.MatchString(s.Name) {
+		return false
+	}
+	if m.Group != nil && !lo.SomeBy(lo.Entries(s.Groups), func(group lo.Entry[string, string]) bool {
+		return m.Group.MatchString(group.Key + ": " + group.Value)
+	}) {
+		return false
+	}
+	if m.Input != nil && !slice.RxMatchAny(*m.Input, s.Inputs...) {
+		return false
+	}
+	if m.ID != nil && !m.ID.MatchString(s.ID) {
+		return false
+	}
+	for key, rx := range m.M3U {
+		if !chFound || !rx.MatchString(ch.Attributes.Get(key)) {
+			return false
+		}
+	}
+	if m.Enabled != nil && s.Enabled != *m.Enabled {
+		return false
+	}
+	if !lo.EveryBy(m.All, func(m cfg.RuleMatch) bool { return matchRule(m, s, ch, chFound) }) {
+		return false
+	}
+	if len(m.Any) > 0 && !lo.SomeBy(m.Any, func(m cfg.RuleMatch) bool { return matchRule(m, s, ch, chFound) }) {
+		return false
+	}
+	if m.Not != nil && matchRule(*m.Not, s, ch, chFound) {
+		return false
+	}
+	return true
+}
+
+// applyAction returns deep copy of stream <s> with action <a> performed and true if stream has been changed.
+//
+// Returns stream as is, false and error if action can not be performed.
+func applyAction(a cfg.RuleAction, s astra.Stream) (astra.Stream, bool, error) {
+	out := copier.MustDeep(s)
+
+	switch {
+	case a.AddHash != "":
+		var changed bool
+		for idx, inp := range out.Inputs {
+			inp, inpChanged, err := urlUtil.AddHash(a.AddHash, inp)
+			if err != nil {
+				return s, false, errors.Wrap(err, "Add hash")
+			}
+			out.Inputs[idx] = inp
+			changed = changed || inpChanged
+		}
+		return out, changed, nil
+	case a.SetKeepActive != nil:
+		out.HTTPKeepActive = strconv.Itoa(*a.SetKeepActive)
+	case a.Enable != nil:
+		if *a.Enable && !s.Enabled {
+			out = out.Enable()
+		} else if !*a.Enable && s.Enabled {
+			out = out.Disable()
+		}
+	case a.SetType != "":
+		out.Type = string(a.SetType)
+	case a.AddGroup != "":
+		category, group, _ := strings.Cut(a.AddGroup, ": ")
+		out.Groups = lo.Assign(out.Groups, map[string]string{category: group})
+	case a.RemoveInput != nil:
+		for _, inp := range lo.Uniq(append(append([]string{}, s.Inputs...), s.DisabledInputs...)) {
+			if a.RemoveInput.MatchString(inp) {
+				out = out.RemoveInputsCb(inp, func() {})
+			}
+		}
+	case len(a.SetField) > 0:
+		var err error
+		if out, err = out.SetFields(a.SetField); err != nil {
+			return s, false, errors.Wrap(err, "Set field")
+		}
+	}
+
+	return out, !cmp.Equal(s, out, cmpopts.EquateEmpty()), nil
+}
+
+// describeAction returns action <a> in the form of "key: value" to display in logs
+func describeAction(a cfg.RuleAction) string {
+	switch {
+	case a.AddHash != "":
+		return "add_hash: " + a.AddHash
+	case a.SetKeepActive != nil:
+		return fmt.Sprintf("set_keep_active: %v", *a.SetKeepActive)
+	case a.Enable != nil:
+		return fmt.Sprintf("enable: %v", *a.Enable)
+	case a.SetType != "":
+		return fmt.Sprintf("set_type: %v", a.SetType)
+	case a.AddGroup != "":
+		return "add_group: " + a.AddGroup
+	case a.RemoveInput != nil:
+		return "remove_input: " + a.RemoveInput.String()
+	case len(a.SetField) > 0:
+		data, _ := json.Marshal(a.SetField)
+		return "set_field: " + string(data)
+	}
+	return ""
+}
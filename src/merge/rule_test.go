@@ -0,0 +1,152 @@
+package merge
+
+import (
+	"regexp"
+	"testing"
+
+	"m3u_merge_astra/astra"
+	"m3u_merge_astra/cfg"
+	"m3u_merge_astra/m3u"
+	"m3u_merge_astra/util/copier"
+
+	"github.com/samber/lo"
+	"github.com/stretchr/testify/assert"
+	"github.com/zenizh/go-capturer"
+)
+
+func TestApplyRules(t *testing.T) {
+	rules := []cfg.Rule{
+		{
+			Name: "Radio",
+			Match: cfg.RuleMatch{
+				Group: regexp.MustCompile(`(?i)^All: Radio$`),
+				Not:   &cfg.RuleMatch{ID: regexp.MustCompile(`^a`)},
+			},
+			Actions: []cfg.RuleAction{
+				{Enable: lo.ToPtr(false)},
+				{SetKeepActive: lo.ToPtr(0)},
+				{SetField: map[string]any{"timeout": 30}},
+			},
+		},
+		{
+			Name: "Provider",
+			Match: cfg.RuleMatch{Any: []cfg.RuleMatch{
+				{Input: regexp.MustCompile(`^http://provider/`)},
+				{M3U: map[string]regexp.Regexp{"tvg-id": *regexp.MustCompile(`\.uk$`)}},
+			}},
+			Actions: []cfg.RuleAction{
+				{AddHash: "no_sync"},
+				{RemoveInput: regexp.MustCompile(`^http://dead/`)},
+				{AddGroup: "Provider: UK"},
+			},
+		},
+		{
+			Name: "Enabled HD",
+			Match: cfg.RuleMatch{
+				Enabled: lo.ToPtr(true),
+				All:     []cfg.RuleMatch{{Name: regexp.MustCompile(` HD$`)}},
+			},
+			Actions: []cfg.RuleAction{{SetType: cfg.MPTS}},
+		},
+	}
+
+	sl1 := []astra.Stream{
+		{ // Index 0. Matches "Radio"
+			ID:      "0000",
+			Name:    "Radio FM",
+			Enabled: true,
+			Groups:  map[string]string{"All": "Radio"},
+			Inputs:  []string{"http://other/1"},
+		},
+		{ // Index 1. Matches "Radio" group, but not ID
+			ID:      "a001",
+			Name:    "Radio 2",
+			Enabled: true,
+			Groups:  map[string]string{"All": "Radio"},
+			Inputs:  []string{"http://other/1"},
+		},
+		{ // Index 2. Matches "Provider" by input and "Enabled HD"
+			ID:      "0002",
+			Name:    "Sport HD",
+			Enabled: true,
+			Inputs:  []string{"http://provider/1", "http://dead/1"},
+		},
+		{ // Index 3. Matches "Provider" by M3U attribute
+			ID:             "0003",
+			Name:           "News",
+			Inputs:         []string{"http://other/3"},
+			DisabledInputs: []string{"http://dead/3"},
+		},
+		{ // Index 4. Matches "Provider" by input, but removed
+			ID:     "0004",
+			Name:   "Sport 2",
+			Inputs: []string{"http://provider/4"},
+			Remove: true,
+		},
+		{ // Index 5. Does not match "Enabled HD" as disabled
+			ID:     "0005",
+			Name:   "Movies HD",
+			Inputs: []string{"http://other/5"},
+		},
+	}
+	sl1Original := copier.TestDeep(t, sl1)
+	cl1 := []m3u.Channel{
+		{Name: "News", URL: "http://other/3", Attributes: m3u.Attributes{"tvg-id": "news.uk"}},
+		{Name: "Movies HD", URL: "http://other/5", Attributes: m3u.Attributes{"tvg-id": "movies.us"}},
+	}
+
+	var sl2 []astra.Stream
+	out := capturer.CaptureStderr(func() {
+		r := newDefRepo()
+		r.cfg.Streams.Rules = rules
+		sl2 = r.ApplyRules(sl1, cl1)
+	})
+	assert.NotSame(t, &sl1, &sl2, "should return copy of streams")
+	assert.Exactly(t, sl1Original, sl1, "should not modify the source")
+	assert.Len(t, sl2, len(sl1), "amount of output streams should stay the same")
+
+	expected := sl1[0]
+	expected.Enabled = false
+	expected.MarkDisabled = true
+	expected.HTTPKeepActive = "0"
+	expected.Timeout = lo.ToPtr(astra.Number(30))
+	assert.Exactly(t, expected, sl2[0], "should apply every action of the rule")
+
+	assert.Exactly(t, sl1[1], sl2[1], "should not apply rule as negated condition matches")
+
+	expected = sl1[2]
+	expected.Inputs = []string{"http://provider/1#no_sync"}
+	expected.DisabledInputs = []string{}
+	expected.Groups = map[string]string{"Provider": "UK"}
+	expected.Type = string(cfg.MPTS)
+	assert.Exactly(t, expected, sl2[2], "should apply every matching rule")
+
+	expected = sl1[3]
+	expected.Inputs = []string{"http://other/3#no_sync"}
+	expected.DisabledInputs = []string{}
+	expected.Groups = map[string]string{"Provider": "UK"}
+	assert.Exactly(t, expected, sl2[3], "should match M3U attributes of corresponding channel")
+
+	assert.Exactly(t, sl1[4], sl2[4], "should not change removed stream")
+	assert.Exactly(t, sl1[5], sl2[5], "should not apply rule as stream is disabled")
+
+	assert.Contains(t, out, `Applying rule to stream: rule "Radio", ID "0000", name "Radio FM", group "All: Radio", `+
+		`actions "enable: false; set_keep_active: 0; set_field: {"timeout":30}"`)
+	assert.Contains(t, out, `Applying rule to stream: rule "Provider", ID "0002", name "Sport HD", `+
+		`group "Provider: UK", actions "add_hash: no_sync; remove_input: ^http://dead/; add_group: Provider: UK"`)
+	assert.NotContains(t, out, `ID "a001"`, "should not log anything for not matching stream")
+
+	// Test actions which change nothing and invalid actions
+	out = capturer.CaptureStderr(func() {
+		r := newDefRepo()
+		r.cfg.Streams.Rules = []cfg.Rule{
+			{Name: "Noop", Actions: []cfg.RuleAction{{SetType: cfg.SPTS}}},
+			{Name: "Bad", Actions: []cfg.RuleAction{{SetField: map[string]any{"timeout": "abc"}}}},
+		}
+		sl2 = r.ApplyRules([]astra.Stream{{ID: "0000", Name: "Name", Type: string(cfg.SPTS)}}, nil)
+	})
+	assert.Exactly(t, []astra.Stream{{ID: "0000", Name: "Name", Type: string(cfg.SPTS)}}, sl2,
+		"should not change stream")
+	assert.Contains(t, out, `Rule matches stream but changes nothing: rule "Noop", ID "0000", name "Name"`)
+	assert.Contains(t, out, `Apply rule "Bad" to stream "Name" (0000): Set field: Decode fields`)
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"os"
+
+	"m3u_merge_astra/astra"
+	"m3u_merge_astra/astra/api"
+	"m3u_merge_astra/cfg"
+	"m3u_merge_astra/cli"
+	"m3u_merge_astra/util/input"
+	"m3u_merge_astra/util/logger"
+
+	"github.com/cockroachdb/errors"
+)
+
+// plan fetches astra config and M3U channels, merges them according to <cfg>, prints changes and writes them along
+// with fingerprint of astra config to plan file specified in <flags>
+func plan(log *logger.Logger, flags cli.Flags, cfg cfg.Root) {
+	// Fetch astra config
+	log.Info("Fetching astra config")
+	astraCfg, err := newAPIHandler(log, flags, cfg).FetchCfg()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	modifiedCats, modifiedStreams, err := mergeCfg(log, flags, cfg, astraCfg)
+	if err != nil {
+		log.Fatal(err)
+	}
+	astraRepo := astra.NewRepo(log, cfg)
+	changedCatMap := astraRepo.ChangedCategories(astraCfg.Categories, modifiedCats)
+	changedStreams := astraRepo.ChangedStreams(astraCfg.Streams, modifiedStreams)
+
+	if err := printDiff(astraRepo.Diff(astraCfg.Streams, modifiedStreams, astraCfg.Categories,
+		modifiedCats)); err != nil {
+		log.Fatal(err)
+	}
+
+	astraPlan, err := astra.NewPlan(astraCfg, changedCatMap, changedStreams)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := astraRepo.SavePlan(flags.Plan.Args.PlanPath, astraPlan); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// apply sends changes from plan file specified in <flags> to astra and returns outcome of sending.
+//
+// Fails if astra config changed since the plan was written.
+func apply(log *logger.Logger, flags cli.Flags, cfg cfg.Root) api.Outcome {
+	astraRepo := astra.NewRepo(log, cfg)
+
+	astraPlan, err := astraRepo.ReadPlan(flags.Apply.Args.PlanPath)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// Fetch astra config
+	log.Info("Fetching astra config")
+	apiHandler := newAPIHandler(log, flags, cfg)
+	astraCfg, err := apiHandler.FetchCfg()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	fingerprint, err := astra.Fingerprint(astraCfg)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if fingerprint != astraPlan.Fingerprint {
+		log.Fatal(errors.Newf("Astra config changed since the plan was written at %v, write a new plan",
+			astraPlan.Created))
+	}
+
+	if !flags.Noninteractive && !input.AskYesNo(log, os.Stdin, "Send changes from plan to astra (Y/N)? ") {
+		return api.NothingChanged
+	}
+
+	outcome, err := send(log, flags, cfg, apiHandler, astraCfg, astraPlan.CategoryMap(), astraPlan.Streams)
+	if err != nil {
+		log.Fatal(err)
+	}
+	return outcome
+}
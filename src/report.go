@@ -0,0 +1,33 @@
+package main
+
+import (
+	"fmt"
+
+	"m3u_merge_astra/astra"
+	"m3u_merge_astra/cfg"
+	"m3u_merge_astra/cli"
+	"m3u_merge_astra/merge"
+	"m3u_merge_astra/util/logger"
+)
+
+// report prints streams and M3U channels which do not match each other and near misses between them as YAML
+func report(log *logger.Logger, flags cli.Flags, cfg cfg.Root) {
+	// Fetch astra config
+	log.Info("Fetching astra config")
+	apiHandler := newAPIHandler(log, flags, cfg)
+	astraCfg, err := apiHandler.FetchCfg()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	m3uChannels, err := fetchChannels(log, flags, cfg)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// Compare names as they would be compared during merge
+	streams := astra.NewRepo(log, cfg).RemoveNamePrefixes(astraCfg.Streams)
+
+	rep := merge.NewRepo(log, cfg).Report(streams, m3uChannels, flags.Report.Threshold)
+	fmt.Print(rep.String())
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"os"
+
+	"m3u_merge_astra/astra"
+	"m3u_merge_astra/astra/api"
+	"m3u_merge_astra/cfg"
+	"m3u_merge_astra/cli"
+	"m3u_merge_astra/util/input"
+	"m3u_merge_astra/util/logger"
+
+	"github.com/samber/lo"
+)
+
+// restore sends categories and streams from snapshot file specified in <flags> to astra, reverting any changes made
+// after the snapshot was taken, and returns outcome of sending
+func restore(log *logger.Logger, flags cli.Flags, cfg cfg.Root) api.Outcome {
+	astraRepo := astra.NewRepo(log, cfg)
+
+	snapshot, err := astraRepo.ReadSnapshot(flags.Restore.Args.SnapshotPath)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// Fetch astra config
+	log.Info("Fetching astra config")
+	apiHandler := newAPIHandler(log, flags, cfg)
+	astraCfg, err := apiHandler.FetchCfg()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	changedCatMap := astraRepo.RestoreCategories(astraCfg.Categories, snapshot.Categories)
+	changedStreams := astraRepo.RestoreStreams(astraCfg.Streams, snapshot.Streams)
+
+	if flags.DryRun {
+		log.Info("Dry run, changes are not sent to astra")
+		changedCats := lo.Map(changedCatMap, func(entry lo.Entry[int, astra.Category], _ int) astra.Category {
+			return entry.Value
+		})
+		diff := astraRepo.Diff(astraCfg.Streams, changedStreams, astraCfg.Categories, changedCats)
+		if err := printDiff(diff); err != nil {
+			log.Fatal(err)
+		}
+		return api.NothingChanged
+	}
+
+	if len(changedCatMap) == 0 && len(changedStreams) == 0 {
+		log.Info("Astra config is identical to the snapshot, nothing to restore")
+		return api.NothingChanged
+	}
+
+	if !flags.Noninteractive && !input.AskYesNo(log, os.Stdin, "Restore astra config from snapshot (Y/N)? ") {
+		return api.NothingChanged
+	}
+
+	// Save current state to be able to undo the restore
+	if flags.BackupDir != "" {
+		if _, err := astraRepo.SaveSnapshot(flags.BackupDir, flags.BackupKeep, astraCfg); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	return api.Apply(log, apiHandler, astraRepo, astraCfg, changedCatMap, changedStreams, flags.Atomic).Outcome
+}
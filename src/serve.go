@@ -0,0 +1,65 @@
+package main
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"m3u_merge_astra/cfg"
+	"m3u_merge_astra/cli"
+	"m3u_merge_astra/util/logger"
+
+	"github.com/cockroachdb/errors"
+	"github.com/go-co-op/gocron"
+)
+
+// serve runs program on schedule specified in <flags> until SIGINT or SIGTERM signal is received.
+//
+// Runs never overlap: if previous run is still in progress, scheduled run is skipped.
+//
+// On the first signal it waits for the current run to finish, on the second one it exits immediately.
+func serve(log *logger.Logger, flags cli.Flags, cfg cfg.Root) {
+	flags.Noninteractive = true
+
+	scheduler := gocron.NewScheduler(time.Local)
+	scheduler.SetMaxConcurrentJobs(1, gocron.RescheduleMode)
+
+	runNum := 0
+	runOnce := func() {
+		runNum++
+		runLog := log.WithContext("run", runNum)
+		runLog.Info("Starting scheduled run")
+		outcome, err := run(runLog, flags, cfg)
+		if err != nil {
+			runLog.ErrorFi("Scheduled run failed", "error", err)
+			return
+		}
+		runLog.InfoFi("Scheduled run finished", "outcome", outcome)
+	}
+
+	var err error
+	if flags.Serve.Cron != "" {
+		_, err = scheduler.Cron(flags.Serve.Cron).Do(runOnce)
+	} else {
+		_, err = scheduler.Every(flags.Serve.Interval).Do(runOnce)
+	}
+	if err != nil {
+		log.Fatal(errors.Wrap(err, "Schedule runs"))
+	}
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+
+	scheduler.StartAsync()
+	log.InfoFi("Serving", "interval", flags.Serve.Interval.String(), "cron", flags.Serve.Cron)
+
+	<-signals
+	log.Info("SIGINT or SIGTERM signal received, waiting for the current run to finish")
+	go func() {
+		<-signals
+		log.Info("SIGINT or SIGTERM signal received again, shutting down immediately")
+		os.Exit(1)
+	}()
+	scheduler.Stop()
+}
@@ -3,6 +3,7 @@ package compare
 import (
 	"m3u_merge_astra/cfg"
 	"m3u_merge_astra/util/simplify"
+	"regexp"
 	"strings"
 
 	"github.com/samber/lo"
@@ -10,7 +11,53 @@ import (
 
 // IsNameSame returns true if standardized <lName> is equal to standardized <rName> using transliteration settings and
 // aliases from <cfg>.
+//
+// If cfg.FuzzyMatching is enabled, names with similarity score (see FuzzyScore) not less than cfg.FuzzyThreshold are
+// also considered equal.
 func IsNameSame(cfg cfg.General, lName, rName string) bool {
+	_, same := NameScore(cfg, lName, rName)
+	return same
+}
+
+// NameScore returns similarity score of <lName> and <rName> in range from 0 to 1 and true if names are considered equal
+// by IsNameSame.
+//
+// Score is 1 if names are equal without fuzzy matching, FuzzyScore if cfg.FuzzyMatching is enabled or 0 otherwise.
+func NameScore(cfg cfg.General, lName, rName string) (float64, bool) {
+	if isNameSameStrict(cfg, lName, rName) {
+		return 1, true
+	}
+	if !cfg.FuzzyMatching {
+		return 0, false
+	}
+	score := FuzzyScore(cfg, lName, rName)
+	return score, score >= cfg.FuzzyThreshold
+}
+
+// FuzzyScore returns Jaro-Winkler similarity of <lName> and <rName> in range from 0 to 1.
+//
+// Before comparsion, every match of cfg.FuzzyStripRxList is removed from names and names are simplified. If
+// transliteration is enabled in <cfg>, the best score of transliterated and original names is returned.
+func FuzzyScore(cfg cfg.General, lName, rName string) float64 {
+	lSimpleName := simplify.Name(strip(lName, cfg.FuzzyStripRxList))
+	rSimpleName := simplify.Name(strip(rName, cfg.FuzzyStripRxList))
+
+	score := jaroWinkler(lSimpleName, rSimpleName)
+	if cfg.SimilarTranslit {
+		lRemapped, rRemapped := remap(lSimpleName, cfg.SimilarTranslitMap), remap(rSimpleName, cfg.SimilarTranslitMap)
+		score = max(score, jaroWinkler(lRemapped, rRemapped))
+	}
+	if cfg.FullTranslit {
+		lRemapped, rRemapped := remap(lSimpleName, cfg.FullTranslitMap), remap(rSimpleName, cfg.FullTranslitMap)
+		score = max(score, jaroWinkler(lRemapped, rRemapped))
+	}
+
+	return score
+}
+
+// isNameSameStrict returns true if standardized <lName> is equal to standardized <rName> using transliteration settings
+// and aliases from <cfg>.
+func isNameSameStrict(cfg cfg.General, lName, rName string) bool {
 	if lName == rName {
 		return true
 	}
@@ -42,6 +89,69 @@ func IsNameSame(cfg cfg.General, lName, rName string) bool {
 	return false
 }
 
+// strip returns <inp> with every match of every expression in <rxList> removed
+func strip(inp string, rxList []regexp.Regexp) string {
+	for _, rx := range rxList {
+		inp = rx.ReplaceAllString(inp, "")
+	}
+	return inp
+}
+
+// jaroWinkler returns Jaro-Winkler similarity of <lInp> and <rInp> in range from 0 to 1
+func jaroWinkler(lInp, rInp string) float64 {
+	l, r := []rune(lInp), []rune(rInp)
+	if len(l) == 0 && len(r) == 0 {
+		return 1
+	}
+	if len(l) == 0 || len(r) == 0 {
+		return 0
+	}
+
+	// Count characters matching within the window
+	window := max(0, max(len(l), len(r))/2-1)
+	lMatched, rMatched := make([]bool, len(l)), make([]bool, len(r))
+	matches := 0
+	for lIdx := range l {
+		for rIdx := max(0, lIdx-window); rIdx < min(len(r), lIdx+window+1); rIdx++ {
+			if !rMatched[rIdx] && l[lIdx] == r[rIdx] {
+				lMatched[lIdx], rMatched[rIdx] = true, true
+				matches++
+				break
+			}
+		}
+	}
+	if matches == 0 {
+		return 0
+	}
+
+	// Count matching characters in different order
+	transpositions := 0
+	rIdx := 0
+	for lIdx := range l {
+		if !lMatched[lIdx] {
+			continue
+		}
+		for !rMatched[rIdx] {
+			rIdx++
+		}
+		if l[lIdx] != r[rIdx] {
+			transpositions++
+		}
+		rIdx++
+	}
+
+	m := float64(matches)
+	jaro := (m/float64(len(l)) + m/float64(len(r)) + (m-float64(transpositions)/2)/m) / 3
+
+	// Boost score of strings with common prefix up to 4 characters
+	prefix := 0
+	for prefix < min(4, len(l), len(r)) && l[prefix] == r[prefix] {
+		prefix++
+	}
+
+	return jaro + float64(prefix)*0.1*(1-jaro)
+}
+
 // remap returns remapped <inp> using <dict>
 func remap(inp string, dict map[string]string) string {
 	var sb strings.Builder
@@ -2,6 +2,7 @@ package compare
 
 import (
 	"m3u_merge_astra/cfg"
+	"regexp"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -71,6 +72,64 @@ func TestIsNameSame(t *testing.T) {
 	assert.False(t, IsNameSame(cfg, "name_3_var_2", "Name 3"), msg)
 }
 
+func TestNameScore(t *testing.T) {
+	cfg := cfg.General{FuzzyThreshold: 0.9, FuzzyStripRxList: cfg.DefFuzzyStripRxList()}
+
+	score, same := NameScore(cfg, "Some Thing", "@Something")
+	assert.Exactly(t, 1.0, score, "should return score of 1 for equal names")
+	assert.True(t, same, "names should be equvalent")
+
+	score, same = NameScore(cfg, "Discovery HD", "Discovery")
+	assert.Exactly(t, 0.0, score, "should return score of 0 for different names if fuzzy matching is disabled")
+	assert.False(t, same, "names should not be equvalent as FuzzyMatching = false")
+
+	cfg.FuzzyMatching = true
+	score, same = NameScore(cfg, "Discovery FHD", "Discovery")
+	assert.Exactly(t, 1.0, score, "should strip quality suffixes")
+	assert.True(t, same, "names should be equvalent")
+	assert.True(t, IsNameSame(cfg, "Discovery FHD", "Discovery"), "names should be equvalent")
+
+	score, same = NameScore(cfg, "Discovery Chanel", "Discovery Channel")
+	assert.Greater(t, score, 0.9, "should return high score for similar names")
+	assert.Less(t, score, 1.0, "should return score less than 1 for different names")
+	assert.True(t, same, "names should be equvalent")
+
+	score, same = NameScore(cfg, "Discovery", "Eurosport")
+	assert.Less(t, score, 0.9, "should return low score for different names")
+	assert.False(t, same, "names should not be equvalent")
+	assert.False(t, IsNameSame(cfg, "Discovery", "Eurosport"), "names should not be equvalent")
+}
+
+func TestFuzzyScore(t *testing.T) {
+	cfg := cfg.General{
+		FullTranslitMap:  cfg.DefFullTranslitMap(),
+		FuzzyStripRxList: cfg.DefFuzzyStripRxList(),
+	}
+
+	assert.Exactly(t, 1.0, FuzzyScore(cfg, "Name +1", "Name (+2)"), "should strip time shift suffixes")
+	assert.Exactly(t, 1.0, FuzzyScore(cfg, "Name 4K", "name orig"), "should strip quality suffixes")
+	assert.Exactly(t, 1.0, FuzzyScore(cfg, "Hidden", "hidden"), "should not strip parts of words")
+
+	assert.Less(t, FuzzyScore(cfg, "монитор", "Monitor"), 0.5, "should not transliterate names")
+	cfg.FullTranslit = true
+	assert.Exactly(t, 1.0, FuzzyScore(cfg, "монитор", "Monitor"), "should transliterate names")
+}
+
+func TestJaroWinkler(t *testing.T) {
+	assert.InDelta(t, 0.961, jaroWinkler("martha", "marhta"), 0.001, "should return this score")
+	assert.InDelta(t, 0.840, jaroWinkler("dwayne", "duane"), 0.001, "should return this score")
+	assert.InDelta(t, 0.813, jaroWinkler("dixon", "dicksonx"), 0.001, "should return this score")
+	assert.Exactly(t, 1.0, jaroWinkler("тв", "тв"), "should return 1 for equal strings")
+	assert.Exactly(t, 1.0, jaroWinkler("", ""), "should return 1 for empty strings")
+	assert.Exactly(t, 0.0, jaroWinkler("abc", ""), "should return 0 if one of strings is empty")
+	assert.Exactly(t, 0.0, jaroWinkler("abc", "xyz"), "should return 0 for strings without common characters")
+}
+
+func TestStrip(t *testing.T) {
+	rxList := []regexp.Regexp{*regexp.MustCompile(` HD$`), *regexp.MustCompile(`^The `)}
+	assert.Exactly(t, "Name", strip("The Name HD", rxList), "should remove every match of every expression")
+}
+
 func TestRemap(t *testing.T) {
 	dict := map[string]string{"A": "1", "B": "2", "C": "3"}
 	assert.Exactly(t, "123D", remap("ABCD", dict), "should replace every char of input with proper value from dictonary")
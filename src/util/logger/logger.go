@@ -189,8 +189,25 @@ func (l Logger) AddFileWriter(filePath string) (*os.File, error) {
 	return logFile, nil
 }
 
+// WithContext returns copy of logger which adds <fields> to every message.
+//
+// Returned logger shares writers and level with the original one.
+func (l Logger) WithContext(fields ...any) *Logger {
+	ctx := pLog.NewContext(nil).Context(l.Logger.Context)
+	addFields(ctx, fields)
+	log := *l.Logger
+	log.Context = ctx.Value()
+	return &Logger{Logger: &log, writer: l.writer}
+}
+
 // print adds message <msg> and <fields> to <entry> and prints it
 func print(entry *pLog.Entry, msg string, fields []any) {
+	addFields(entry, fields)
+	entry.Msg(msg)
+}
+
+// addFields adds key / value pairs from <fields> to <entry>
+func addFields(entry *pLog.Entry, fields []any) {
 	// Not using entry.KeysAndValues() as it will not add keys which can't be converted to string by type assertion
 	var key string
 	for i, field := range fields {
@@ -200,7 +217,6 @@ func print(entry *pLog.Entry, msg string, fields []any) {
 			entry.Any(key, field)
 		}
 	}
-	entry.Msg(msg)
 }
 
 // newConsoleFormatter returns formatter funtion with <timeFormat> for console writer.
@@ -232,6 +232,19 @@ func TestPanicFi(t *testing.T) {
 	assert.Regexp(t, regexp.MustCompile(timeRx+` PANIC message: a "b", 1 "2"`), out)
 }
 
+func TestWithContext(t *testing.T) {
+	out := capturer.CaptureStderr(func() {
+		log := New(DebugLevel)
+		ctxLog := log.WithContext("run", 1)
+		ctxLog.InfoFi("message 1", "k", "v")
+		ctxLog.WithContext("step", "a").Info("message 2")
+		log.Info("message 3")
+	})
+	assert.Regexp(t, regexp.MustCompile(timeRx+` INFO message 1: run "1", k "v"`), out)
+	assert.Regexp(t, regexp.MustCompile(timeRx+` INFO message 2: run "1", step "a"`), out)
+	assert.Regexp(t, regexp.MustCompile(timeRx+` INFO message 3\n`), out, "should not modify the source logger")
+}
+
 func TestAddFileWriter(t *testing.T) {
 	path := filepath.Join(t.TempDir(), "test.log")
 
@@ -1,6 +1,7 @@
 package network
 
 import (
+	"io"
 	"net"
 	"net/url"
 	"syscall"
@@ -25,6 +26,8 @@ const (
 
 	// context deadline exceeded (Client.timeout exceeded while awaiting headers)
 	Timeout ErrType = "Timeout"
+	// connection reset by peer, EOF
+	Reset ErrType = "Connection reset"
 
 	Unknown ErrType = "Unknown"
 )
@@ -51,10 +54,19 @@ func GetErrType(err error) ErrType {
 		return Refused
 	}
 
-	netErr := err.(net.Error)
+	if errors.Is(err, syscall.ECONNRESET) || errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return Reset
+	}
+
+	var netErr net.Error
 	if ok := errors.As(err, &netErr); ok && netErr.Timeout() {
 		return Timeout
 	}
 
 	return Unknown
 }
+
+// IsTemporary returns true if error of type <t> can disappear on retry
+func (t ErrType) IsTemporary() bool {
+	return t == Refused || t == Timeout || t == Reset
+}
@@ -1,9 +1,12 @@
 package network
 
 import (
+	"io"
 	"net"
+	"syscall"
 	"testing"
 
+	"github.com/cockroachdb/errors"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -11,4 +14,16 @@ func TestGetErrType(t *testing.T) {
 	// Other cases are tested in http_test.go
 	assert.Exactly(t, Unknown, GetErrType(&net.OpError{}), "should return unknown error type")
 	assert.Exactly(t, Nil, GetErrType(nil), "should return nil error type")
+	assert.Exactly(t, Unknown, GetErrType(errors.New("API error")), "should return unknown type for non-network error")
+	assert.Exactly(t, Reset, GetErrType(errors.Wrap(io.EOF, "Read")), "should return connection reset error type")
+	assert.Exactly(t, Reset, GetErrType(syscall.ECONNRESET), "should return connection reset error type")
+}
+
+func TestIsTemporary(t *testing.T) {
+	assert.True(t, Timeout.IsTemporary(), "timeout should be temporary")
+	assert.True(t, Refused.IsTemporary(), "refused connection should be temporary")
+	assert.True(t, Reset.IsTemporary(), "reset connection should be temporary")
+	assert.False(t, NoSuchHost.IsTemporary(), "unknown host should not be temporary")
+	assert.False(t, Unknown.IsTemporary(), "unknown error should not be temporary")
+	assert.False(t, Nil.IsTemporary(), "nil error should not be temporary")
 }
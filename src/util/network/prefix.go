@@ -0,0 +1,21 @@
+package network
+
+import (
+	"net/netip"
+)
+
+// LastAddr returns the last address of <prefix>
+func LastAddr(prefix netip.Prefix) netip.Addr {
+	prefix = prefix.Masked()
+	addrBytes := prefix.Addr().AsSlice()
+	for bit := prefix.Bits(); bit < len(addrBytes)*8; bit++ {
+		addrBytes[bit/8] |= 1 << (7 - bit%8)
+	}
+	addr, _ := netip.AddrFromSlice(addrBytes)
+	return addr
+}
+
+// IsMulticastPrefix returns true if every address of <prefix> is multicast
+func IsMulticastPrefix(prefix netip.Prefix) bool {
+	return prefix.IsValid() && prefix.Masked().Addr().IsMulticast() && LastAddr(prefix).IsMulticast()
+}
@@ -0,0 +1,34 @@
+package network
+
+import (
+	"net/netip"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLastAddr(t *testing.T) {
+	test := func(cidr, expected string) {
+		assert.Exactly(t, netip.MustParseAddr(expected), LastAddr(netip.MustParsePrefix(cidr)), "should return last "+
+			"address of "+cidr)
+	}
+	test("239.255.0.0/16", "239.255.255.255")
+	test("239.1.0.1/30", "239.1.0.3")
+	test("239.1.0.1/32", "239.1.0.1")
+	test("239.0.0.0/1", "255.255.255.255")
+	test("ff00::/8", "ffff:ffff:ffff:ffff:ffff:ffff:ffff:ffff")
+}
+
+func TestIsMulticastPrefix(t *testing.T) {
+	test := func(cidr string, expected bool) {
+		assert.Exactly(t, expected, IsMulticastPrefix(netip.MustParsePrefix(cidr)), "should check "+cidr)
+	}
+	test("239.255.0.0/16", true)
+	test("224.0.0.0/4", true)
+	test("239.1.0.1/32", true)
+	test("ff00::/8", true)
+	test("10.0.0.0/8", false)
+	test("239.0.0.0/1", false) // First address is 128.0.0.0
+	test("224.0.0.0/3", false) // Last address is 255.255.255.255
+	assert.False(t, IsMulticastPrefix(netip.Prefix{}), "should return false for invalid prefix")
+}
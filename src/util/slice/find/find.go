@@ -1,6 +1,9 @@
 package find
 
 import (
+	"sort"
+	"strings"
+
 	"m3u_merge_astra/cfg"
 	"m3u_merge_astra/util/compare"
 	"m3u_merge_astra/util/slice"
@@ -8,6 +11,19 @@ import (
 	"github.com/samber/lo"
 )
 
+// Identified used to ensure implementing struct has GetName and GetTVGID methods for ID based functions in this file
+type Identified interface {
+	slice.Named
+	GetTVGID(cfg cfg.General) string
+}
+
+// Scored represents <list> entry found by name with it's index and similarity score
+type Scored[T any] struct {
+	Elm   T
+	Idx   int
+	Score float64
+}
+
 // IndexOrElse returns unmodified <list>, <list> entry and it's index if <predicate> returns true or <list> with
 // <fallback>, <fallback> and it's index (len - 1) if not found.
 func IndexOrElse[T any](list []T, fallback T, predicate func(elm T) bool) ([]T, T, int) {
@@ -19,29 +35,36 @@ func IndexOrElse[T any](list []T, fallback T, predicate func(elm T) bool) ([]T,
 	return list, elm, idx
 }
 
-// Named returns <list> entry, it's index and true if .Name() of it matching the <name> or empty object, -1 and false if
-// not found.
+// Named returns <list> entry with the best similarity score, it's index and true if .Name() of it matching the <name>
+// or empty object, -1 and false if not found.
 //
 // Both names standartized before comparsion using transliteration settings from <cfg>.
 func Named[T slice.Named](cfg cfg.General, list []T, name string) (T, int, bool) {
-	return lo.FindIndexOf(list, func(o T) bool {
-		return compare.IsNameSame(cfg, o.GetName(), name)
-	})
+	return best(RankSimilar(cfg, list, name, 0))
 }
 
 // EverySimilar runs callback <cb> for every entry of <list> starting from index <start> if it's .Name() matching the
 // <name>.
 //
+// Callback runs in order of similarity score from best to worst (see RankSimilar).
+//
 // Both names standartized before comparsion using transliteration settings from <cfg>.
 func EverySimilar[T slice.Named](cfg cfg.General, list []T, name string, start int, cb func(foundObj T, foundIdx int)) {
-	for currIdx := start; currIdx < len(list); currIdx++ {
-		currElm := list[currIdx]
-		if compare.IsNameSame(cfg, currElm.GetName(), name) {
-			cb(currElm, currIdx)
-		}
+	for _, found := range RankSimilar(cfg, list, name, start) {
+		cb(found.Elm, found.Idx)
 	}
 }
 
+// RankSimilar returns entries of <list> starting from index <start> if it's .Name() matching the <name>, sorted by
+// similarity score from best to worst. Entries with equal score keep their order.
+//
+// Both names standartized before comparsion using transliteration settings from <cfg> (see util/compare.NameScore).
+func RankSimilar[T slice.Named](cfg cfg.General, list []T, name string, start int) []Scored[T] {
+	return rank(list, start, func(elm T) (float64, bool) {
+		return compare.NameScore(cfg, elm.GetName(), name)
+	})
+}
+
 // GetSimilar returns shallow copy of <list> only with entries whose .Name() matching the <name>.
 //
 // Both names standartized before comparsion using transliteration settings from <cfg>.
@@ -61,3 +84,96 @@ func HasAnySimilar[T slice.Named](cfg cfg.General, list []T, names ...string) bo
 		})
 	})
 }
+
+// Matching returns <list> entry, it's index and true if it matching the <tvgID> or <name> or empty object, -1 and false
+// if not found.
+//
+// If cfg.TVGIDMatching is enabled, entries with .GetTVGID() equal to <tvgID> are searched first. Otherwise, or if
+// nothing found, entry with the best name similarity score and unknown tvg-id is returned (see isMatching).
+func Matching[T Identified](cfg cfg.General, list []T, tvgID, name string) (T, int, bool) {
+	if cfg.TVGIDMatching && tvgID != "" {
+		elm, idx, found := lo.FindIndexOf(list, func(elm T) bool {
+			return strings.EqualFold(elm.GetTVGID(cfg), tvgID)
+		})
+		if found {
+			return elm, idx, found
+		}
+	}
+	return best(RankMatching(cfg, list, tvgID, name, 0))
+}
+
+// EveryMatching runs callback <cb> for every entry of <list> starting from index <start> if it matching the <tvgID> or
+// <name> (see isMatching).
+//
+// Callback runs in order of similarity score from best to worst (see RankMatching).
+func EveryMatching[T Identified](cfg cfg.General, list []T, tvgID, name string, start int,
+	cb func(foundObj T, foundIdx int)) {
+	for _, found := range RankMatching(cfg, list, tvgID, name, start) {
+		cb(found.Elm, found.Idx)
+	}
+}
+
+// RankMatching returns entries of <list> starting from index <start> if it matching the <tvgID> or <name>, sorted by
+// similarity score from best to worst. Entries with equal score keep their order.
+//
+// Entries matching the <tvgID> have score of 1 (see isMatching).
+func RankMatching[T Identified](cfg cfg.General, list []T, tvgID, name string, start int) []Scored[T] {
+	return rank(list, start, func(elm T) (float64, bool) {
+		return isMatching(cfg, elm, tvgID, name)
+	})
+}
+
+// GetMatching returns shallow copy of <list> only with entries matching the <tvgID> or <name> (see isMatching).
+func GetMatching[T Identified](cfg cfg.General, list []T, tvgID, name string) []T {
+	return lo.Filter(list, func(elm T, idx int) bool {
+		_, matching := isMatching(cfg, elm, tvgID, name)
+		return matching
+	})
+}
+
+// HasMatching returns true if <list> contains entry matching the <tvgID> or <name> (see isMatching)
+func HasMatching[T Identified](cfg cfg.General, list []T, tvgID, name string) bool {
+	return lo.ContainsBy(list, func(elm T) bool {
+		_, matching := isMatching(cfg, elm, tvgID, name)
+		return matching
+	})
+}
+
+// isMatching returns similarity score of <elm> and true if <elm> matching the <tvgID> or <name>.
+//
+// If cfg.TVGIDMatching is enabled and both <tvgID> and .GetTVGID() of <elm> are not empty, they are compared case
+// insensitive. Otherwise names are standartized and compared using transliteration settings from <cfg> (see
+// util/compare.NameScore).
+func isMatching[T Identified](cfg cfg.General, elm T, tvgID, name string) (float64, bool) {
+	if cfg.TVGIDMatching && tvgID != "" {
+		if elmTVGID := elm.GetTVGID(cfg); elmTVGID != "" {
+			matching := strings.EqualFold(elmTVGID, tvgID)
+			return lo.Ternary(matching, 1.0, 0.0), matching
+		}
+	}
+	return compare.NameScore(cfg, elm.GetName(), name)
+}
+
+// rank returns entries of <list> starting from index <start> for which <score> returns true, sorted by score from best
+// to worst. Entries with equal score keep their order.
+func rank[T any](list []T, start int, score func(elm T) (float64, bool)) (out []Scored[T]) {
+	for idx := start; idx < len(list); idx++ {
+		if elmScore, ok := score(list[idx]); ok {
+			out = append(out, Scored[T]{Elm: list[idx], Idx: idx, Score: elmScore})
+		}
+	}
+	sort.SliceStable(out, func(i, j int) bool {
+		return out[i].Score > out[j].Score
+	})
+	return
+}
+
+// best returns entry of <ranked> with the best score, it's index and true or empty object, -1 and false if <ranked> is
+// empty.
+func best[T any](ranked []Scored[T]) (T, int, bool) {
+	if len(ranked) == 0 {
+		var empty T
+		return empty, -1, false
+	}
+	return ranked[0].Elm, ranked[0].Idx, true
+}
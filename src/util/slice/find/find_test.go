@@ -55,6 +55,14 @@ func TestNamed(t *testing.T) {
 	assert.Exactly(t, 1, idx, "should return index of first found element")
 	assert.True(t, found, "should find object matching the specified name")
 
+	cfg.FuzzyMatching = true
+	cfg.FuzzyThreshold = 0.8
+	o, idx, found = Named(cfg, ol, "Name 3 HD")
+	assert.Exactly(t, ol[2], o, "should return object with the best similarity score")
+	assert.Exactly(t, 2, idx, "should return index of the best found element")
+	assert.True(t, found, "should find object similar to the specified name")
+	cfg.FuzzyMatching = false
+
 	o, idx, found = Named(cfg, ol, "name4")
 	assert.Exactly(t, slice.TestNamedStruct{}, o, "should return empty object if not found")
 	assert.Exactly(t, -1, idx, "should return index -1 if not found")
@@ -86,6 +94,26 @@ func TestEverySimilar(t *testing.T) {
 	assert.Exactly(t, expected, idxNameMap, "should find these objects")
 }
 
+func TestRankSimilar(t *testing.T) {
+	cfg := cfg.General{FuzzyMatching: true, FuzzyThreshold: 0.85}
+	ol := []slice.TestNamedStruct{
+		/* 0 */ {Name: "Discovery Channel"},
+		/* 1 */ {Name: "Discovery Chanel"},
+		/* 2 */ {Name: "Eurosport"},
+		/* 3 */ {Name: "Discovery_Channel"},
+		/* 4 */ {Name: "Discovery Chan"},
+	}
+
+	actual := RankSimilar(cfg, ol, "Discovery Channel", 1)
+	assert.Len(t, actual, 3, "should find this amount of objects")
+	assert.Exactly(t, Scored[slice.TestNamedStruct]{Elm: ol[3], Idx: 3, Score: 1}, actual[0], "should rank equal first")
+	assert.Exactly(t, 1, actual[1].Idx, "should rank more similar object higher")
+	assert.Exactly(t, 4, actual[2].Idx, "should rank less similar object lower")
+	assert.Greater(t, actual[1].Score, actual[2].Score, "should sort by score")
+
+	assert.Empty(t, RankSimilar(cfg, ol, "Unknown", 0), "should return nothing if not found")
+}
+
 func TestGetSimilar(t *testing.T) {
 	cfg := cfg.General{
 		SimilarTranslit:    true,
@@ -124,3 +152,95 @@ func TestHasAnySimilar(t *testing.T) {
 	assert.False(t, HasAnySimilar(cfg, ol, "Name 4"), "should not find this object")
 	assert.True(t, HasAnySimilar(cfg, ol, "Name 4", "name2!"), "should find second object")
 }
+
+// testIdentified used to satisfy Identified interface in tests
+type testIdentified struct {
+	Name  string
+	TVGID string
+}
+
+func (o testIdentified) GetName() string {
+	return o.Name
+}
+
+func (o testIdentified) GetTVGID(_ cfg.General) string {
+	return o.TVGID
+}
+
+func TestMatching(t *testing.T) {
+	cfg := cfg.General{TVGIDMatching: true}
+	ol := []testIdentified{
+		/* 0 */ {Name: "Sport", TVGID: "sport.us"},
+		/* 1 */ {Name: "Sport"},
+		/* 2 */ {Name: "Sport HD", TVGID: "Sport.UK"},
+	}
+
+	o, idx, found := Matching(cfg, ol, "sport.uk", "Sport")
+	assert.Exactly(t, ol[2], o, "should prioritize object with the same tvg-id over the same name")
+	assert.Exactly(t, 2, idx, "should return index of found element")
+	assert.True(t, found, "should find object")
+
+	o, idx, found = Matching(cfg, ol, "sport.fr", "Sport")
+	assert.Exactly(t, ol[1], o, "should fall back to name of object without tvg-id")
+	assert.Exactly(t, 1, idx, "should return index of found element")
+	assert.True(t, found, "should find object")
+
+	o, idx, found = Matching(cfg, ol, "", "Sport")
+	assert.Exactly(t, ol[0], o, "should compare names if tvg-id is empty")
+	assert.Exactly(t, 0, idx, "should return index of found element")
+	assert.True(t, found, "should find object")
+
+	o, idx, found = Matching(cfg, ol, "news.uk", "News")
+	assert.Exactly(t, testIdentified{}, o, "should return empty object if not found")
+	assert.Exactly(t, -1, idx, "should return index -1 if not found")
+	assert.False(t, found, "should return false if not found")
+
+	cfg.TVGIDMatching = false
+	o, idx, found = Matching(cfg, ol, "sport.uk", "Sport")
+	assert.Exactly(t, ol[0], o, "should compare only names if tvg-id matching is disabled")
+	assert.Exactly(t, 0, idx, "should return index of found element")
+	assert.True(t, found, "should find object")
+}
+
+func TestEveryMatching(t *testing.T) {
+	cfg := cfg.General{TVGIDMatching: true}
+	ol := []testIdentified{
+		/* 0 */ {Name: "Sport", TVGID: "sport.uk"},
+		/* 1 */ {Name: "Sport", TVGID: "sport.us"},
+		/* 2 */ {Name: "Sport"},
+		/* 3 */ {Name: "Sport FHD", TVGID: "sport.uk"},
+		/* 4 */ {Name: "Sport orig", TVGID: "sport.uk"},
+	}
+
+	idxNameMap := map[int]string{}
+	EveryMatching(cfg, ol, "sport.uk", "Sport", 1, func(foundObj testIdentified, foundIdx int) {
+		idxNameMap[foundIdx] = foundObj.Name
+	})
+
+	expected := map[int]string{2: "Sport", 3: "Sport FHD", 4: "Sport orig"}
+	assert.Exactly(t, expected, idxNameMap, "should find these objects")
+}
+
+func TestGetMatching(t *testing.T) {
+	cfg := cfg.General{TVGIDMatching: true}
+	ol1 := []testIdentified{
+		{Name: "Sport", TVGID: "sport.uk"}, {Name: "Sport", TVGID: "sport.us"}, {Name: "Sport"}, {Name: "News"},
+	}
+	ol1Original := copier.TestDeep(t, ol1)
+	ol2 := GetMatching(cfg, ol1, "sport.uk", "Sport")
+
+	assert.NotSame(t, &ol1, &ol2, "should return copy of objects")
+	assert.Exactly(t, ol1Original, ol1, "should not modify the source objects")
+
+	expected := []testIdentified{{Name: "Sport", TVGID: "sport.uk"}, {Name: "Sport"}}
+	assert.Exactly(t, expected, ol2, "should find these objects")
+}
+
+func TestHasMatching(t *testing.T) {
+	cfg := cfg.General{TVGIDMatching: true}
+	ol := []testIdentified{{Name: "Sport", TVGID: "sport.us"}, {Name: "Sport HD", TVGID: "sport.uk"}}
+
+	assert.True(t, HasMatching(cfg, ol, "sport.uk", "Sport"), "should find object with the same tvg-id")
+	assert.False(t, HasMatching(cfg, ol, "sport.fr", "Sport"), "should not compare names if tvg-id differ")
+	assert.True(t, HasMatching(cfg, ol, "", "Sport"), "should compare names if tvg-id is empty")
+}
@@ -271,6 +271,7 @@ func setIndent(input []rune, tIndent int) []rune {
 
 	var output []rune
 	prevLineHyphensAmount := 0
+	prevLineIndent := 0
 
 	sc := scan.New(input, 0)
 	for sc.Lines(false) {
@@ -278,9 +279,11 @@ func setIndent(input []rune, tIndent int) []rune {
 		isFolder := strings.HasSuffix(trimLine, ":")
 		isComment := strings.HasPrefix(trimLine, "#")
 		hypensAmount := getHyphensAmount(trimLine)
-		isSeqValue := !isFolder && !isComment && hypensAmount == 0 && prevLineHyphensAmount == 1
-
 		cIndent := parse.GetIndent(sc.Line)
+		// Sequence value is indented deeper than the list item before it, key after the list is not
+		isSeqValue := !isFolder && !isComment && hypensAmount == 0 && prevLineHyphensAmount == 1 &&
+			cIndent > prevLineIndent
+
 		parentIndent := getParentIndent(sc.Line)
 		newIndent := parentIndent
 
@@ -301,6 +304,7 @@ func setIndent(input []rune, tIndent int) []rune {
 		output = append(output, []rune(sc.Line)...)
 
 		prevLineHyphensAmount = hypensAmount
+		prevLineIndent = cIndent
 	}
 
 	return output